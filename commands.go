@@ -77,6 +77,7 @@ type deleteShardGroupCommand struct {
 type createUserCommand struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+	Hashed   bool   `json:"hashed,omitempty"`
 	Admin    bool   `json:"admin,omitempty"`
 }
 
@@ -744,7 +744,7 @@ func TestHandler_CreateUser_BadRequest_NoPassword(t *testing.T) {
 	status, body := MustHTTP("GET", s.URL+`/query`, query, nil, "")
 	if status != http.StatusBadRequest {
 		t.Fatalf("unexpected status: %d", status)
-	} else if body != `{"error":"error parsing query: found EOF, expected WITH at line 1, char 18"}` {
+	} else if body != `{"error":"error parsing query: found EOF, expected WITH at line 1, char 17"}` {
 		t.Fatalf("unexpected body: %s", body)
 	}
 }
@@ -128,6 +128,14 @@ var (
 
 	// ErrContinuousQueryExists is returned when creating a duplicate continuous query.
 	ErrContinuousQueryExists = errors.New("continuous query already exists")
+
+	// ErrRegexSourceNotSupported is returned when a statement other than a
+	// SHOW statement reads from a regex source, which it cannot expand yet.
+	ErrRegexSourceNotSupported = errors.New("regex sources are only supported in SHOW statements")
+
+	// ErrNotImplemented is returned when executing a statement that can be
+	// parsed but is not supported by the server yet.
+	ErrNotImplemented = errors.New("not implemented")
 )
 
 // BatchPoints is used to send batched data in a single write.
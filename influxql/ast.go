@@ -4,10 +4,14 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"hash/fnv"
+	"math"
 	"regexp"
+	"regexp/syntax"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // DataType represents the primitive data types available in InfluxQL.
@@ -26,6 +30,14 @@ const (
 	Time = DataType("time")
 	// Duration means the data type is a duration of time.
 	Duration = DataType("duration")
+	// Float means the data type is a floating point number.
+	Float = DataType("float")
+	// Integer means the data type is an integer.
+	Integer = DataType("integer")
+	// Tag means the reference is to a tag rather than a field.
+	Tag = DataType("tag")
+	// AnyField means the reference is to a field of any data type.
+	AnyField = DataType("field")
 )
 
 // InspectDataType returns the data type of a given value.
@@ -57,30 +69,33 @@ type Node interface {
 func (*Query) node()     {}
 func (Statements) node() {}
 
-func (*AlterRetentionPolicyStatement) node()  {}
-func (*CreateContinuousQueryStatement) node() {}
-func (*CreateDatabaseStatement) node()        {}
-func (*CreateRetentionPolicyStatement) node() {}
-func (*CreateUserStatement) node()            {}
-func (*DeleteStatement) node()                {}
-func (*DropContinuousQueryStatement) node()   {}
-func (*DropDatabaseStatement) node()          {}
-func (*DropMeasurementStatement) node()       {}
-func (*DropRetentionPolicyStatement) node()   {}
-func (*DropSeriesStatement) node()            {}
-func (*DropUserStatement) node()              {}
-func (*GrantStatement) node()                 {}
-func (*ShowContinuousQueriesStatement) node() {}
-func (*ShowDatabasesStatement) node()         {}
-func (*ShowFieldKeysStatement) node()         {}
-func (*ShowRetentionPoliciesStatement) node() {}
-func (*ShowMeasurementsStatement) node()      {}
-func (*ShowSeriesStatement) node()            {}
-func (*ShowTagKeysStatement) node()           {}
-func (*ShowTagValuesStatement) node()         {}
-func (*ShowUsersStatement) node()             {}
-func (*RevokeStatement) node()                {}
-func (*SelectStatement) node()                {}
+func (*AlterRetentionPolicyStatement) node()     {}
+func (*CreateContinuousQueryStatement) node()    {}
+func (*CreateDatabaseStatement) node()           {}
+func (*CreateRetentionPolicyStatement) node()    {}
+func (*CreateUserStatement) node()               {}
+func (*DeleteStatement) node()                   {}
+func (*DropContinuousQueryStatement) node()      {}
+func (*DropDatabaseStatement) node()             {}
+func (*DropMeasurementStatement) node()          {}
+func (*DropRetentionPolicyStatement) node()      {}
+func (*DropSeriesStatement) node()               {}
+func (*DropUserStatement) node()                 {}
+func (*GrantStatement) node()                    {}
+func (*ShowContinuousQueriesStatement) node()    {}
+func (*ShowDatabasesStatement) node()            {}
+func (*ShowFieldKeyCardinalityStatement) node()  {}
+func (*ShowFieldKeysStatement) node()            {}
+func (*ShowRetentionPoliciesStatement) node()    {}
+func (*ShowMeasurementsStatement) node()         {}
+func (*ShowSeriesStatement) node()               {}
+func (*ShowTagKeyCardinalityStatement) node()    {}
+func (*ShowTagKeysStatement) node()              {}
+func (*ShowTagValuesCardinalityStatement) node() {}
+func (*ShowTagValuesStatement) node()            {}
+func (*ShowUsersStatement) node()                {}
+func (*RevokeStatement) node()                   {}
+func (*SelectStatement) node()                   {}
 
 func (*BinaryExpr) node()      {}
 func (*BooleanLiteral) node()  {}
@@ -91,6 +106,7 @@ func (*DurationLiteral) node() {}
 func (*Field) node()           {}
 func (Fields) node()           {}
 func (*Join) node()            {}
+func (*ListLiteral) node()     {}
 func (*Measurement) node()     {}
 func (Measurements) node()     {}
 func (*nilLiteral) node()      {}
@@ -109,11 +125,50 @@ func (*Wildcard) node()        {}
 // Query represents a collection of ordered statements.
 type Query struct {
 	Statements Statements
+
+	// Terminated is true if the last statement ended with a semicolon.
+	Terminated bool
 }
 
 // String returns a string representation of the query.
 func (q *Query) String() string { return q.Statements.String() }
 
+// Hash returns a hash of the normalized form of the query. Queries that
+// differ only in whitespace or in the case of keywords and function names
+// have the same hash.
+func (q *Query) Hash() uint64 {
+	h := fnv.New64a()
+	for _, stmt := range q.Statements {
+		_, _ = h.Write([]byte(normalizedString(stmt)))
+
+		// Passwords are redacted from the string so hash them separately.
+		if stmt, ok := stmt.(*CreateUserStatement); ok {
+			_, _ = h.Write([]byte{0})
+			_, _ = h.Write([]byte(stmt.Password))
+		}
+		_, _ = h.Write([]byte{';'})
+	}
+	return h.Sum64()
+}
+
+// normalizedString returns the string representation of a statement with
+// lowercase function names.
+func normalizedString(stmt Statement) string {
+	stmt = CloneStatement(stmt)
+	lower := func(n Node) {
+		if call, ok := n.(*Call); ok {
+			call.Name = strings.ToLower(call.Name)
+		}
+	}
+	WalkFunc(stmt, lower)
+
+	switch stmt := stmt.(type) {
+	case *CreateContinuousQueryStatement:
+		WalkFunc(stmt.Source, lower)
+	}
+	return stmt.String()
+}
+
 // Statements represents a list of statements.
 type Statements []Statement
 
@@ -131,6 +186,14 @@ type Statement interface {
 	Node
 	stmt()
 	RequiredPrivileges() ExecutionPrivileges
+
+	// StatementType returns the leading keywords of the statement,
+	// such as "SELECT" or "SHOW SERIES".
+	StatementType() string
+
+	// DefaultDatabase returns the database the statement targets or an
+	// empty string if it is cluster-wide or does not name a database.
+	DefaultDatabase() string
 }
 
 // ExecutionPrivilege is a privilege required for a user to execute
@@ -147,30 +210,131 @@ type ExecutionPrivilege struct {
 // ExecutionPrivileges is a list of privileges required to execute a statement.
 type ExecutionPrivileges []ExecutionPrivilege
 
-func (*AlterRetentionPolicyStatement) stmt()  {}
-func (*CreateContinuousQueryStatement) stmt() {}
-func (*CreateDatabaseStatement) stmt()        {}
-func (*CreateRetentionPolicyStatement) stmt() {}
-func (*CreateUserStatement) stmt()            {}
-func (*DeleteStatement) stmt()                {}
-func (*DropContinuousQueryStatement) stmt()   {}
-func (*DropDatabaseStatement) stmt()          {}
-func (*DropMeasurementStatement) stmt()       {}
-func (*DropRetentionPolicyStatement) stmt()   {}
-func (*DropSeriesStatement) stmt()            {}
-func (*DropUserStatement) stmt()              {}
-func (*GrantStatement) stmt()                 {}
-func (*ShowContinuousQueriesStatement) stmt() {}
-func (*ShowDatabasesStatement) stmt()         {}
-func (*ShowFieldKeysStatement) stmt()         {}
-func (*ShowMeasurementsStatement) stmt()      {}
-func (*ShowRetentionPoliciesStatement) stmt() {}
-func (*ShowSeriesStatement) stmt()            {}
-func (*ShowTagKeysStatement) stmt()           {}
-func (*ShowTagValuesStatement) stmt()         {}
-func (*ShowUsersStatement) stmt()             {}
-func (*RevokeStatement) stmt()                {}
-func (*SelectStatement) stmt()                {}
+func (*AlterRetentionPolicyStatement) stmt()     {}
+func (*CreateContinuousQueryStatement) stmt()    {}
+func (*CreateDatabaseStatement) stmt()           {}
+func (*CreateRetentionPolicyStatement) stmt()    {}
+func (*CreateUserStatement) stmt()               {}
+func (*DeleteStatement) stmt()                   {}
+func (*DropContinuousQueryStatement) stmt()      {}
+func (*DropDatabaseStatement) stmt()             {}
+func (*DropMeasurementStatement) stmt()          {}
+func (*DropRetentionPolicyStatement) stmt()      {}
+func (*DropSeriesStatement) stmt()               {}
+func (*DropUserStatement) stmt()                 {}
+func (*GrantStatement) stmt()                    {}
+func (*ShowContinuousQueriesStatement) stmt()    {}
+func (*ShowDatabasesStatement) stmt()            {}
+func (*ShowFieldKeyCardinalityStatement) stmt()  {}
+func (*ShowFieldKeysStatement) stmt()            {}
+func (*ShowMeasurementsStatement) stmt()         {}
+func (*ShowRetentionPoliciesStatement) stmt()    {}
+func (*ShowSeriesStatement) stmt()               {}
+func (*ShowTagKeyCardinalityStatement) stmt()    {}
+func (*ShowTagKeysStatement) stmt()              {}
+func (*ShowTagValuesCardinalityStatement) stmt() {}
+func (*ShowTagValuesStatement) stmt()            {}
+func (*ShowUsersStatement) stmt()                {}
+func (*RevokeStatement) stmt()                   {}
+func (*SelectStatement) stmt()                   {}
+
+func (*AlterRetentionPolicyStatement) StatementType() string    { return "ALTER RETENTION POLICY" }
+func (*CreateContinuousQueryStatement) StatementType() string   { return "CREATE CONTINUOUS QUERY" }
+func (*CreateDatabaseStatement) StatementType() string          { return "CREATE DATABASE" }
+func (*CreateRetentionPolicyStatement) StatementType() string   { return "CREATE RETENTION POLICY" }
+func (*CreateUserStatement) StatementType() string              { return "CREATE USER" }
+func (*DeleteStatement) StatementType() string                  { return "DELETE" }
+func (*DropContinuousQueryStatement) StatementType() string     { return "DROP CONTINUOUS QUERY" }
+func (*DropDatabaseStatement) StatementType() string            { return "DROP DATABASE" }
+func (*DropMeasurementStatement) StatementType() string         { return "DROP MEASUREMENT" }
+func (*DropRetentionPolicyStatement) StatementType() string     { return "DROP RETENTION POLICY" }
+func (*DropSeriesStatement) StatementType() string              { return "DROP SERIES" }
+func (*DropUserStatement) StatementType() string                { return "DROP USER" }
+func (*GrantStatement) StatementType() string                   { return "GRANT" }
+func (*ShowContinuousQueriesStatement) StatementType() string   { return "SHOW CONTINUOUS QUERIES" }
+func (*ShowDatabasesStatement) StatementType() string           { return "SHOW DATABASES" }
+func (*ShowFieldKeyCardinalityStatement) StatementType() string { return "SHOW FIELD KEY CARDINALITY" }
+func (*ShowFieldKeysStatement) StatementType() string           { return "SHOW FIELD KEYS" }
+func (*ShowMeasurementsStatement) StatementType() string        { return "SHOW MEASUREMENTS" }
+func (*ShowRetentionPoliciesStatement) StatementType() string   { return "SHOW RETENTION POLICIES" }
+func (*ShowSeriesStatement) StatementType() string              { return "SHOW SERIES" }
+func (*ShowTagKeyCardinalityStatement) StatementType() string   { return "SHOW TAG KEY CARDINALITY" }
+func (*ShowTagKeysStatement) StatementType() string             { return "SHOW TAG KEYS" }
+func (*ShowTagValuesCardinalityStatement) StatementType() string {
+	return "SHOW TAG VALUES CARDINALITY"
+}
+func (*ShowTagValuesStatement) StatementType() string { return "SHOW TAG VALUES" }
+func (*ShowUsersStatement) StatementType() string     { return "SHOW USERS" }
+func (*RevokeStatement) StatementType() string        { return "REVOKE" }
+func (*SelectStatement) StatementType() string        { return "SELECT" }
+
+func (s *AlterRetentionPolicyStatement) DefaultDatabase() string  { return s.Database }
+func (s *CreateContinuousQueryStatement) DefaultDatabase() string { return s.Database }
+func (s *CreateDatabaseStatement) DefaultDatabase() string        { return s.Name }
+func (s *CreateRetentionPolicyStatement) DefaultDatabase() string { return s.Database }
+func (*CreateUserStatement) DefaultDatabase() string              { return "" }
+func (s *DeleteStatement) DefaultDatabase() string                { return sourceDatabase(s.Source) }
+func (*DropContinuousQueryStatement) DefaultDatabase() string     { return "" }
+func (s *DropDatabaseStatement) DefaultDatabase() string          { return s.Name }
+func (s *DropMeasurementStatement) DefaultDatabase() string       { return identDatabase(s.Name) }
+func (s *DropRetentionPolicyStatement) DefaultDatabase() string   { return s.Database }
+func (s *DropSeriesStatement) DefaultDatabase() string            { return sourceDatabase(s.Source) }
+func (*DropUserStatement) DefaultDatabase() string                { return "" }
+func (s *GrantStatement) DefaultDatabase() string                 { return s.On }
+func (*ShowContinuousQueriesStatement) DefaultDatabase() string   { return "" }
+func (*ShowDatabasesStatement) DefaultDatabase() string           { return "" }
+func (s *ShowFieldKeyCardinalityStatement) DefaultDatabase() string {
+	return cardinalityDatabase(s.Database, s.Source)
+}
+func (s *ShowFieldKeysStatement) DefaultDatabase() string         { return sourceDatabase(s.Source) }
+func (*ShowMeasurementsStatement) DefaultDatabase() string        { return "" }
+func (s *ShowRetentionPoliciesStatement) DefaultDatabase() string { return s.Database }
+func (s *ShowSeriesStatement) DefaultDatabase() string            { return sourceDatabase(s.Source) }
+func (s *ShowTagKeyCardinalityStatement) DefaultDatabase() string {
+	return cardinalityDatabase(s.Database, s.Source)
+}
+func (s *ShowTagKeysStatement) DefaultDatabase() string { return sourceDatabase(s.Source) }
+func (s *ShowTagValuesCardinalityStatement) DefaultDatabase() string {
+	return cardinalityDatabase(s.Database, s.Source)
+}
+func (s *ShowTagValuesStatement) DefaultDatabase() string { return sourceDatabase(s.Source) }
+func (*ShowUsersStatement) DefaultDatabase() string       { return "" }
+func (s *RevokeStatement) DefaultDatabase() string        { return s.On }
+func (s *SelectStatement) DefaultDatabase() string        { return sourceDatabase(s.Source) }
+
+// sourceDatabase returns the database named by a source's measurements.
+// An empty string is returned if the measurements are not qualified with a
+// database or if they name different databases.
+func sourceDatabase(src Source) string {
+	var a Measurements
+	switch src := src.(type) {
+	case *Measurement:
+		return identDatabase(src.Name)
+	case *Join:
+		a = src.Measurements
+	case *Merge:
+		a = src.Measurements
+	}
+
+	var db string
+	for i, m := range a {
+		if other := identDatabase(m.Name); i == 0 {
+			db = other
+		} else if other != db {
+			return ""
+		}
+	}
+	return db
+}
+
+// identDatabase returns the database segment of a fully qualified
+// "db"."rp"."measurement" identifier or an empty string otherwise.
+func identDatabase(name string) string {
+	if segments, err := SplitIdent(name); err == nil && len(segments) == 3 {
+		return segments[0]
+	}
+	return ""
+}
 
 // Expr represents an expression that can be evaluated to a value.
 type Expr interface {
@@ -182,6 +346,7 @@ func (*BinaryExpr) expr()      {}
 func (*BooleanLiteral) expr()  {}
 func (*Call) expr()            {}
 func (*DurationLiteral) expr() {}
+func (*ListLiteral) expr()     {}
 func (*nilLiteral) expr()      {}
 func (*NumberLiteral) expr()   {}
 func (*ParenExpr) expr()       {}
@@ -213,9 +378,15 @@ type SortField struct {
 // String returns a string representation of a sort field
 func (field *SortField) String() string {
 	var buf bytes.Buffer
-	_, _ = buf.WriteString(field.Name)
-	_, _ = buf.WriteString(" ")
-	_, _ = buf.WriteString(strconv.FormatBool(field.Ascending))
+	if field.Name != "" {
+		_, _ = buf.WriteString(field.Name)
+		_, _ = buf.WriteString(" ")
+	}
+	if field.Ascending {
+		_, _ = buf.WriteString("ASC")
+	} else {
+		_, _ = buf.WriteString("DESC")
+	}
 	return buf.String()
 }
 
@@ -231,6 +402,18 @@ func (a SortFields) String() string {
 	return strings.Join(fields, ", ")
 }
 
+// clone returns a deep copy of the sort fields.
+func (a SortFields) clone() SortFields {
+	if a == nil {
+		return nil
+	}
+	other := make(SortFields, len(a))
+	for i, f := range a {
+		other[i] = &SortField{Name: f.Name, Ascending: f.Ascending}
+	}
+	return other
+}
+
 // CreateDatabaseStatement represents a command for creating a new database.
 type CreateDatabaseStatement struct {
 	// Name of the database to be created.
@@ -301,17 +484,24 @@ type CreateUserStatement struct {
 	// User's password
 	Password string
 
+	// True if Password is already a bcrypt hash.
+	Hashed bool
+
 	// User's privilege level.
 	Privilege *Privilege
 }
 
 // String returns a string representation of the create user statement.
+// The password is redacted so statements can be logged safely.
 func (s *CreateUserStatement) String() string {
 	var buf bytes.Buffer
 	_, _ = buf.WriteString("CREATE USER ")
 	_, _ = buf.WriteString(s.Name)
 	_, _ = buf.WriteString(" WITH PASSWORD ")
-	_, _ = buf.WriteString(s.Password)
+	if s.Hashed {
+		_, _ = buf.WriteString("HASH ")
+	}
+	_, _ = buf.WriteString("'[REDACTED]'")
 
 	if s.Privilege != nil {
 		_, _ = buf.WriteString(" WITH ")
@@ -377,10 +567,33 @@ func (p Privilege) String() string {
 	return ""
 }
 
+// Privileges represents a list of privileges.
+type Privileges []Privilege
+
+// String returns a string representation of a list of privileges.
+func (a Privileges) String() string {
+	var str []string
+	for _, p := range a {
+		str = append(str, p.String())
+	}
+	return strings.Join(str, ", ")
+}
+
+// Max returns the highest privilege in the list.
+func (a Privileges) Max() Privilege {
+	max := NoPrivileges
+	for _, p := range a {
+		if p > max {
+			max = p
+		}
+	}
+	return max
+}
+
 // GrantStatement represents a command for granting a privilege.
 type GrantStatement struct {
-	// The privilege to be granted.
-	Privilege Privilege
+	// The privileges to be granted.
+	Privileges Privileges
 
 	// Thing to grant privilege on (e.g., a DB).
 	On string
@@ -393,7 +606,7 @@ type GrantStatement struct {
 func (s *GrantStatement) String() string {
 	var buf bytes.Buffer
 	_, _ = buf.WriteString("GRANT ")
-	_, _ = buf.WriteString(s.Privilege.String())
+	_, _ = buf.WriteString(s.Privileges.String())
 	if s.On != "" {
 		_, _ = buf.WriteString(" ON ")
 		_, _ = buf.WriteString(s.On)
@@ -410,8 +623,8 @@ func (s *GrantStatement) RequiredPrivileges() ExecutionPrivileges {
 
 // RevokeStatement represents a command to revoke a privilege from a user.
 type RevokeStatement struct {
-	// Privilege to be revoked.
-	Privilege Privilege
+	// Privileges to be revoked.
+	Privileges Privileges
 
 	// Thing to revoke privilege to (e.g., a DB)
 	On string
@@ -424,7 +637,7 @@ type RevokeStatement struct {
 func (s *RevokeStatement) String() string {
 	var buf bytes.Buffer
 	_, _ = buf.WriteString("REVOKE ")
-	_, _ = buf.WriteString(s.Privilege.String())
+	_, _ = buf.WriteString(s.Privileges.String())
 	if s.On != "" {
 		_, _ = buf.WriteString(" ON ")
 		_, _ = buf.WriteString(s.On)
@@ -495,6 +708,9 @@ type AlterRetentionPolicyStatement struct {
 
 	// Should this policy be set as defalut for the database?
 	Default bool
+
+	// New name of the policy, if it is being renamed.
+	NewName string
 }
 
 // String returns a string representation of the alter retention policy statement.
@@ -519,6 +735,11 @@ func (s *AlterRetentionPolicyStatement) String() string {
 		_, _ = buf.WriteString(" DEFAULT")
 	}
 
+	if s.NewName != "" {
+		_, _ = buf.WriteString(" RENAME TO ")
+		_, _ = buf.WriteString(s.NewName)
+	}
+
 	return buf.String()
 }
 
@@ -554,6 +775,12 @@ type SelectStatement struct {
 	// Returns rows starting at an offset from the first row.
 	Offset int
 
+	// HasLimit and HasOffset are set when the LIMIT or OFFSET clause was
+	// specified, which allows an explicit "OFFSET 0" to be distinguished
+	// from no offset at all.
+	HasLimit  bool
+	HasOffset bool
+
 	// memoize the group by interval
 	groupByInterval time.Duration
 
@@ -564,27 +791,20 @@ type SelectStatement struct {
 // Clone returns a deep copy of the statement.
 func (s *SelectStatement) Clone() *SelectStatement {
 	other := &SelectStatement{
-		Fields:     make(Fields, len(s.Fields)),
-		Dimensions: make(Dimensions, len(s.Dimensions)),
+		Fields:     s.Fields.clone(),
+		Dimensions: s.Dimensions.clone(),
 		Source:     cloneSource(s.Source),
-		SortFields: make(SortFields, len(s.SortFields)),
+		SortFields: s.SortFields.clone(),
 		Condition:  CloneExpr(s.Condition),
 		Limit:      s.Limit,
 		Offset:     s.Offset,
+		HasLimit:   s.HasLimit,
+		HasOffset:  s.HasOffset,
+		RawQuery:   s.RawQuery,
 	}
 	if s.Target != nil {
 		other.Target = &Target{Measurement: s.Target.Measurement, Database: s.Target.Database}
 	}
-	for i, f := range s.Fields {
-		other.Fields[i] = &Field{Expr: CloneExpr(f.Expr), Alias: f.Alias}
-	}
-	for i, d := range s.Dimensions {
-		other.Dimensions[i] = &Dimension{Expr: CloneExpr(d.Expr)}
-	}
-	// TODO: Copy sources.
-	for i, f := range s.SortFields {
-		other.SortFields[i] = &SortField{Name: f.Name, Ascending: f.Ascending}
-	}
 	return other
 }
 
@@ -595,7 +815,11 @@ func cloneSource(s Source) Source {
 
 	switch s := s.(type) {
 	case *Measurement:
-		return &Measurement{Name: s.Name}
+		other := &Measurement{Name: s.Name}
+		if s.Regex != nil {
+			other.Regex = &RegexLiteral{Val: s.Regex.Val}
+		}
+		return other
 	case *Join:
 		other := &Join{Measurements: make(Measurements, len(s.Measurements))}
 		for i, m := range s.Measurements {
@@ -613,6 +837,32 @@ func cloneSource(s Source) Source {
 	}
 }
 
+// RewriteMeasurements returns a copy of the statement with every measurement
+// in the source, including join and merge lists, and the INTO target replaced
+// by the result of fn. The function is passed copies so the original statement
+// is not modified.
+func (s *SelectStatement) RewriteMeasurements(fn func(*Measurement) *Measurement) *SelectStatement {
+	other := s.Clone()
+
+	switch src := other.Source.(type) {
+	case *Measurement:
+		other.Source = fn(src)
+	case *Join:
+		for i, m := range src.Measurements {
+			src.Measurements[i] = fn(m)
+		}
+	case *Merge:
+		for i, m := range src.Measurements {
+			src.Measurements[i] = fn(m)
+		}
+	}
+
+	if other.Target != nil {
+		other.Target.Measurement = fn(&Measurement{Name: other.Target.Measurement}).Name
+	}
+	return other
+}
+
 // RewriteWildcards returns the re-written form of the select statement. Any wildcard query
 // fields are replaced with the supplied fields, and any wildcard GROUP BY fields are replaced
 // with the supplied dimensions.
@@ -622,9 +872,32 @@ func (s *SelectStatement) RewriteWildcards(fields Fields, dimensions Dimensions)
 	// Rewrite all wildcard query fields
 	rwFields := make(Fields, 0, len(s.Fields))
 	for _, f := range s.Fields {
-		switch f.Expr.(type) {
+		switch expr := f.Expr.(type) {
 		case *Wildcard:
 			rwFields = append(rwFields, fields...)
+		case *Call:
+			if !isWildcardCall(expr) {
+				rwFields = append(rwFields, f)
+				continue
+			}
+
+			// Expand a call over a wildcard to one call per supported field.
+			// Each call is aliased with its field name to keep columns distinct.
+			prefix := f.Alias
+			if prefix == "" {
+				prefix = expr.Name
+			}
+			numeric := wildcardFunctions[strings.ToLower(expr.Name)]
+			for _, field := range fields {
+				ref, ok := field.Expr.(*VarRef)
+				if !ok || ref.Type == Tag || (numeric && (ref.Type == String || ref.Type == Boolean)) {
+					continue
+				}
+				rwFields = append(rwFields, &Field{
+					Expr:  &Call{Name: expr.Name, Args: []Expr{&VarRef{Val: ref.Val, Type: ref.Type}}},
+					Alias: prefix + "_" + ref.Val,
+				})
+			}
 		default:
 			rwFields = append(rwFields, f)
 		}
@@ -656,8 +929,10 @@ func (s *SelectStatement) String() string {
 		_, _ = buf.WriteString(" ")
 		_, _ = buf.WriteString(s.Target.String())
 	}
-	_, _ = buf.WriteString(" FROM ")
-	_, _ = buf.WriteString(s.Source.String())
+	if s.Source != nil {
+		_, _ = buf.WriteString(" FROM ")
+		_, _ = buf.WriteString(s.Source.String())
+	}
 	if s.Condition != nil {
 		_, _ = buf.WriteString(" WHERE ")
 		_, _ = buf.WriteString(s.Condition.String())
@@ -670,163 +945,736 @@ func (s *SelectStatement) String() string {
 		_, _ = buf.WriteString(" ORDER BY ")
 		_, _ = buf.WriteString(s.SortFields.String())
 	}
-	if s.Limit > 0 {
+	if s.Limit > 0 || s.HasLimit {
 		_, _ = fmt.Fprintf(&buf, " LIMIT %d", s.Limit)
 	}
-	if s.Offset > 0 {
+	if s.Offset > 0 || s.HasOffset {
 		_, _ = buf.WriteString(" OFFSET ")
 		_, _ = buf.WriteString(strconv.Itoa(s.Offset))
 	}
 	return buf.String()
 }
 
-// RequiredPrivileges returns the privilege required to execute the SelectStatement.
-func (s *SelectStatement) RequiredPrivileges() ExecutionPrivileges {
-	ep := ExecutionPrivileges{{Name: "", Privilege: ReadPrivilege}}
-
-	if s.Target != nil {
-		p := ExecutionPrivilege{Name: s.Target.Database, Privilege: WritePrivilege}
-		ep = append(ep, p)
+// Validate performs checks on the statement that the parser does not enforce.
+// It returns an error for invalid dimensions, duplicate field aliases, fields
+// without a source, lists outside function arguments, and function calls that
+// are invalid for their arguments or the GROUP BY clause.
+func (s *SelectStatement) Validate() error {
+	if err := s.Fields.Validate(); err != nil {
+		return err
+	}
+	if s.Source == nil && s.Fields.hasVarRefs() {
+		return errors.New("fields require a FROM clause")
 	}
-	return ep
-}
 
-// Aggregated returns true if the statement uses aggregate functions.
-func (s *SelectStatement) Aggregated() bool {
-	var v bool
-	WalkFunc(s.Fields, func(n Node) {
-		if _, ok := n.(*Call); ok {
-			v = true
+	// Fields without a source are evaluated once so now() is the only
+	// function that can be called.
+	if s.Source == nil {
+		var err error
+		WalkFunc(s.Fields, func(n Node) {
+			if call, ok := n.(*Call); ok && err == nil && (strings.ToLower(call.Name) != "now" || len(call.Args) != 0) {
+				err = fmt.Errorf("invalid function %s: only now() can be called without a FROM clause", call)
+			}
+		})
+		if err != nil {
+			return err
 		}
-	})
-	return v
-}
-
-// OnlyTimeDimensions returns true if the statement has a where clause with only time constraints
-func (s *SelectStatement) OnlyTimeDimensions() bool {
-	return s.walkForTime(s.Condition)
-}
+	}
 
-// walkForTime is called by the OnlyTimeDimensions method to walk the where clause to determine if
-// the only things specified are based on time
-func (s *SelectStatement) walkForTime(node Node) bool {
-	switch n := node.(type) {
-	case *BinaryExpr:
-		if n.Op == AND || n.Op == OR {
-			return s.walkForTime(n.LHS) && s.walkForTime(n.RHS)
-		}
-		if ref, ok := n.LHS.(*VarRef); ok && strings.ToLower(ref.Val) == "time" {
-			return true
+	// Arithmetic between aggregates is allowed but raw values cannot be
+	// combined with them since there is no single row to take them from.
+	if s.Aggregated() {
+		for _, f := range s.Fields {
+			if hasRawRefs(f.Expr) {
+				return fmt.Errorf("invalid field %s: mixing aggregate and non-aggregate values is not supported", f)
+			}
 		}
-		return false
-	case *ParenExpr:
-		// walk down the tree
-		return s.walkForTime(n.Expr)
-	default:
-		return false
 	}
-}
 
-// HasWildcard returns whether or not the select statement has at least 1 wildcard
-func (s *SelectStatement) HasWildcard() bool {
+	// A wildcard argument is expanded to one call per field so it can only
+	// be the sole argument of a supported function that is the whole field.
 	for _, f := range s.Fields {
-		_, ok := f.Expr.(*Wildcard)
-		if ok {
-			return true
+		if err := validateWildcardArgs(f.Expr, true); err != nil {
+			return err
 		}
 	}
 
-	for _, d := range s.Dimensions {
-		_, ok := d.Expr.(*Wildcard)
-		if ok {
-			return true
+	// Functions with a fixed signature must be called with valid arguments.
+	var err error
+	WalkFunc(s.Fields, func(n Node) {
+		if call, ok := n.(*Call); ok && err == nil {
+			err = validateCallArgs(call)
 		}
+	})
+	if err != nil {
+		return err
 	}
 
-	return false
-}
-
-// GroupByIterval extracts the time interval, if specified.
-func (s *SelectStatement) GroupByInterval() (time.Duration, error) {
-	// return if we've already pulled it out
-	if s.groupByInterval != 0 {
-		return s.groupByInterval, nil
+	// Lists are passed to functions as a single value of one type.
+	for _, f := range s.Fields {
+		if err := validateListArgs(f.Expr, false); err != nil {
+			return err
+		}
 	}
-
-	// Ignore if there are no dimensions.
-	if len(s.Dimensions) == 0 {
-		return 0, nil
+	if err := validateListArgs(s.Condition, false); err != nil {
+		return err
 	}
 
-	for _, d := range s.Dimensions {
-		if call, ok := d.Expr.(*Call); ok && strings.ToLower(call.Name) == "time" {
-			// Make sure there is exactly one argument.
-			if len(call.Args) != 1 {
-				return 0, errors.New("time dimension expected one argument")
+	// The time column is the key of each aggregate so it cannot be an argument.
+	WalkFunc(s.Fields, func(n Node) {
+		if call, ok := n.(*Call); ok && err == nil {
+			for _, arg := range call.Args {
+				if ref, ok := arg.(*VarRef); ok && strings.ToLower(ref.Val) == "time" {
+					err = fmt.Errorf("invalid function %s: cannot aggregate the time column", call)
+				}
 			}
+		}
+	})
+	if err != nil {
+		return err
+	}
 
-			// Ensure the argument is a duration.
-			lit, ok := call.Args[0].(*DurationLiteral)
-			if !ok {
-				return 0, errors.New("time dimension must have one duration argument")
+	// Conditions parse as arguments but only predicate functions accept them.
+	WalkFunc(s.Fields, func(n Node) {
+		if call, ok := n.(*Call); ok && err == nil && !predicateFunctions[strings.ToLower(call.Name)] {
+			for _, arg := range call.Args {
+				if isPredicate(arg) {
+					err = fmt.Errorf("invalid function %s: condition %s not allowed as an argument", call, arg)
+					break
+				}
 			}
-			s.groupByInterval = lit.Val
-			return lit.Val, nil
 		}
+	})
+	if err != nil {
+		return err
 	}
-	return 0, nil
-}
 
-// SetTimeRange sets the start and end time of the select statement to [start, end). i.e. start inclusive, end exclusive.
-// This is used commonly for continuous queries so the start and end are in buckets.
-func (s *SelectStatement) SetTimeRange(start, end time.Time) error {
-	cond := fmt.Sprintf("time >= '%s' AND time < '%s'", start.UTC().Format(time.RFC3339Nano), end.UTC().Format(time.RFC3339Nano))
-	if s.Condition != nil {
-		cond = fmt.Sprintf("%s AND %s", s.rewriteWithoutTimeDimensions(), cond)
+	if err := s.Dimensions.Validate(); err != nil {
+		return err
 	}
 
-	expr, err := NewParser(strings.NewReader(cond)).ParseExpr()
-	if err != nil {
-		return err
+	var timeDim *Call
+	for _, dim := range s.Dimensions {
+		if call, ok := dim.Expr.(*Call); ok && strings.ToLower(call.Name) == "time" {
+			timeDim = call
+		}
 	}
 
-	// fold out any previously replaced time dimensios and set the condition
-	s.Condition = Reduce(expr, nil)
+	// Grouping by a time interval combines raw values into buckets.
+	if timeDim != nil && !s.Aggregated() {
+		return fmt.Errorf("invalid dimension %s: GROUP BY time() requires an aggregate function", timeDim)
+	}
 
+	// Some functions compare consecutive buckets so they need an interval.
+	if timeDim == nil {
+		WalkFunc(s.Fields, func(n Node) {
+			if call, ok := n.(*Call); ok && err == nil && timeGroupedFunctions[strings.ToLower(call.Name)] {
+				err = fmt.Errorf("invalid function %s: GROUP BY time() is required", call)
+			}
+		})
+	}
+	return err
+}
+
+// timeGroupedFunctions are the functions that operate on consecutive time
+// buckets and can only be used with a GROUP BY time() interval.
+var timeGroupedFunctions = map[string]bool{
+	"derivative":              true,
+	"non_negative_derivative": true,
+	"difference":              true,
+	"holt_winters":            true,
+	"holt_winters_with_fit":   true,
+}
+
+// validateCallArgs returns an error if the arguments of call do not match
+// the signature of the function. Functions without a known signature are
+// not checked.
+func validateCallArgs(call *Call) error {
+	switch strings.ToLower(call.Name) {
+	case "sample":
+		// sample(field, N) returns N random values of the field.
+		if len(call.Args) != 2 {
+			return fmt.Errorf("invalid function %s: expected 2 arguments, got %d", call, len(call.Args))
+		} else if _, ok := call.Args[0].(*VarRef); !ok {
+			return fmt.Errorf("invalid function %s: first argument must be a field", call)
+		} else if !isIntegerLiteral(call.Args[1], 1) {
+			return fmt.Errorf("invalid function %s: second argument must be a positive integer", call)
+		}
+	case "derivative", "non_negative_derivative", "elapsed":
+		// derivative(expr, unit) returns the rate of change per unit.
+		// elapsed(field, unit) returns the time between values in units.
+		if len(call.Args) < 1 || len(call.Args) > 2 {
+			return fmt.Errorf("invalid function %s: expected 1 or 2 arguments, got %d", call, len(call.Args))
+		} else if len(call.Args) == 2 {
+			if _, ok := call.Args[1].(*DurationLiteral); !ok {
+				return fmt.Errorf("invalid function %s: second argument must be a duration", call)
+			}
+		}
+		if _, ok := call.Args[0].(*VarRef); !ok && strings.ToLower(call.Name) == "elapsed" {
+			return fmt.Errorf("invalid function %s: first argument must be a field", call)
+		}
+	case "moving_average":
+		// moving_average(expr, N) averages each window of N values.
+		if len(call.Args) != 2 {
+			return fmt.Errorf("invalid function %s: expected 2 arguments, got %d", call, len(call.Args))
+		} else if !isIntegerLiteral(call.Args[1], 2) {
+			return fmt.Errorf("invalid function %s: second argument must be an integer greater than 1", call)
+		}
+	case "cumulative_sum":
+		// cumulative_sum(expr) returns the running total of the values.
+		if len(call.Args) != 1 {
+			return fmt.Errorf("invalid function %s: expected 1 argument, got %d", call, len(call.Args))
+		}
+	case "holt_winters", "holt_winters_with_fit":
+		// holt_winters(aggregate, N, S) predicts N values with seasonal pattern S.
+		if len(call.Args) != 3 {
+			return fmt.Errorf("invalid function %s: expected 3 arguments, got %d", call, len(call.Args))
+		} else if _, ok := call.Args[0].(*Call); !ok {
+			return fmt.Errorf("invalid function %s: first argument must be an aggregate function", call)
+		} else if !isIntegerLiteral(call.Args[1], 1) {
+			return fmt.Errorf("invalid function %s: second argument must be a positive integer", call)
+		} else if !isIntegerLiteral(call.Args[2], 0) {
+			return fmt.Errorf("invalid function %s: third argument must be a non-negative integer", call)
+		}
+	}
 	return nil
 }
 
-// rewriteWithoutTimeDimensions will remove any WHERE time... clauses from the select statement
-// This is necessary when setting an explicit time range to override any that previously existed.
-func (s *SelectStatement) rewriteWithoutTimeDimensions() string {
-	n := RewriteFunc(s.Condition, func(n Node) Node {
-		switch n := n.(type) {
-		case *BinaryExpr:
-			if n.LHS.String() == "time" {
-				return &BooleanLiteral{Val: true}
-			}
-			return n
-		case *Call:
-			return &BooleanLiteral{Val: true}
-		default:
-			return n
-		}
-	})
+// predicateFunctions are the functions that accept a condition, such as
+// "host = 'a'", as an argument. No built-in function accepts one yet.
+var predicateFunctions = map[string]bool{}
 
-	return n.String()
+// isPredicate returns true if expr is a comparison or logical expression.
+func isPredicate(expr Expr) bool {
+	for {
+		paren, ok := expr.(*ParenExpr)
+		if !ok {
+			break
+		}
+		expr = paren.Expr
+	}
+	bin, ok := expr.(*BinaryExpr)
+	return ok && ExprDataType(bin) == Boolean
+}
+
+// isIntegerLiteral returns true if expr is an integral number literal of
+// at least min.
+func isIntegerLiteral(expr Expr, min float64) bool {
+	lit, ok := expr.(*NumberLiteral)
+	return ok && lit.Val == math.Trunc(lit.Val) && lit.Val >= min
+}
+
+// wildcardFunctions are the functions that accept a wildcard argument. The
+// value is true if the function only applies to numeric fields.
+var wildcardFunctions = map[string]bool{
+	"count":  false,
+	"first":  false,
+	"last":   false,
+	"max":    true,
+	"mean":   true,
+	"min":    true,
+	"spread": true,
+	"stddev": true,
+	"sum":    true,
+}
+
+// isWildcardCall returns true if call has a single wildcard argument.
+func isWildcardCall(call *Call) bool {
+	if len(call.Args) != 1 {
+		return false
+	}
+	_, ok := call.Args[0].(*Wildcard)
+	return ok
 }
 
-/*
+// validateWildcardArgs returns an error if expr contains a call with a
+// wildcard argument that cannot be expanded. The top flag is true if expr
+// is the entire expression of a field.
+func validateWildcardArgs(expr Expr, top bool) error {
+	switch expr := expr.(type) {
+	case *Call:
+		for _, arg := range expr.Args {
+			if _, ok := arg.(*Wildcard); !ok {
+				if err := validateWildcardArgs(arg, false); err != nil {
+					return err
+				}
+				continue
+			}
 
-BinaryExpr
+			if _, ok := wildcardFunctions[strings.ToLower(expr.Name)]; !ok {
+				return fmt.Errorf("invalid function %s: wildcard argument not supported", expr)
+			} else if !top || len(expr.Args) != 1 {
+				return fmt.Errorf("invalid function %s: wildcard must be the only argument of a field", expr)
+			}
+		}
+	case *BinaryExpr:
+		if err := validateWildcardArgs(expr.LHS, false); err != nil {
+			return err
+		}
+		return validateWildcardArgs(expr.RHS, false)
+	case *ParenExpr:
+		return validateWildcardArgs(expr.Expr, false)
+	}
+	return nil
+}
 
-SELECT mean(xxx.value) + avg(yyy.value) FROM xxx JOIN yyy WHERE xxx.host = 123
+// validateListArgs returns an error if expr contains a list that is not a
+// function argument or whose elements are not literals of the same type.
+// The arg flag is true if expr is an argument of a call.
+func validateListArgs(expr Expr, arg bool) error {
+	switch expr := expr.(type) {
+	case *ListLiteral:
+		if !arg {
+			return fmt.Errorf("invalid list %s: only supported as a function argument", expr)
+		}
 
-from xxx where host = 123
-select avg(value) from yyy where host = 123
+		var typ DataType
+		for _, v := range expr.Vals {
+			vtyp := literalDataType(v)
+			if vtyp == Unknown {
+				return fmt.Errorf("invalid list %s: element %s is not a literal", expr, v)
+			} else if typ != Unknown && vtyp != typ {
+				return fmt.Errorf("invalid list %s: mixed element types %s and %s", expr, typ, vtyp)
+			}
+			typ = vtyp
+		}
+	case *Call:
+		for _, arg := range expr.Args {
+			if err := validateListArgs(arg, true); err != nil {
+				return err
+			}
+		}
+	case *BinaryExpr:
+		if err := validateListArgs(expr.LHS, false); err != nil {
+			return err
+		}
+		return validateListArgs(expr.RHS, false)
+	case *ParenExpr:
+		return validateListArgs(expr.Expr, false)
+	}
+	return nil
+}
 
-SELECT xxx.value FROM xxx WHERE xxx.host = 123
-SELECT yyy.value FROM yyy
+// literalDataType returns the data type of a literal.
+// Returns Unknown if expr is not a literal.
+func literalDataType(expr Expr) DataType {
+	switch expr.(type) {
+	case *NumberLiteral:
+		return Number
+	case *BooleanLiteral:
+		return Boolean
+	case *StringLiteral:
+		return String
+	case *TimeLiteral:
+		return Time
+	case *DurationLiteral:
+		return Duration
+	}
+	return Unknown
+}
+
+// ExprDataType returns the data type that expr evaluates to when it can be
+// determined from its literals, operators and type hints alone. Integral
+// number literals are integers. Arithmetic on two integers is an integer
+// except for division, which is a float like any arithmetic involving a
+// float. Comparisons are boolean. Returns Unknown for references without a
+// type hint, function calls and invalid combinations such as 'a' + 1.
+func ExprDataType(expr Expr) DataType {
+	switch expr := expr.(type) {
+	case *NumberLiteral:
+		if expr.Val == math.Trunc(expr.Val) {
+			return Integer
+		}
+		return Float
+	case *BooleanLiteral:
+		return Boolean
+	case *StringLiteral:
+		return String
+	case *TimeLiteral:
+		return Time
+	case *DurationLiteral:
+		return Duration
+	case *VarRef:
+		switch expr.Type {
+		case Float, Integer, String, Boolean:
+			return expr.Type
+		}
+	case *ParenExpr:
+		return ExprDataType(expr.Expr)
+	case *BinaryExpr:
+		return binaryExprDataType(expr.Op, ExprDataType(expr.LHS), ExprDataType(expr.RHS))
+	}
+	return Unknown
+}
+
+// binaryExprDataType returns the data type of applying op to operands of
+// type lhs and rhs.
+func binaryExprDataType(op Token, lhs, rhs DataType) DataType {
+	switch op {
+	case AND, OR, EQ, NEQ, EQREGEX, NEQREGEX, LIKE, NLIKE, LT, LTE, GT, GTE:
+		return Boolean
+	}
+
+	isNumber := func(typ DataType) bool { return typ == Integer || typ == Float }
+	switch {
+	case lhs == Unknown || rhs == Unknown:
+		return Unknown
+	case isNumber(lhs) && isNumber(rhs):
+		if lhs == Integer && rhs == Integer && op != DIV {
+			return Integer
+		}
+		return Float
+	case lhs == Time && rhs == Duration && (op == ADD || op == SUB):
+		return Time
+	case lhs == Duration && rhs == Time && op == ADD:
+		return Time
+	case lhs == Time && rhs == Time && op == SUB:
+		return Duration
+	case lhs == Duration && rhs == Duration && (op == ADD || op == SUB):
+		return Duration
+	case lhs == Duration && isNumber(rhs) && (op == MUL || op == DIV):
+		return Duration
+	case isNumber(lhs) && rhs == Duration && op == MUL:
+		return Duration
+	}
+	return Unknown
+}
+
+// hasRawRefs returns true if expr references a variable outside of a call.
+// The time column is not considered raw as every aggregate has a time.
+func hasRawRefs(expr Expr) bool {
+	switch expr := expr.(type) {
+	case *VarRef:
+		return strings.ToLower(expr.Val) != "time"
+	case *BinaryExpr:
+		return hasRawRefs(expr.LHS) || hasRawRefs(expr.RHS)
+	case *ParenExpr:
+		return hasRawRefs(expr.Expr)
+	}
+	return false
+}
+
+// RequiredPrivileges returns the privilege required to execute the SelectStatement.
+func (s *SelectStatement) RequiredPrivileges() ExecutionPrivileges {
+	ep := ExecutionPrivileges{{Name: "", Privilege: ReadPrivilege}}
+
+	if s.Target != nil {
+		p := ExecutionPrivilege{Name: s.Target.Database, Privilege: WritePrivilege}
+		ep = append(ep, p)
+	}
+	return ep
+}
+
+// Aggregated returns true if the statement uses aggregate functions.
+func (s *SelectStatement) Aggregated() bool {
+	var v bool
+	WalkFunc(s.Fields, func(n Node) {
+		if _, ok := n.(*Call); ok {
+			v = true
+		}
+	})
+	return v
+}
+
+// HasTimeRange returns true if the condition bounds time from below or above.
+// Only comparisons joined to the condition by AND are considered since a
+// bound on one side of an OR does not restrict the other side.
+func (s *SelectStatement) HasTimeRange() bool {
+	// Fold now() so relative bounds such as "time > now() - 1h" are found.
+	cond := Reduce(s.Condition, &NowValuer{Now: time.Now()})
+	for _, term := range Conjuncts(cond) {
+		if e, ok := term.(*BinaryExpr); ok && e.Op == OR {
+			continue
+		}
+		if min, max := TimeRange(term); !min.IsZero() || !max.IsZero() {
+			return true
+		}
+	}
+	return false
+}
+
+// Complexity returns a heuristic estimate of the cost of executing the
+// statement, for use in rate limiting. It only inspects the AST so it does
+// not account for the number of series or points. The score is the sum of:
+//
+//	2   per measurement read, including each measurement of a join or merge
+//	10  for a regex source, which can match any number of measurements
+//	1   per function call in the fields
+//	5   per regex comparison in the condition
+//	2   per tag in the GROUP BY clause
+//	10  for GROUP BY *
+//
+// The sum is multiplied by 10 if the condition has no lower time bound since
+// every point of the source is read. Higher scores are more expensive.
+func (s *SelectStatement) Complexity() int {
+	var n int
+	switch src := s.Source.(type) {
+	case *Measurement:
+		if src.Regex != nil {
+			n += 10
+		} else {
+			n += 2
+		}
+	case *Join:
+		n += 2 * len(src.Measurements)
+	case *Merge:
+		n += 2 * len(src.Measurements)
+	}
+
+	WalkFunc(s.Fields, func(node Node) {
+		if _, ok := node.(*Call); ok {
+			n++
+		}
+	})
+
+	WalkFunc(s.Condition, func(node Node) {
+		if e, ok := node.(*BinaryExpr); ok && (e.Op == EQREGEX || e.Op == NEQREGEX) {
+			n += 5
+		}
+	})
+
+	for _, d := range s.Dimensions {
+		switch d.Expr.(type) {
+		case *VarRef:
+			n += 2
+		case *Wildcard:
+			n += 10
+		}
+	}
+
+	// Fold now() so relative bounds such as "time > now() - 1h" are found.
+	// As in HasTimeRange, a bound inside an OR does not limit the scan.
+	cond := Reduce(s.Condition, &NowValuer{Now: time.Now()})
+	bounded := false
+	for _, term := range Conjuncts(cond) {
+		if e, ok := term.(*BinaryExpr); ok && e.Op == OR {
+			continue
+		}
+		if min, _ := TimeRange(term); !min.IsZero() {
+			bounded = true
+		}
+	}
+	if !bounded {
+		n *= 10
+	}
+	return n
+}
+
+// NamesInSelect returns the unique field names referenced in the select fields.
+// References qualified as tags (e.g. "host::tag") are not included.
+func (s *SelectStatement) NamesInSelect() []string {
+	var a []string
+	m := make(map[string]struct{})
+	WalkFunc(s.Fields, func(n Node) {
+		if ref, ok := n.(*VarRef); ok && ref.Type != Tag {
+			if _, ok := m[ref.Val]; !ok {
+				m[ref.Val] = struct{}{}
+				a = append(a, ref.Val)
+			}
+		}
+	})
+	return a
+}
+
+// OnlyTimeDimensions returns true if the statement has a where clause with only time constraints
+func (s *SelectStatement) OnlyTimeDimensions() bool {
+	return s.walkForTime(s.Condition)
+}
+
+// walkForTime is called by the OnlyTimeDimensions method to walk the where clause to determine if
+// the only things specified are based on time
+func (s *SelectStatement) walkForTime(node Node) bool {
+	switch n := node.(type) {
+	case *BinaryExpr:
+		if n.Op == AND || n.Op == OR {
+			return s.walkForTime(n.LHS) && s.walkForTime(n.RHS)
+		}
+		if ref, ok := n.LHS.(*VarRef); ok && strings.ToLower(ref.Val) == "time" {
+			return true
+		}
+		return false
+	case *ParenExpr:
+		// walk down the tree
+		return s.walkForTime(n.Expr)
+	default:
+		return false
+	}
+}
+
+// HasWildcard returns whether or not the select statement has at least 1 wildcard
+func (s *SelectStatement) HasWildcard() bool {
+	for _, f := range s.Fields {
+		switch expr := f.Expr.(type) {
+		case *Wildcard:
+			return true
+		case *Call:
+			if isWildcardCall(expr) {
+				return true
+			}
+		}
+	}
+
+	for _, d := range s.Dimensions {
+		_, ok := d.Expr.(*Wildcard)
+		if ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GroupByIterval extracts the time interval, if specified.
+func (s *SelectStatement) GroupByInterval() (time.Duration, error) {
+	// return if we've already pulled it out
+	if s.groupByInterval != 0 {
+		return s.groupByInterval, nil
+	}
+
+	// Ignore if there are no dimensions.
+	if len(s.Dimensions) == 0 {
+		return 0, nil
+	}
+
+	for _, d := range s.Dimensions {
+		if call, ok := d.Expr.(*Call); ok && strings.ToLower(call.Name) == "time" {
+			// Make sure there is exactly one argument.
+			if len(call.Args) != 1 {
+				return 0, errors.New("time dimension expected one argument")
+			}
+
+			// Ensure the argument is a duration.
+			lit, ok := call.Args[0].(*DurationLiteral)
+			if !ok {
+				return 0, errors.New("time dimension must have one duration argument")
+			}
+			s.groupByInterval = lit.Val
+			return lit.Val, nil
+		}
+	}
+	return 0, nil
+}
+
+// SetTimeRange sets the start and end time of the select statement to [start, end). i.e. start inclusive, end exclusive.
+// This is used commonly for continuous queries so the start and end are in buckets.
+// Existing time comparisons are removed, including those inside OR terms, so
+// the new range cannot contradict them. Other predicates are kept.
+func (s *SelectStatement) SetTimeRange(start, end time.Time) error {
+	var cond Expr
+	for _, expr := range Conjuncts(s.Condition) {
+		if expr = removeTimeComparisons(expr); expr != nil {
+			cond = conjoin(cond, expr)
+		}
+	}
+
+	ref := &VarRef{Val: "time"}
+	cond = conjoin(cond, &BinaryExpr{Op: GTE, LHS: ref, RHS: &TimeLiteral{Val: start.UTC()}})
+	cond = conjoin(cond, &BinaryExpr{Op: LT, LHS: ref, RHS: &TimeLiteral{Val: end.UTC()}})
+	s.Condition = cond
+
+	return nil
+}
+
+// RewriteTimeCondition replaces now() in comparisons against the time column
+// with now, so relative bounds such as "time > now() - 1h" become absolute
+// time literals. Other uses of now() are not changed.
+func (s *SelectStatement) RewriteTimeCondition(now time.Time) {
+	if s.Condition == nil {
+		return
+	}
+
+	valuer := &NowValuer{Now: now.UTC()}
+	s.Condition = RewriteFunc(s.Condition, func(n Node) Node {
+		e, ok := n.(*BinaryExpr)
+		if !ok {
+			return n
+		}
+		switch e.Op {
+		case EQ, NEQ, LT, LTE, GT, GTE:
+		default:
+			return n
+		}
+
+		// Only replace the other side of a time comparison if it folds to
+		// a time literal.
+		if ref, ok := e.LHS.(*VarRef); ok && strings.ToLower(ref.Val) == "time" {
+			if lit, ok := Reduce(e.RHS, valuer).(*TimeLiteral); ok {
+				e.RHS = lit
+			}
+		} else if ref, ok := e.RHS.(*VarRef); ok && strings.ToLower(ref.Val) == "time" {
+			if lit, ok := Reduce(e.LHS, valuer).(*TimeLiteral); ok {
+				e.LHS = lit
+			}
+		}
+		return e
+	}).(Expr)
+}
+
+// hasTimeRef returns true if expr references the time column.
+func hasTimeRef(expr Expr) bool {
+	var found bool
+	WalkFunc(expr, func(n Node) {
+		if ref, ok := n.(*VarRef); ok && strings.ToLower(ref.Val) == "time" {
+			found = true
+		}
+	})
+	return found
+}
+
+// removeTimeComparisons returns a copy of expr without its time comparisons.
+// Returns nil if only time comparisons remain.
+func removeTimeComparisons(expr Expr) Expr {
+	switch expr := expr.(type) {
+	case *ParenExpr:
+		// Keep the parentheses only if a logical expression remains.
+		e := removeTimeComparisons(expr.Expr)
+		if bin, ok := e.(*BinaryExpr); ok && (bin.Op == AND || bin.Op == OR) {
+			return &ParenExpr{Expr: e}
+		}
+		return e
+	case *BinaryExpr:
+		if isTimeComparison(expr) {
+			return nil
+		} else if expr.Op != AND && expr.Op != OR {
+			break
+		}
+
+		lhs, rhs := removeTimeComparisons(expr.LHS), removeTimeComparisons(expr.RHS)
+		if lhs == nil {
+			return rhs
+		} else if rhs == nil {
+			return lhs
+		}
+		return &BinaryExpr{Op: expr.Op, LHS: lhs, RHS: rhs}
+	}
+	return CloneExpr(expr)
+}
+
+// isTimeComparison returns true if expr compares the time column, such as
+// "time > now() - 1h".
+func isTimeComparison(expr Expr) bool {
+	bin, ok := expr.(*BinaryExpr)
+	if !ok {
+		return false
+	}
+	switch bin.Op {
+	case EQ, NEQ, LT, LTE, GT, GTE:
+		return hasTimeRef(bin)
+	}
+	return false
+}
+
+/*
+
+BinaryExpr
+
+SELECT mean(xxx.value) + avg(yyy.value) FROM xxx JOIN yyy WHERE xxx.host = 123
+
+from xxx where host = 123
+select avg(value) from yyy where host = 123
+
+SELECT xxx.value FROM xxx WHERE xxx.host = 123
+SELECT yyy.value FROM yyy
 
 ---
 
@@ -856,6 +1704,8 @@ func (s *SelectStatement) Substatement(ref *VarRef) (*SelectStatement, error) {
 		Dimensions: s.Dimensions,
 		Limit:      s.Limit,
 		Offset:     s.Offset,
+		HasLimit:   s.HasLimit,
+		HasOffset:  s.HasOffset,
 		SortFields: s.SortFields,
 	}
 
@@ -980,13 +1830,13 @@ type DeleteStatement struct {
 // String returns a string representation of the delete statement.
 func (s *DeleteStatement) String() string {
 	var buf bytes.Buffer
-	_, _ = buf.WriteString("DELETE ")
+	_, _ = buf.WriteString("DELETE FROM ")
 	_, _ = buf.WriteString(s.Source.String())
 	if s.Condition != nil {
 		_, _ = buf.WriteString(" WHERE ")
 		_, _ = buf.WriteString(s.Condition.String())
 	}
-	return s.String()
+	return buf.String()
 }
 
 // RequiredPrivileges returns the privilege required to execute a DeleteStatement.
@@ -994,6 +1844,107 @@ func (s *DeleteStatement) RequiredPrivileges() ExecutionPrivileges {
 	return ExecutionPrivileges{{Name: "", Privilege: WritePrivilege}}
 }
 
+// ShowClauses represents the optional FROM, WHERE, ORDER BY, LIMIT and OFFSET
+// clauses shared by SHOW statements.
+type ShowClauses struct {
+	// Data source the statement lists from. Nil if there is no FROM clause.
+	Source Source
+
+	// An expression evaluated on each result. Nil if there is no WHERE clause.
+	Condition Expr
+
+	// Fields to sort results by.
+	SortFields SortFields
+
+	// Maximum number of rows to be returned.
+	// Unlimited if zero.
+	Limit int
+
+	// Returns rows starting at an offset from the first row.
+	Offset int
+}
+
+// writeSource writes the FROM clause, if any, to buf.
+func (c *ShowClauses) writeSource(buf *bytes.Buffer) {
+	if c.Source != nil {
+		_, _ = buf.WriteString(" FROM ")
+		_, _ = buf.WriteString(c.Source.String())
+	}
+}
+
+// writeTrailing writes the WHERE, ORDER BY, LIMIT and OFFSET clauses, if any, to buf.
+func (c *ShowClauses) writeTrailing(buf *bytes.Buffer) {
+	if c.Condition != nil {
+		_, _ = buf.WriteString(" WHERE ")
+		_, _ = buf.WriteString(c.Condition.String())
+	}
+	if len(c.SortFields) > 0 {
+		_, _ = buf.WriteString(" ORDER BY ")
+		_, _ = buf.WriteString(c.SortFields.String())
+	}
+	if c.Limit > 0 {
+		_, _ = buf.WriteString(" LIMIT ")
+		_, _ = buf.WriteString(strconv.Itoa(c.Limit))
+	}
+	if c.Offset > 0 {
+		_, _ = buf.WriteString(" OFFSET ")
+		_, _ = buf.WriteString(strconv.Itoa(c.Offset))
+	}
+}
+
+// ShowStatement represents a SHOW statement that accepts the shared clauses.
+// Clauses that a statement does not support are always returned empty.
+type ShowStatement interface {
+	Statement
+	Clauses() ShowClauses
+	setClauses(c ShowClauses)
+}
+
+// Clauses returns the clauses of the statement.
+func (s *ShowSeriesStatement) Clauses() ShowClauses {
+	return ShowClauses{Source: s.Source, Condition: s.Condition, SortFields: s.SortFields, Limit: s.Limit, Offset: s.Offset}
+}
+
+func (s *ShowSeriesStatement) setClauses(c ShowClauses) {
+	s.Source, s.Condition, s.SortFields, s.Limit, s.Offset = c.Source, c.Condition, c.SortFields, c.Limit, c.Offset
+}
+
+// Clauses returns the clauses of the statement. The statement has no FROM clause.
+func (s *ShowMeasurementsStatement) Clauses() ShowClauses {
+	return ShowClauses{Condition: s.Condition, SortFields: s.SortFields, Limit: s.Limit, Offset: s.Offset}
+}
+
+func (s *ShowMeasurementsStatement) setClauses(c ShowClauses) {
+	s.Condition, s.SortFields, s.Limit, s.Offset = c.Condition, c.SortFields, c.Limit, c.Offset
+}
+
+// Clauses returns the clauses of the statement.
+func (s *ShowTagKeysStatement) Clauses() ShowClauses {
+	return ShowClauses{Source: s.Source, Condition: s.Condition, SortFields: s.SortFields, Limit: s.Limit, Offset: s.Offset}
+}
+
+func (s *ShowTagKeysStatement) setClauses(c ShowClauses) {
+	s.Source, s.Condition, s.SortFields, s.Limit, s.Offset = c.Source, c.Condition, c.SortFields, c.Limit, c.Offset
+}
+
+// Clauses returns the clauses of the statement.
+func (s *ShowTagValuesStatement) Clauses() ShowClauses {
+	return ShowClauses{Source: s.Source, Condition: s.Condition, SortFields: s.SortFields, Limit: s.Limit, Offset: s.Offset}
+}
+
+func (s *ShowTagValuesStatement) setClauses(c ShowClauses) {
+	s.Source, s.Condition, s.SortFields, s.Limit, s.Offset = c.Source, c.Condition, c.SortFields, c.Limit, c.Offset
+}
+
+// Clauses returns the clauses of the statement. The statement has no WHERE clause.
+func (s *ShowFieldKeysStatement) Clauses() ShowClauses {
+	return ShowClauses{Source: s.Source, SortFields: s.SortFields, Limit: s.Limit, Offset: s.Offset}
+}
+
+func (s *ShowFieldKeysStatement) setClauses(c ShowClauses) {
+	s.Source, s.SortFields, s.Limit, s.Offset = c.Source, c.SortFields, c.Limit, c.Offset
+}
+
 // ShowSeriesStatement represents a command for listing series in the database.
 type ShowSeriesStatement struct {
 	// Measurement(s) the series are listed for.
@@ -1018,22 +1969,9 @@ func (s *ShowSeriesStatement) String() string {
 	var buf bytes.Buffer
 	_, _ = buf.WriteString("SHOW SERIES")
 
-	if s.Condition != nil {
-		_, _ = buf.WriteString(" WHERE ")
-		_, _ = buf.WriteString(s.Condition.String())
-	}
-	if len(s.SortFields) > 0 {
-		_, _ = buf.WriteString(" ORDER BY ")
-		_, _ = buf.WriteString(s.SortFields.String())
-	}
-	if s.Limit > 0 {
-		_, _ = buf.WriteString(" LIMIT ")
-		_, _ = buf.WriteString(strconv.Itoa(s.Limit))
-	}
-	if s.Offset > 0 {
-		_, _ = buf.WriteString(" OFFSET ")
-		_, _ = buf.WriteString(strconv.Itoa(s.Offset))
-	}
+	c := s.Clauses()
+	c.writeSource(&buf)
+	c.writeTrailing(&buf)
 	return buf.String()
 }
 
@@ -1113,11 +2051,70 @@ type CreateContinuousQueryStatement struct {
 
 	// Source of data (SELECT statement).
 	Source *SelectStatement
+
+	// Interval to run the query at. Zero uses the GROUP BY interval.
+	ResampleEvery time.Duration
+
+	// Time range to recompute on each run. Zero uses the GROUP BY interval.
+	ResampleFor time.Duration
 }
 
 // String returns a string representation of the statement.
 func (s *CreateContinuousQueryStatement) String() string {
-	return fmt.Sprintf("CREATE CONTINUOUS QUERY %s ON %s BEGIN %s END", s.Name, s.Database, s.Source.String())
+	var buf bytes.Buffer
+	_, _ = fmt.Fprintf(&buf, "CREATE CONTINUOUS QUERY %s ON %s ", s.Name, s.Database)
+
+	if s.ResampleEvery > 0 || s.ResampleFor > 0 {
+		_, _ = buf.WriteString("RESAMPLE ")
+		if s.ResampleEvery > 0 {
+			_, _ = buf.WriteString("EVERY ")
+			_, _ = buf.WriteString(FormatDuration(s.ResampleEvery))
+			_, _ = buf.WriteString(" ")
+		}
+		if s.ResampleFor > 0 {
+			_, _ = buf.WriteString("FOR ")
+			_, _ = buf.WriteString(FormatDuration(s.ResampleFor))
+			_, _ = buf.WriteString(" ")
+		}
+	}
+
+	_, _ = fmt.Fprintf(&buf, "BEGIN %s END", s.Source.String())
+	return buf.String()
+}
+
+// Validate performs checks on the statement that the parser does not enforce.
+// It returns an error if the source does not write into a measurement, if
+// an aggregated source does not have a positive GROUP BY time interval or if
+// the RESAMPLE FOR duration is shorter than that interval.
+func (s *CreateContinuousQueryStatement) Validate() error {
+	if s.Database == "" {
+		return errors.New("continuous query requires a database")
+	} else if s.Source == nil {
+		return errors.New("continuous query requires a source")
+	} else if s.Source.Target == nil || s.Source.Target.Measurement == "" {
+		return errors.New("continuous query requires an INTO measurement")
+	}
+
+	if err := s.Source.Validate(); err != nil {
+		return err
+	}
+
+	if s.ResampleEvery < 0 {
+		return errors.New("RESAMPLE EVERY duration must be positive")
+	} else if s.ResampleFor < 0 {
+		return errors.New("RESAMPLE FOR duration must be positive")
+	}
+
+	if s.Source.Aggregated() {
+		if d, err := s.Source.GroupByInterval(); err != nil {
+			return err
+		} else if d <= 0 {
+			return errors.New("continuous query requires a positive GROUP BY time interval")
+		} else if s.ResampleFor != 0 && s.ResampleFor < d {
+			return fmt.Errorf("RESAMPLE FOR duration %s must be at least the GROUP BY interval %s", FormatDuration(s.ResampleFor), FormatDuration(d))
+		}
+	}
+	return nil
 }
 
 // RequiredPrivileges returns the privilege required to execute a CreateContinuousQueryStatement.
@@ -1173,25 +2170,11 @@ type ShowMeasurementsStatement struct {
 
 // String returns a string representation of the statement.
 func (s *ShowMeasurementsStatement) String() string {
-	var buf bytes.Buffer
-	_, _ = buf.WriteString("SHOW MEASUREMENTS")
-
-	if s.Condition != nil {
-		_, _ = buf.WriteString(" WHERE ")
-		_, _ = buf.WriteString(s.Condition.String())
-	}
-	if len(s.SortFields) > 0 {
-		_, _ = buf.WriteString(" ORDER BY ")
-		_, _ = buf.WriteString(s.SortFields.String())
-	}
-	if s.Limit > 0 {
-		_, _ = buf.WriteString(" LIMIT ")
-		_, _ = buf.WriteString(strconv.Itoa(s.Limit))
-	}
-	if s.Offset > 0 {
-		_, _ = buf.WriteString(" OFFSET ")
-		_, _ = buf.WriteString(strconv.Itoa(s.Offset))
-	}
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("SHOW MEASUREMENTS")
+
+	c := s.Clauses()
+	c.writeTrailing(&buf)
 	return buf.String()
 }
 
@@ -1222,14 +2205,18 @@ func (s *DropMeasurementStatement) RequiredPrivileges() ExecutionPrivileges {
 // ShowRetentionPoliciesStatement represents a command for listing retention policies.
 type ShowRetentionPoliciesStatement struct {
 	// Name of the database to list policies for.
+	// Uses the current database if blank.
 	Database string
 }
 
 // String returns a string representation of a ShowRetentionPoliciesStatement.
 func (s *ShowRetentionPoliciesStatement) String() string {
 	var buf bytes.Buffer
-	_, _ = buf.WriteString("SHOW RETENTION POLICIES ")
-	_, _ = buf.WriteString(s.Database)
+	_, _ = buf.WriteString("SHOW RETENTION POLICIES")
+	if s.Database != "" {
+		_, _ = buf.WriteString(" ON ")
+		_, _ = buf.WriteString(s.Database)
+	}
 	return buf.String()
 }
 
@@ -1238,6 +2225,81 @@ func (s *ShowRetentionPoliciesStatement) RequiredPrivileges() ExecutionPrivilege
 	return ExecutionPrivileges{{Name: "", Privilege: ReadPrivilege}}
 }
 
+// ShowTagKeyCardinalityStatement represents a command for counting the
+// distinct tag keys of measurements.
+type ShowTagKeyCardinalityStatement struct {
+	// Database to count tag keys in. Uses the default database if blank.
+	Database string
+
+	// Measurement(s) the tag keys are counted for.
+	Source Source
+
+	// An expression evaluated on a series name or tag.
+	Condition Expr
+}
+
+// String returns a string representation of the statement.
+func (s *ShowTagKeyCardinalityStatement) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("SHOW TAG KEY CARDINALITY")
+	writeCardinalityClauses(&buf, s.Database, s.Source, nil, s.Condition)
+	return buf.String()
+}
+
+// RequiredPrivileges returns the privilege(s) required to execute a ShowTagKeyCardinalityStatement.
+func (s *ShowTagKeyCardinalityStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Name: s.Database, Privilege: ReadPrivilege}}
+}
+
+// ShowFieldKeyCardinalityStatement represents a command for counting the
+// distinct field keys of measurements.
+type ShowFieldKeyCardinalityStatement struct {
+	// Database to count field keys in. Uses the default database if blank.
+	Database string
+
+	// Measurement(s) the field keys are counted for.
+	Source Source
+
+	// An expression evaluated on a series name or tag.
+	Condition Expr
+}
+
+// String returns a string representation of the statement.
+func (s *ShowFieldKeyCardinalityStatement) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("SHOW FIELD KEY CARDINALITY")
+	writeCardinalityClauses(&buf, s.Database, s.Source, nil, s.Condition)
+	return buf.String()
+}
+
+// RequiredPrivileges returns the privilege(s) required to execute a ShowFieldKeyCardinalityStatement.
+func (s *ShowFieldKeyCardinalityStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Name: s.Database, Privilege: ReadPrivilege}}
+}
+
+// writeCardinalityClauses writes the ON, FROM, WITH KEY and WHERE clauses of
+// a cardinality statement to buf. The WITH KEY clause is omitted if there are
+// no tag keys.
+func writeCardinalityClauses(buf *bytes.Buffer, database string, source Source, tagKeys []string, condition Expr) {
+	if database != "" {
+		_, _ = buf.WriteString(" ON ")
+		_, _ = buf.WriteString(database)
+	}
+	c := ShowClauses{Source: source, Condition: condition}
+	c.writeSource(buf)
+	writeTagKeys(buf, tagKeys)
+	c.writeTrailing(buf)
+}
+
+// cardinalityDatabase returns the database of a cardinality statement.
+// The ON clause takes precedence over the database of the source.
+func cardinalityDatabase(database string, source Source) string {
+	if database != "" {
+		return database
+	}
+	return sourceDatabase(source)
+}
+
 // ShowTagKeysStatement represents a command for listing tag keys.
 type ShowTagKeysStatement struct {
 	// Data source that fields are extracted from.
@@ -1262,26 +2324,9 @@ func (s *ShowTagKeysStatement) String() string {
 	var buf bytes.Buffer
 	_, _ = buf.WriteString("SHOW TAG KEYS")
 
-	if s.Source != nil {
-		_, _ = buf.WriteString(" FROM ")
-		_, _ = buf.WriteString(s.Source.String())
-	}
-	if s.Condition != nil {
-		_, _ = buf.WriteString(" WHERE ")
-		_, _ = buf.WriteString(s.Condition.String())
-	}
-	if len(s.SortFields) > 0 {
-		_, _ = buf.WriteString(" ORDER BY ")
-		_, _ = buf.WriteString(s.SortFields.String())
-	}
-	if s.Limit > 0 {
-		_, _ = buf.WriteString(" LIMIT ")
-		_, _ = buf.WriteString(strconv.Itoa(s.Limit))
-	}
-	if s.Offset > 0 {
-		_, _ = buf.WriteString(" OFFSET ")
-		_, _ = buf.WriteString(strconv.Itoa(s.Offset))
-	}
+	c := s.Clauses()
+	c.writeSource(&buf)
+	c.writeTrailing(&buf)
 	return buf.String()
 }
 
@@ -1317,26 +2362,10 @@ func (s *ShowTagValuesStatement) String() string {
 	var buf bytes.Buffer
 	_, _ = buf.WriteString("SHOW TAG VALUES")
 
-	if s.Source != nil {
-		_, _ = buf.WriteString(" FROM ")
-		_, _ = buf.WriteString(s.Source.String())
-	}
-	if s.Condition != nil {
-		_, _ = buf.WriteString(" WHERE ")
-		_, _ = buf.WriteString(s.Condition.String())
-	}
-	if len(s.SortFields) > 0 {
-		_, _ = buf.WriteString(" ORDER BY ")
-		_, _ = buf.WriteString(s.SortFields.String())
-	}
-	if s.Limit > 0 {
-		_, _ = buf.WriteString(" LIMIT ")
-		_, _ = buf.WriteString(strconv.Itoa(s.Limit))
-	}
-	if s.Offset > 0 {
-		_, _ = buf.WriteString(" OFFSET ")
-		_, _ = buf.WriteString(strconv.Itoa(s.Offset))
-	}
+	c := s.Clauses()
+	c.writeSource(&buf)
+	writeTagKeys(&buf, s.TagKeys)
+	c.writeTrailing(&buf)
 	return buf.String()
 }
 
@@ -1345,6 +2374,47 @@ func (s *ShowTagValuesStatement) RequiredPrivileges() ExecutionPrivileges {
 	return ExecutionPrivileges{{Name: "", Privilege: ReadPrivilege}}
 }
 
+// writeTagKeys writes the WITH KEY clause of a tag values statement to buf.
+func writeTagKeys(buf *bytes.Buffer, tagKeys []string) {
+	if len(tagKeys) == 1 {
+		_, _ = buf.WriteString(" WITH KEY = ")
+		_, _ = buf.WriteString(tagKeys[0])
+	} else if len(tagKeys) > 1 {
+		_, _ = buf.WriteString(" WITH KEY IN (")
+		_, _ = buf.WriteString(strings.Join(tagKeys, ", "))
+		_, _ = buf.WriteString(")")
+	}
+}
+
+// ShowTagValuesCardinalityStatement represents a command for counting the
+// distinct values of tag keys.
+type ShowTagValuesCardinalityStatement struct {
+	// Database to count tag values in. Uses the default database if blank.
+	Database string
+
+	// Measurement(s) the tag values are counted for.
+	Source Source
+
+	// Tag key(s) to count values of.
+	TagKeys []string
+
+	// An expression evaluated on a series name or tag.
+	Condition Expr
+}
+
+// String returns a string representation of the statement.
+func (s *ShowTagValuesCardinalityStatement) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("SHOW TAG VALUES CARDINALITY")
+	writeCardinalityClauses(&buf, s.Database, s.Source, s.TagKeys, s.Condition)
+	return buf.String()
+}
+
+// RequiredPrivileges returns the privilege(s) required to execute a ShowTagValuesCardinalityStatement.
+func (s *ShowTagValuesCardinalityStatement) RequiredPrivileges() ExecutionPrivileges {
+	return ExecutionPrivileges{{Name: s.Database, Privilege: ReadPrivilege}}
+}
+
 // ShowUsersStatement represents a command for listing users.
 type ShowUsersStatement struct{}
 
@@ -1379,22 +2449,9 @@ func (s *ShowFieldKeysStatement) String() string {
 	var buf bytes.Buffer
 	_, _ = buf.WriteString("SHOW FIELD KEYS")
 
-	if s.Source != nil {
-		_, _ = buf.WriteString(" FROM ")
-		_, _ = buf.WriteString(s.Source.String())
-	}
-	if len(s.SortFields) > 0 {
-		_, _ = buf.WriteString(" ORDER BY ")
-		_, _ = buf.WriteString(s.SortFields.String())
-	}
-	if s.Limit > 0 {
-		_, _ = buf.WriteString(" LIMIT ")
-		_, _ = buf.WriteString(strconv.Itoa(s.Limit))
-	}
-	if s.Offset > 0 {
-		_, _ = buf.WriteString(" OFFSET ")
-		_, _ = buf.WriteString(strconv.Itoa(s.Offset))
-	}
+	c := s.Clauses()
+	c.writeSource(&buf)
+	c.writeTrailing(&buf)
 	return buf.String()
 }
 
@@ -1415,6 +2472,80 @@ func (a Fields) String() string {
 	return strings.Join(str, ", ")
 }
 
+// clone returns a deep copy of the fields.
+func (a Fields) clone() Fields {
+	if a == nil {
+		return nil
+	}
+	other := make(Fields, len(a))
+	for i, f := range a {
+		other[i] = &Field{Expr: CloneExpr(f.Expr), Alias: f.Alias}
+	}
+	return other
+}
+
+// Validate returns an error if two fields have the same explicit alias.
+// The error names both fields and their one-based position in the list.
+// Derived names are not checked since Names() makes them unique.
+func (a Fields) Validate() error {
+	aliases := make(map[string]int)
+	for i, f := range a {
+		if f.Alias == "" {
+			continue
+		} else if j, ok := aliases[f.Alias]; ok {
+			return fmt.Errorf("duplicate alias %s: field %d (%s) and field %d (%s)", f.Alias, j+1, a[j], i+1, f)
+		}
+		aliases[f.Alias] = i
+	}
+	return nil
+}
+
+// Names returns the column name of each field. Fields without a name are
+// named "col" followed by their index. Duplicate names are made unique by
+// appending "_1", "_2", etc. to all but the first field with the name.
+func (a Fields) Names() []string {
+	names := make([]string, len(a))
+	taken := make(map[string]bool, len(a))
+	for i, f := range a {
+		if names[i] = f.Name(); names[i] == "" {
+			names[i] = fmt.Sprintf("col%d", i)
+		}
+		taken[names[i]] = true
+	}
+
+	// Rename duplicates, skipping suffixes that are used by other fields.
+	seen := make(map[string]int, len(a))
+	for i, name := range names {
+		n, ok := seen[name]
+		if !ok {
+			seen[name] = 0
+			continue
+		}
+		for {
+			n++
+			if other := fmt.Sprintf("%s_%d", name, n); !taken[other] {
+				names[i] = other
+				taken[other] = true
+				break
+			}
+		}
+		seen[name] = n
+	}
+	return names
+}
+
+// hasVarRefs returns true if any field references a variable or wildcard.
+func (a Fields) hasVarRefs() bool {
+	var v bool
+	WalkFunc(a, func(n Node) {
+		switch n.(type) {
+		case *VarRef, *Wildcard:
+			v = true
+		}
+	})
+	return v
+}
+
 // Field represents an expression retrieved from a select statement.
 type Field struct {
 	Expr  Expr
@@ -1422,7 +2553,9 @@ type Field struct {
 }
 
 // Name returns the name of the field. Returns alias, if set.
-// Otherwise uses the function name or variable name.
+// Otherwise uses the function name or variable name. An expression is named
+// by joining the names of the calls and variables it contains with an
+// underscore, e.g. "mean_max" for "mean(value) / max(value)".
 func (f *Field) Name() string {
 	// Return alias, if set.
 	if f.Alias != "" {
@@ -1430,15 +2563,38 @@ func (f *Field) Name() string {
 	}
 
 	// Return the function name or variable name, if available.
-	switch expr := f.Expr.(type) {
+	return strings.Join(exprNames(f.Expr, nil), "_")
+}
+
+// exprNames appends the names of the calls and variables in expr to a.
+// The arguments of a call are not included.
+func exprNames(expr Expr, a []string) []string {
+	switch expr := expr.(type) {
 	case *Call:
-		return expr.Name
+		return append(a, expr.Name)
 	case *VarRef:
-		return expr.Val
+		return append(a, expr.Val)
+	case *BinaryExpr:
+		return exprNames(expr.RHS, exprNames(expr.LHS, a))
+	case *ParenExpr:
+		return exprNames(expr.Expr, a)
+	}
+	return a
+}
+
+// IsStringConstant returns true if the field is only a string literal.
+// This is usually a field name that was mistakenly written in single quotes,
+// such as "SELECT 'value' FROM cpu".
+func (f *Field) IsStringConstant() bool {
+	expr := f.Expr
+	for {
+		if e, ok := expr.(*ParenExpr); ok {
+			expr = e.Expr
+			continue
+		}
+		_, ok := expr.(*StringLiteral)
+		return ok
 	}
-
-	// Otherwise return a blank name.
-	return ""
 }
 
 // String returns a string representation of the field.
@@ -1452,15 +2608,74 @@ func (f *Field) String() string {
 // Dimensions represents a list of dimensions.
 type Dimensions []*Dimension
 
-// String returns a string representation of the dimensions.
+// String returns a string representation of the dimensions. The time()
+// dimension is written first and the remaining dimensions keep their order.
 func (a Dimensions) String() string {
 	var str []string
 	for _, d := range a {
-		str = append(str, d.String())
+		if d.isTime() {
+			str = append(str, d.String())
+		}
+	}
+	for _, d := range a {
+		if !d.isTime() {
+			str = append(str, d.String())
+		}
 	}
 	return strings.Join(str, ", ")
 }
 
+// clone returns a deep copy of the dimensions.
+func (a Dimensions) clone() Dimensions {
+	if a == nil {
+		return nil
+	}
+	other := make(Dimensions, len(a))
+	for i, d := range a {
+		other[i] = &Dimension{Expr: CloneExpr(d.Expr), Alias: d.Alias}
+	}
+	return other
+}
+
+// Validate returns an error if any dimension cannot be grouped by.
+// Only tag references, wildcards and a single time() call are allowed. The
+// parser does not know the schema so grouping by a field is not detected.
+func (a Dimensions) Validate() error {
+	var hasTime, hasTags, hasWildcard bool
+	for _, dim := range a {
+		switch expr := dim.Expr.(type) {
+		case *VarRef:
+			// Tag references are validated against the schema at execution time.
+			if hasWildcard {
+				return fmt.Errorf("invalid dimension %s: GROUP BY * cannot be combined with other tags", expr)
+			}
+			hasTags = true
+		case *Wildcard:
+			// A wildcard is expanded to every tag so other tags would be repeated.
+			if hasWildcard {
+				return errors.New("multiple GROUP BY * not allowed")
+			} else if hasTags {
+				return errors.New("invalid dimension *: GROUP BY * cannot be combined with other tags")
+			}
+			hasWildcard = true
+		case *Call:
+			if strings.ToLower(expr.Name) != "time" {
+				return fmt.Errorf("invalid dimension %s: only time() calls allowed in GROUP BY", expr)
+			} else if len(expr.Args) != 1 {
+				return errors.New("time dimension expected one argument")
+			} else if _, ok := expr.Args[0].(*DurationLiteral); !ok {
+				return errors.New("time dimension must have one duration argument")
+			} else if hasTime {
+				return errors.New("multiple time dimensions not allowed")
+			}
+			hasTime = true
+		default:
+			return fmt.Errorf("invalid dimension %s: only tags and time() allowed in GROUP BY", expr)
+		}
+	}
+	return nil
+}
+
 // Normalize returns the interval and tag dimensions separately.
 // Returns 0 if no time interval is specified.
 // Returns an error if multiple time dimensions exist or if non-VarRef dimensions are specified.
@@ -1498,11 +2713,57 @@ func (a Dimensions) Normalize() (time.Duration, []string, error) {
 
 // Dimension represents an expression that a select statement is grouped by.
 type Dimension struct {
-	Expr Expr
+	Expr  Expr
+	Alias string
 }
 
-// String returns a string representation of the dimension.
-func (d *Dimension) String() string { return d.Expr.String() }
+// String returns a string representation of the dimension. Each segment of
+// a reference is quoted unless it is a bare identifier that is not a keyword.
+// A tag key that is not a valid identifier, such as one expanded from a
+// wildcard, is quoted as a single segment.
+func (d *Dimension) String() string {
+	expr := d.Expr.String()
+	if ref, ok := d.Expr.(*VarRef); ok {
+		segments := ref.Segments()
+		if segments == nil {
+			segments = []string{ref.Val}
+		}
+
+		var buf bytes.Buffer
+		for i, segment := range segments {
+			if i > 0 {
+				_ = buf.WriteByte('.')
+			}
+			if isBareIdent(segment) {
+				_, _ = buf.WriteString(segment)
+			} else {
+				_, _ = buf.WriteString(QuoteIdent([]string{segment}))
+			}
+		}
+		expr = (&VarRef{Val: buf.String(), Type: ref.Type}).String()
+	}
+
+	if d.Alias == "" {
+		return expr
+	}
+	return fmt.Sprintf("%s AS %s", expr, d.Alias)
+}
+
+// isTime returns true if the dimension is a time() call.
+func (d *Dimension) isTime() bool {
+	call, ok := d.Expr.(*Call)
+	return ok && strings.ToLower(call.Name) == "time"
+}
+
+// isBareIdent returns true if s can be written as an identifier without quotes.
+func isBareIdent(s string) bool {
+	for i, ch := range s {
+		if (i == 0 && !isLetter(ch)) || !isIdentChar(ch) {
+			return false
+		}
+	}
+	return s != "" && Lookup(s) == IDENT
+}
 
 // Measurements represents a list of measurements.
 type Measurements []*Measurement
@@ -1519,10 +2780,19 @@ func (a Measurements) String() string {
 // Measurement represents a single measurement used as a datasource.
 type Measurement struct {
 	Name string
+
+	// Regex matches measurement names. It is set instead of Name when the
+	// source is written as a regex, such as "FROM /cpu.*/".
+	Regex *RegexLiteral
 }
 
 // String returns a string representation of the measurement.
-func (m *Measurement) String() string { return m.Name }
+func (m *Measurement) String() string {
+	if m.Regex != nil {
+		return m.Regex.String()
+	}
+	return m.Name
+}
 
 // Join represents two datasources joined together.
 type Join struct {
@@ -1547,10 +2817,27 @@ func (m *Merge) String() string {
 // VarRef represents a reference to a variable.
 type VarRef struct {
 	Val string
+
+	// Optional type hint given with a "::type" suffix.
+	Type DataType
 }
 
 // String returns a string representation of the variable reference.
-func (r *VarRef) String() string { return r.Val }
+func (r *VarRef) String() string {
+	if r.Type == Unknown {
+		return r.Val
+	}
+	return r.Val + "::" + string(r.Type)
+}
+
+// Segments returns the dot-separated parts of the reference with quotes
+// removed, such as "cpu" and "value" for "cpu.value". A qualified reference
+// names a field of a measurement in a join. Returns nil if the name is not a
+// valid identifier.
+func (r *VarRef) Segments() []string {
+	segments, _ := splitIdentSegments(r.Val)
+	return segments
+}
 
 // Call represents a function call.
 type Call struct {
@@ -1607,6 +2894,21 @@ func isFalseLiteral(expr Expr) bool {
 	return false
 }
 
+// IsBooleanLiteral returns true if expr reduces to a constant boolean along
+// with the value of the constant. Only constant subexpressions are folded,
+// so a condition that references a variable is not a literal unless the
+// variable is short-circuited, as in "false AND host = 'a'".
+func IsBooleanLiteral(expr Expr) (ok, val bool) {
+	if expr == nil {
+		return false, false
+	}
+	lit, ok := Reduce(expr, nil).(*BooleanLiteral)
+	if !ok {
+		return false, false
+	}
+	return true, lit.Val
+}
+
 // StringLiteral represents a string literal.
 type StringLiteral struct {
 	Val string
@@ -1621,8 +2923,9 @@ type TimeLiteral struct {
 }
 
 // String returns a string representation of the literal.
+// The time is written in UTC using RFC3339 so it can be parsed back to the same instant.
 func (l *TimeLiteral) String() string {
-	return `"` + l.Val.UTC().Format(DateTimeFormat) + `"`
+	return `'` + l.Val.UTC().Format(time.RFC3339Nano) + `'`
 }
 
 // DurationLiteral represents a duration literal.
@@ -1630,8 +2933,39 @@ type DurationLiteral struct {
 	Val time.Duration
 }
 
+// NewDurationLiteral returns a new duration literal.
+func NewDurationLiteral(d time.Duration) *DurationLiteral { return &DurationLiteral{Val: d} }
+
+// String returns a string representation of the literal.
+// Microseconds are given an explicit unit so they're not scanned as a number.
+func (l *DurationLiteral) String() string {
+	s := FormatDuration(l.Val)
+	if isDigit(rune(s[len(s)-1])) {
+		return s + "u"
+	}
+	return s
+}
+
+// ListLiteral represents a bracketed list of values, such as "[1, 2, 3]".
+// It is only supported as a function argument and is parsed when
+// ParserOptions.ListLiterals is set.
+type ListLiteral struct {
+	Vals []Expr
+}
+
 // String returns a string representation of the literal.
-func (l *DurationLiteral) String() string { return FormatDuration(l.Val) }
+func (l *ListLiteral) String() string {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString("[")
+	for i, v := range l.Vals {
+		if i > 0 {
+			_, _ = buf.WriteString(", ")
+		}
+		_, _ = buf.WriteString(v.String())
+	}
+	_, _ = buf.WriteString("]")
+	return buf.String()
+}
 
 // nilLiteral represents a nil literal.
 // This is not available to the query language itself. It's only used internally.
@@ -1665,16 +2999,177 @@ type RegexLiteral struct {
 	Val *regexp.Regexp
 }
 
-// String returns a string representation of the literal.
-func (r *RegexLiteral) String() string { return r.Val.String() }
-
-// Wildcard represents a wild card expression.
-type Wildcard struct{}
-
-// String returns a string representation of the wildcard.
-func (e *Wildcard) String() string { return "*" }
-
+// String returns a string representation of the literal.
+// The pattern is delimited by forward slashes and unescaped slashes are escaped.
+func (r *RegexLiteral) String() string {
+	var buf bytes.Buffer
+	_ = buf.WriteByte('/')
+
+	// Escape bare slashes only. An escape sequence such as \/ is copied as is
+	// so that it isn't written with a second backslash.
+	s := r.Val.String()
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			_ = buf.WriteByte(s[i])
+			i++
+		} else if s[i] == '/' {
+			_ = buf.WriteByte('\\')
+		}
+		_ = buf.WriteByte(s[i])
+	}
+
+	_ = buf.WriteByte('/')
+	return buf.String()
+}
+
+// MatchesAll returns true if the regex matches every value, such as // and
+// /.*/. A pattern that can match an empty string without any anchors or word
+// boundaries always finds a match at the start of a value. Other patterns
+// that happen to match every value, such as /^.*/, return false.
+func (r *RegexLiteral) MatchesAll() bool {
+	re, err := syntax.Parse(r.Val.String(), syntax.Perl)
+	if err != nil {
+		return false
+	}
+	return !hasEmptyWidthAssertion(re) && r.Val.MatchString("")
+}
+
+// hasEmptyWidthAssertion returns true if re contains an anchor or word boundary.
+func hasEmptyWidthAssertion(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpBeginLine, syntax.OpEndLine, syntax.OpBeginText, syntax.OpEndText,
+		syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		return true
+	}
+	for _, sub := range re.Sub {
+		if hasEmptyWidthAssertion(sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// Wildcard represents a wild card expression.
+type Wildcard struct{}
+
+// String returns a string representation of the wildcard.
+func (e *Wildcard) String() string { return "*" }
+
+// CloneStatement returns a deep copy of the statement. Modifying the copy,
+// including its fields, dimensions and conditions, does not affect stmt.
+func CloneStatement(stmt Statement) Statement {
+	switch s := stmt.(type) {
+	case nil:
+		return nil
+	case *SelectStatement:
+		return s.Clone()
+	case *CreateContinuousQueryStatement:
+		other := *s
+		if s.Source != nil {
+			other.Source = s.Source.Clone()
+		}
+		return &other
+	case *CreateUserStatement:
+		other := *s
+		if s.Privilege != nil {
+			other.Privilege = NewPrivilege(*s.Privilege)
+		}
+		return &other
+	case *GrantStatement:
+		other := *s
+		other.Privileges = append(Privileges(nil), s.Privileges...)
+		return &other
+	case *RevokeStatement:
+		other := *s
+		other.Privileges = append(Privileges(nil), s.Privileges...)
+		return &other
+	case *AlterRetentionPolicyStatement:
+		other := *s
+		if s.Duration != nil {
+			d := *s.Duration
+			other.Duration = &d
+		}
+		if s.Replication != nil {
+			n := *s.Replication
+			other.Replication = &n
+		}
+		return &other
+	case *DeleteStatement:
+		return &DeleteStatement{Source: cloneSource(s.Source), Condition: CloneExpr(s.Condition)}
+	case *DropSeriesStatement:
+		return &DropSeriesStatement{SeriesID: s.SeriesID, Source: cloneSource(s.Source), Condition: CloneExpr(s.Condition)}
+	case *ShowSeriesStatement:
+		other := *s
+		other.Source, other.Condition, other.SortFields = cloneSource(s.Source), CloneExpr(s.Condition), s.SortFields.clone()
+		return &other
+	case *ShowMeasurementsStatement:
+		other := *s
+		other.Condition, other.SortFields = CloneExpr(s.Condition), s.SortFields.clone()
+		return &other
+	case *ShowTagKeysStatement:
+		other := *s
+		other.Source, other.Condition, other.SortFields = cloneSource(s.Source), CloneExpr(s.Condition), s.SortFields.clone()
+		return &other
+	case *ShowTagValuesStatement:
+		other := *s
+		other.Source, other.Condition, other.SortFields = cloneSource(s.Source), CloneExpr(s.Condition), s.SortFields.clone()
+		if s.TagKeys != nil {
+			other.TagKeys = append([]string(nil), s.TagKeys...)
+		}
+		return &other
+	case *ShowFieldKeysStatement:
+		other := *s
+		other.Source, other.SortFields = cloneSource(s.Source), s.SortFields.clone()
+		return &other
+	case *ShowTagKeyCardinalityStatement:
+		return &ShowTagKeyCardinalityStatement{Database: s.Database, Source: cloneSource(s.Source), Condition: CloneExpr(s.Condition)}
+	case *ShowFieldKeyCardinalityStatement:
+		return &ShowFieldKeyCardinalityStatement{Database: s.Database, Source: cloneSource(s.Source), Condition: CloneExpr(s.Condition)}
+	case *ShowTagValuesCardinalityStatement:
+		other := *s
+		other.Source, other.Condition = cloneSource(s.Source), CloneExpr(s.Condition)
+		if s.TagKeys != nil {
+			other.TagKeys = append([]string(nil), s.TagKeys...)
+		}
+		return &other
+
+	// The remaining statements only contain values.
+	case *CreateDatabaseStatement:
+		other := *s
+		return &other
+	case *CreateRetentionPolicyStatement:
+		other := *s
+		return &other
+	case *DropContinuousQueryStatement:
+		other := *s
+		return &other
+	case *DropDatabaseStatement:
+		other := *s
+		return &other
+	case *DropMeasurementStatement:
+		other := *s
+		return &other
+	case *DropRetentionPolicyStatement:
+		other := *s
+		return &other
+	case *DropUserStatement:
+		other := *s
+		return &other
+	case *ShowContinuousQueriesStatement:
+		return &ShowContinuousQueriesStatement{}
+	case *ShowDatabasesStatement:
+		return &ShowDatabasesStatement{}
+	case *ShowRetentionPoliciesStatement:
+		other := *s
+		return &other
+	case *ShowUsersStatement:
+		return &ShowUsersStatement{}
+	}
+	panic("unreachable")
+}
+
 // CloneExpr returns a deep copy of the expression.
+// Regex literals share their compiled pattern since it is immutable.
 func CloneExpr(expr Expr) Expr {
 	if expr == nil {
 		return nil
@@ -1685,13 +3180,21 @@ func CloneExpr(expr Expr) Expr {
 	case *BooleanLiteral:
 		return &BooleanLiteral{Val: expr.Val}
 	case *Call:
-		args := make([]Expr, len(expr.Args))
-		for i, arg := range expr.Args {
-			args[i] = CloneExpr(arg)
+		var args []Expr
+		for _, arg := range expr.Args {
+			args = append(args, CloneExpr(arg))
 		}
 		return &Call{Name: expr.Name, Args: args}
 	case *DurationLiteral:
 		return &DurationLiteral{Val: expr.Val}
+	case *ListLiteral:
+		var vals []Expr
+		for _, v := range expr.Vals {
+			vals = append(vals, CloneExpr(v))
+		}
+		return &ListLiteral{Vals: vals}
+	case *nilLiteral:
+		return &nilLiteral{}
 	case *NumberLiteral:
 		return &NumberLiteral{Val: expr.Val}
 	case *ParenExpr:
@@ -1703,15 +3206,155 @@ func CloneExpr(expr Expr) Expr {
 	case *TimeLiteral:
 		return &TimeLiteral{Val: expr.Val}
 	case *VarRef:
-		return &VarRef{Val: expr.Val}
+		return &VarRef{Val: expr.Val, Type: expr.Type}
 	case *Wildcard:
 		return &Wildcard{}
 	}
 	panic("unreachable")
 }
 
+// Simplify returns a copy of expr with redundant parentheses removed.
+//
+// Parentheses are kept only around a binary expression whose operator binds
+// more loosely than its parent, or equally when it is the right-hand side,
+// since operators are left associative. The simplified expression prints
+// to a string that parses to the same tree.
+func Simplify(expr Expr) Expr {
+	if expr == nil {
+		return nil
+	}
+	return simplify(expr)
+}
+
+// simplify returns a copy of expr that is never wrapped in parentheses.
+func simplify(expr Expr) Expr {
+	switch expr := expr.(type) {
+	case *BinaryExpr:
+		lhs, rhs := simplify(expr.LHS), simplify(expr.RHS)
+		prec := expr.Op.Precedence()
+		if lhs, ok := lhs.(*BinaryExpr); ok && lhs.Op.Precedence() < prec {
+			return &BinaryExpr{Op: expr.Op, LHS: &ParenExpr{Expr: lhs}, RHS: wrapRHS(prec, rhs)}
+		}
+		return &BinaryExpr{Op: expr.Op, LHS: lhs, RHS: wrapRHS(prec, rhs)}
+	case *Call:
+		var args []Expr
+		for _, arg := range expr.Args {
+			args = append(args, simplify(arg))
+		}
+		return &Call{Name: expr.Name, Args: args}
+	case *ParenExpr:
+		return simplify(expr.Expr)
+	default:
+		return CloneExpr(expr)
+	}
+}
+
+// wrapRHS parenthesizes the right-hand side of an operator with precedence
+// prec if it would otherwise bind to the left-hand side when parsed.
+func wrapRHS(prec int, rhs Expr) Expr {
+	if rhs, ok := rhs.(*BinaryExpr); ok && rhs.Op.Precedence() <= prec {
+		return &ParenExpr{Expr: rhs}
+	}
+	return rhs
+}
+
+// SplitByTags partitions the AND-joined terms of a condition into the terms
+// that only reference tags and the remaining terms. A reference is a tag if
+// it has a tag type or if it is untyped and its name is in tagKeys.
+//
+// Terms joined by OR are never split, so a term that mixes tag and field
+// predicates is kept whole with the remaining terms. Either result is nil
+// if it has no terms. The original expression is not modified.
+func SplitByTags(expr Expr, tagKeys map[string]bool) (tagExpr, fieldExpr Expr) {
+	for _, term := range Conjuncts(expr) {
+		if onlyTagRefs(term, tagKeys) {
+			tagExpr = conjoin(tagExpr, term)
+		} else {
+			fieldExpr = conjoin(fieldExpr, term)
+		}
+	}
+	return tagExpr, fieldExpr
+}
+
+// Conjuncts returns the terms of expr that are joined by AND, in order.
+// Parentheses are removed from each term and a term joined by another
+// operator, such as OR, is returned whole. Returns nil if expr is nil.
+func Conjuncts(expr Expr) []Expr { return flatten(expr, AND) }
+
+// Disjuncts returns the terms of expr that are joined by OR, in order.
+// Parentheses are removed from each term and a term joined by another
+// operator, such as AND, is returned whole. Returns nil if expr is nil.
+func Disjuncts(expr Expr) []Expr { return flatten(expr, OR) }
+
+// TimePredicates returns the terms of expr joined by AND that reference the
+// time column, in order. A term joined by OR is returned whole, as its
+// predicates cannot be moved independently.
+func TimePredicates(expr Expr) []*BinaryExpr {
+	var a []*BinaryExpr
+	for _, term := range Conjuncts(expr) {
+		if bin, ok := term.(*BinaryExpr); ok && hasTimeRef(bin) {
+			a = append(a, bin)
+		}
+	}
+	return a
+}
+
+// flatten returns the terms of the chain of op expressions in expr.
+func flatten(expr Expr, op Token) []Expr {
+	switch e := expr.(type) {
+	case nil:
+		return nil
+	case *ParenExpr:
+		return flatten(e.Expr, op)
+	case *BinaryExpr:
+		if e.Op == op {
+			return append(flatten(e.LHS, op), flatten(e.RHS, op)...)
+		}
+	}
+	return []Expr{expr}
+}
+
+// conjoin returns a copy of term joined to expr by AND. Returns a copy of
+// term if expr is nil.
+func conjoin(expr, term Expr) Expr {
+	term = CloneExpr(term)
+	if expr == nil {
+		return term
+	}
+	return &BinaryExpr{Op: AND, LHS: parenOR(expr), RHS: parenOR(term)}
+}
+
+// parenOR wraps expr in parentheses if it is joined by OR.
+func parenOR(expr Expr) Expr {
+	if e, ok := expr.(*BinaryExpr); ok && e.Op == OR {
+		return &ParenExpr{Expr: e}
+	}
+	return expr
+}
+
+// onlyTagRefs returns true if expr references at least one variable and
+// every variable is a tag.
+func onlyTagRefs(expr Expr, tagKeys map[string]bool) bool {
+	n, tags := 0, 0
+	WalkFunc(expr, func(node Node) {
+		if ref, ok := node.(*VarRef); ok {
+			n++
+			if ref.Type == Tag || (ref.Type == Unknown && tagKeys[ref.Val]) {
+				tags++
+			}
+		}
+	})
+	return n > 0 && n == tags
+}
+
 // TimeRange returns the minimum and maximum times specified by an expression.
 // Returns zero times if there is no bound.
+//
+// Time may be compared against a time literal, a duration literal or a number
+// literal. Durations and numbers are offsets from the Unix epoch; a number is
+// interpreted as nanoseconds, so "time > 1434055562000000000" and
+// "time > 1434055562s" are equivalent. Numbers are stored as float64 so
+// nanosecond precision is only exact for values up to 2^53.
 func TimeRange(expr Expr) (min, max time.Time) {
 	WalkFunc(expr, func(n Node) {
 		if n, ok := n.(*BinaryExpr); ok {
@@ -1774,6 +3417,8 @@ func timeExprValue(ref Expr, lit Expr) time.Time {
 			return lit.Val
 		case *DurationLiteral:
 			return time.Unix(0, int64(lit.Val)).UTC()
+		case *NumberLiteral:
+			return time.Unix(0, int64(lit.Val)).UTC()
 		}
 	}
 	return time.Time{}
@@ -1820,6 +3465,18 @@ func Walk(v Visitor, node Node) {
 		Walk(v, n.Condition)
 		Walk(v, n.SortFields)
 
+	case *ShowTagKeyCardinalityStatement:
+		Walk(v, n.Source)
+		Walk(v, n.Condition)
+
+	case *ShowTagValuesCardinalityStatement:
+		Walk(v, n.Source)
+		Walk(v, n.Condition)
+
+	case *ShowFieldKeyCardinalityStatement:
+		Walk(v, n.Source)
+		Walk(v, n.Condition)
+
 	case Fields:
 		for _, c := range n {
 			Walk(v, c)
@@ -1847,6 +3504,11 @@ func Walk(v Visitor, node Node) {
 		for _, expr := range n.Args {
 			Walk(v, expr)
 		}
+
+	case *ListLiteral:
+		for _, expr := range n.Vals {
+			Walk(v, expr)
+		}
 	}
 }
 
@@ -1859,6 +3521,388 @@ type walkFuncVisitor func(Node)
 
 func (fn walkFuncVisitor) Visit(n Node) Visitor { fn(n); return fn }
 
+// Dump returns an indented representation of a node hierarchy for debugging.
+// Each node is written on its own line with its type and details and its
+// children are indented beneath it. Unlike String(), the output is not
+// valid InfluxQL.
+func Dump(node Node) string {
+	d := &dumper{}
+	d.dump(node)
+	return d.buf.String()
+}
+
+// dumper writes nodes to a buffer at an increasing indentation level.
+type dumper struct {
+	buf   bytes.Buffer
+	depth int
+}
+
+// line writes a single indented line.
+func (d *dumper) line(format string, a ...interface{}) {
+	_, _ = d.buf.WriteString(strings.Repeat("  ", d.depth))
+	_, _ = fmt.Fprintf(&d.buf, format, a...)
+	_ = d.buf.WriteByte('\n')
+}
+
+// child writes node one level deeper than the current node.
+func (d *dumper) child(node Node) {
+	d.depth++
+	d.dump(node)
+	d.depth--
+}
+
+// section writes a label followed by node, indented beneath the current
+// node. Lists are written as their items. Nothing is written if node is
+// nil or empty.
+func (d *dumper) section(label string, node Node) {
+	var children []Node
+	switch n := node.(type) {
+	case nil:
+	case Fields:
+		for _, f := range n {
+			children = append(children, f)
+		}
+	case Dimensions:
+		for _, dim := range n {
+			children = append(children, dim)
+		}
+	case SortFields:
+		for _, f := range n {
+			children = append(children, f)
+		}
+	case *Target:
+		if n != nil {
+			children = append(children, n)
+		}
+	case *SelectStatement:
+		if n != nil {
+			children = append(children, n)
+		}
+	default:
+		children = append(children, n)
+	}
+
+	if len(children) == 0 {
+		return
+	}
+
+	d.depth++
+	d.line("%s:", label)
+	for _, c := range children {
+		d.child(c)
+	}
+	d.depth--
+}
+
+// limit writes the LIMIT and OFFSET values beneath the current node. A zero
+// value is still written when hasLimit or hasOffset marks it as explicit.
+func (d *dumper) limit(limit, offset int, hasLimit, hasOffset bool) {
+	d.depth++
+	if limit > 0 || hasLimit {
+		d.line("Limit: %d", limit)
+	}
+	if offset > 0 || hasOffset {
+		d.line("Offset: %d", offset)
+	}
+	d.depth--
+}
+
+// dump writes node and its children at the current indentation level.
+func (d *dumper) dump(node Node) {
+	switch n := node.(type) {
+	case *Query:
+		d.line("Query")
+		d.child(n.Statements)
+
+	case Statements:
+		d.line("Statements")
+		for _, s := range n {
+			d.child(s)
+		}
+
+	case *SelectStatement:
+		d.line("SelectStatement")
+		d.section("Fields", n.Fields)
+		d.section("Target", n.Target)
+		d.section("Source", n.Source)
+		d.section("Condition", n.Condition)
+		d.section("Dimensions", n.Dimensions)
+		d.section("SortFields", n.SortFields)
+		d.limit(n.Limit, n.Offset, n.HasLimit, n.HasOffset)
+
+	case *DeleteStatement:
+		d.line("DeleteStatement")
+		d.section("Source", n.Source)
+		d.section("Condition", n.Condition)
+
+	case *DropSeriesStatement:
+		if n.SeriesID > 0 {
+			d.line("DropSeriesStatement(%d)", n.SeriesID)
+		} else {
+			d.line("DropSeriesStatement")
+		}
+		d.section("Source", n.Source)
+		d.section("Condition", n.Condition)
+
+	case *ShowSeriesStatement:
+		d.line("ShowSeriesStatement")
+		d.section("Source", n.Source)
+		d.section("Condition", n.Condition)
+		d.section("SortFields", n.SortFields)
+		d.limit(n.Limit, n.Offset, false, false)
+
+	case *ShowMeasurementsStatement:
+		d.line("ShowMeasurementsStatement")
+		d.section("Condition", n.Condition)
+		d.section("SortFields", n.SortFields)
+		d.limit(n.Limit, n.Offset, false, false)
+
+	case *ShowTagKeysStatement:
+		d.line("ShowTagKeysStatement")
+		d.section("Source", n.Source)
+		d.section("Condition", n.Condition)
+		d.section("SortFields", n.SortFields)
+		d.limit(n.Limit, n.Offset, false, false)
+
+	case *ShowTagValuesStatement:
+		d.line("ShowTagValuesStatement(%s)", strings.Join(n.TagKeys, ", "))
+		d.section("Source", n.Source)
+		d.section("Condition", n.Condition)
+		d.section("SortFields", n.SortFields)
+		d.limit(n.Limit, n.Offset, false, false)
+
+	case *ShowFieldKeysStatement:
+		d.line("ShowFieldKeysStatement")
+		d.section("Source", n.Source)
+		d.section("SortFields", n.SortFields)
+		d.limit(n.Limit, n.Offset, false, false)
+
+	case *ShowTagKeyCardinalityStatement:
+		d.line("ShowTagKeyCardinalityStatement(ON %s)", n.Database)
+		d.section("Source", n.Source)
+		d.section("Condition", n.Condition)
+
+	case *ShowTagValuesCardinalityStatement:
+		d.line("ShowTagValuesCardinalityStatement(ON %s, KEYS %s)", n.Database, strings.Join(n.TagKeys, ", "))
+		d.section("Source", n.Source)
+		d.section("Condition", n.Condition)
+
+	case *ShowFieldKeyCardinalityStatement:
+		d.line("ShowFieldKeyCardinalityStatement(ON %s)", n.Database)
+		d.section("Source", n.Source)
+		d.section("Condition", n.Condition)
+
+	case *CreateContinuousQueryStatement:
+		d.line("CreateContinuousQueryStatement(%s ON %s)", n.Name, n.Database)
+		d.section("Source", n.Source)
+
+	case Fields:
+		d.line("Fields")
+		for _, f := range n {
+			d.child(f)
+		}
+
+	case *Field:
+		if n.Alias != "" {
+			d.line("Field(AS %s)", n.Alias)
+		} else {
+			d.line("Field")
+		}
+		d.child(n.Expr)
+
+	case Dimensions:
+		d.line("Dimensions")
+		for _, dim := range n {
+			d.child(dim)
+		}
+
+	case *Dimension:
+		if n.Alias != "" {
+			d.line("Dimension(AS %s)", n.Alias)
+		} else {
+			d.line("Dimension")
+		}
+		d.child(n.Expr)
+
+	case SortFields:
+		d.line("SortFields")
+		for _, f := range n {
+			d.child(f)
+		}
+
+	case Measurements:
+		d.line("Measurements")
+		for _, m := range n {
+			d.child(m)
+		}
+
+	case *Join:
+		d.line("Join")
+		d.child(n.Measurements)
+
+	case *Merge:
+		d.line("Merge")
+		d.child(n.Measurements)
+
+	case *BinaryExpr:
+		d.line("BinaryExpr(%s)", n.Op)
+		d.child(n.LHS)
+		d.child(n.RHS)
+
+	case *ParenExpr:
+		d.line("ParenExpr")
+		d.child(n.Expr)
+
+	case *Call:
+		d.line("Call(%s)", n.Name)
+		for _, arg := range n.Args {
+			d.child(arg)
+		}
+
+	case *ListLiteral:
+		d.line("ListLiteral")
+		for _, v := range n.Vals {
+			d.child(v)
+		}
+
+	case *Wildcard:
+		d.line("Wildcard")
+
+	case *nilLiteral:
+		d.line("nil")
+
+	case nil:
+		d.line("<nil>")
+
+	default:
+		// Leaf nodes and statements without nested expressions are
+		// written with their string representation.
+		d.line("%s(%s)", strings.TrimPrefix(fmt.Sprintf("%T", node), "*influxql."), node.String())
+	}
+}
+
+// FormatOptions represents the layout options used by Format.
+type FormatOptions struct {
+	// Indent is written once per level of nesting, such as the body of a
+	// continuous query. Defaults to two spaces if blank.
+	Indent string
+}
+
+// Format returns node as multi-line InfluxQL for display.
+//
+// Each clause of a SELECT statement starts on its own line with fields
+// aligned beneath the first field and AND conditions on separate lines.
+// The SELECT statement of a continuous query is indented between BEGIN and
+// END. Other nodes are written on a single line using String(). The output
+// parses to the same AST as node.
+func Format(node Node, opts FormatOptions) string {
+	if opts.Indent == "" {
+		opts.Indent = "  "
+	}
+	return strings.Join(formatLines(node, &opts), "\n")
+}
+
+// formatLines returns the lines of a formatted node.
+func formatLines(node Node, opts *FormatOptions) []string {
+	switch n := node.(type) {
+	case *Query:
+		return formatLines(n.Statements, opts)
+	case Statements:
+		var lines []string
+		for i, stmt := range n {
+			if i > 0 {
+				lines[len(lines)-1] += ";"
+				lines = append(lines, "")
+			}
+			lines = append(lines, formatLines(stmt, opts)...)
+		}
+		return lines
+	case *SelectStatement:
+		return formatSelect(n)
+	case *CreateContinuousQueryStatement:
+		return formatContinuousQuery(n, opts)
+	}
+	return []string{node.String()}
+}
+
+// formatSelect returns the lines of a formatted SELECT statement.
+func formatSelect(s *SelectStatement) []string {
+	var lines []string
+
+	// Write one field per line aligned after the SELECT keyword.
+	pad := strings.Repeat(" ", len("SELECT "))
+	for i, f := range s.Fields {
+		line := pad + f.String()
+		if i == 0 {
+			line = "SELECT " + f.String()
+		}
+		if i < len(s.Fields)-1 {
+			line += ","
+		}
+		lines = append(lines, line)
+	}
+
+	if s.Target != nil {
+		lines = append(lines, s.Target.String())
+	}
+	if s.Source != nil {
+		lines = append(lines, "FROM "+s.Source.String())
+	}
+	if s.Condition != nil {
+		// Only the left-hand chain of AND operators is split across lines
+		// so the condition parses back to the same tree.
+		terms := andChain(s.Condition)
+		lines = append(lines, "WHERE "+terms[0].String())
+		for _, term := range terms[1:] {
+			lines = append(lines, "  AND "+term.String())
+		}
+	}
+	if len(s.Dimensions) > 0 {
+		lines = append(lines, "GROUP BY "+s.Dimensions.String())
+	}
+	if len(s.SortFields) > 0 {
+		lines = append(lines, "ORDER BY "+s.SortFields.String())
+	}
+	if s.Limit > 0 || s.HasLimit {
+		lines = append(lines, "LIMIT "+strconv.Itoa(s.Limit))
+	}
+	if s.Offset > 0 || s.HasOffset {
+		lines = append(lines, "OFFSET "+strconv.Itoa(s.Offset))
+	}
+	return lines
+}
+
+// andChain returns the operands of the AND operators along the left-hand
+// side of expr. Returns expr alone if it is not an AND expression.
+func andChain(expr Expr) []Expr {
+	if e, ok := expr.(*BinaryExpr); ok && e.Op == AND {
+		return append(andChain(e.LHS), e.RHS)
+	}
+	return []Expr{expr}
+}
+
+// formatContinuousQuery returns the lines of a formatted continuous query.
+func formatContinuousQuery(s *CreateContinuousQueryStatement, opts *FormatOptions) []string {
+	lines := []string{fmt.Sprintf("CREATE CONTINUOUS QUERY %s ON %s", s.Name, s.Database)}
+
+	if s.ResampleEvery > 0 || s.ResampleFor > 0 {
+		line := "RESAMPLE"
+		if s.ResampleEvery > 0 {
+			line += " EVERY " + FormatDuration(s.ResampleEvery)
+		}
+		if s.ResampleFor > 0 {
+			line += " FOR " + FormatDuration(s.ResampleFor)
+		}
+		lines = append(lines, line)
+	}
+
+	lines = append(lines, "BEGIN")
+	for _, line := range formatLines(s.Source, opts) {
+		lines = append(lines, opts.Indent+line)
+	}
+	return append(lines, "END")
+}
+
 // Rewriter can be called by Rewrite to replace nodes in the AST hierarchy.
 // The Rewrite() function is called once per node.
 type Rewriter interface {
@@ -1910,6 +3954,11 @@ func Rewrite(r Rewriter, node Node) Node {
 		for i, expr := range n.Args {
 			n.Args[i] = Rewrite(r, expr).(Expr)
 		}
+
+	case *ListLiteral:
+		for i, expr := range n.Vals {
+			n.Vals[i] = Rewrite(r, expr).(Expr)
+		}
 	}
 
 	return r.Rewrite(node)
@@ -1924,7 +3973,8 @@ type rewriterFunc func(Node) Node
 
 func (fn rewriterFunc) Rewrite(n Node) Node { return fn(n) }
 
-// Eval evaluates expr against a map.
+// Eval evaluates expr against a map. LIKE patterns are only matched once
+// Reduce has converted them to regexes.
 func Eval(expr Expr, m map[string]interface{}) interface{} {
 	if expr == nil {
 		return nil
@@ -1939,6 +3989,8 @@ func Eval(expr Expr, m map[string]interface{}) interface{} {
 		return expr.Val
 	case *ParenExpr:
 		return Eval(expr.Expr, m)
+	case *RegexLiteral:
+		return expr.Val
 	case *StringLiteral:
 		return expr.Val
 	case *VarRef:
@@ -1990,6 +4042,15 @@ func evalBinaryExpr(expr *BinaryExpr, m map[string]interface{}) interface{} {
 			return lhs / rhs
 		}
 	case string:
+		switch expr.Op {
+		case EQREGEX, NEQREGEX:
+			re, ok := rhs.(*regexp.Regexp)
+			if !ok {
+				return nil
+			}
+			return re.MatchString(lhs) == (expr.Op == EQREGEX)
+		}
+
 		rhs, _ := rhs.(string)
 		switch expr.Op {
 		case EQ:
@@ -2001,8 +4062,47 @@ func evalBinaryExpr(expr *BinaryExpr, m map[string]interface{}) interface{} {
 	return nil
 }
 
+// ErrInvalidLikePattern is returned when a LIKE pattern ends with an
+// unescaped backslash.
+var ErrInvalidLikePattern = errors.New("invalid LIKE pattern: trailing backslash")
+
+// LikeRegexp converts a LIKE pattern to an anchored regular expression.
+// A "%" matches any sequence of characters and "_" matches any single
+// character. A backslash matches the following character literally so
+// "\\%" matches a percent sign.
+func LikeRegexp(pattern string) (*regexp.Regexp, error) {
+	var buf bytes.Buffer
+	_, _ = buf.WriteString(`(?s)^`)
+	for i := 0; i < len(pattern); {
+		ch, size := utf8.DecodeRuneInString(pattern[i:])
+		i += size
+
+		switch ch {
+		case '%':
+			_, _ = buf.WriteString(`.*`)
+		case '_':
+			_ = buf.WriteByte('.')
+		case '\\':
+			if i == len(pattern) {
+				return nil, ErrInvalidLikePattern
+			}
+			ch, size = utf8.DecodeRuneInString(pattern[i:])
+			i += size
+			_, _ = buf.WriteString(regexp.QuoteMeta(string(ch)))
+		default:
+			_, _ = buf.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	_ = buf.WriteByte('$')
+	return regexp.Compile(buf.String())
+}
+
 // Reduce evaluates expr using the available values in valuer.
 // References that don't exist in valuer are ignored.
+//
+// Constant subexpressions are folded and a new expression tree is returned;
+// the original expression is not modified. Arithmetic that would overflow is
+// left unreduced. Use a NowValuer to fold now() against a fixed clock.
 func Reduce(expr Expr, valuer Valuer) Expr {
 	expr = reduce(expr, valuer)
 
@@ -2078,8 +4178,24 @@ func reduceBinaryExpr(expr *BinaryExpr, valuer Valuer) Expr {
 	case *TimeLiteral:
 		return reduceBinaryExprTimeLHS(op, lhs, rhs)
 	default:
-		return &BinaryExpr{Op: op, LHS: lhs, RHS: rhs}
+		return reduceBinaryExprLike(op, lhs, rhs)
+	}
+}
+
+// reduceBinaryExprLike converts a LIKE pattern to an equivalent regex match
+// so the pattern is compiled once instead of for every evaluated point.
+func reduceBinaryExprLike(op Token, lhs, rhs Expr) Expr {
+	if lit, ok := rhs.(*StringLiteral); ok && (op == LIKE || op == NLIKE) {
+		if re, err := LikeRegexp(lit.Val); err == nil {
+			if op == LIKE {
+				op = EQREGEX
+			} else {
+				op = NEQREGEX
+			}
+			return &BinaryExpr{Op: op, LHS: lhs, RHS: &RegexLiteral{Val: re}}
+		}
 	}
+	return &BinaryExpr{Op: op, LHS: lhs, RHS: rhs}
 }
 
 func reduceBinaryExprBooleanLHS(op Token, lhs *BooleanLiteral, rhs Expr) Expr {
@@ -2106,9 +4222,13 @@ func reduceBinaryExprDurationLHS(op Token, lhs *DurationLiteral, rhs Expr) Expr
 	case *DurationLiteral:
 		switch op {
 		case ADD:
-			return &DurationLiteral{Val: lhs.Val + rhs.Val}
+			if d, ok := addDuration(lhs.Val, rhs.Val); ok {
+				return &DurationLiteral{Val: d}
+			}
 		case SUB:
-			return &DurationLiteral{Val: lhs.Val - rhs.Val}
+			if d, ok := addDuration(lhs.Val, -rhs.Val); ok && rhs.Val != math.MinInt64 {
+				return &DurationLiteral{Val: d}
+			}
 		case EQ:
 			return &BooleanLiteral{Val: lhs.Val == rhs.Val}
 		case NEQ:
@@ -2125,12 +4245,15 @@ func reduceBinaryExprDurationLHS(op Token, lhs *DurationLiteral, rhs Expr) Expr
 	case *NumberLiteral:
 		switch op {
 		case MUL:
-			return &DurationLiteral{Val: lhs.Val * time.Duration(rhs.Val)}
+			if d, ok := floatDuration(float64(lhs.Val) * rhs.Val); ok {
+				return &DurationLiteral{Val: d}
+			}
 		case DIV:
 			if rhs.Val == 0 {
 				return &DurationLiteral{Val: 0}
+			} else if d, ok := floatDuration(float64(lhs.Val) / rhs.Val); ok {
+				return &DurationLiteral{Val: d}
 			}
-			return &DurationLiteral{Val: lhs.Val / time.Duration(rhs.Val)}
 		}
 	case *TimeLiteral:
 		switch op {
@@ -2156,16 +4279,23 @@ func reduceBinaryExprNumberLHS(op Token, lhs *NumberLiteral, rhs Expr) Expr {
 	case *NumberLiteral:
 		switch op {
 		case ADD:
-			return &NumberLiteral{Val: lhs.Val + rhs.Val}
+			if v := lhs.Val + rhs.Val; isFinite(v) {
+				return &NumberLiteral{Val: v}
+			}
 		case SUB:
-			return &NumberLiteral{Val: lhs.Val - rhs.Val}
+			if v := lhs.Val - rhs.Val; isFinite(v) {
+				return &NumberLiteral{Val: v}
+			}
 		case MUL:
-			return &NumberLiteral{Val: lhs.Val * rhs.Val}
+			if v := lhs.Val * rhs.Val; isFinite(v) {
+				return &NumberLiteral{Val: v}
+			}
 		case DIV:
 			if rhs.Val == 0 {
 				return &NumberLiteral{Val: 0}
+			} else if v := lhs.Val / rhs.Val; isFinite(v) {
+				return &NumberLiteral{Val: v}
 			}
-			return &NumberLiteral{Val: lhs.Val / rhs.Val}
 		case EQ:
 			return &BooleanLiteral{Val: lhs.Val == rhs.Val}
 		case NEQ:
@@ -2179,12 +4309,40 @@ func reduceBinaryExprNumberLHS(op Token, lhs *NumberLiteral, rhs Expr) Expr {
 		case LTE:
 			return &BooleanLiteral{Val: lhs.Val <= rhs.Val}
 		}
+	case *DurationLiteral:
+		switch op {
+		case MUL:
+			if d, ok := floatDuration(lhs.Val * float64(rhs.Val)); ok {
+				return &DurationLiteral{Val: d}
+			}
+		}
 	case *nilLiteral:
 		return &BooleanLiteral{Val: false}
 	}
 	return &BinaryExpr{Op: op, LHS: lhs, RHS: rhs}
 }
 
+// addDuration returns the sum of two durations.
+// Returns false if the sum overflows a time.Duration.
+func addDuration(a, b time.Duration) (time.Duration, bool) {
+	if c := a + b; (c > a) == (b > 0) {
+		return c, true
+	}
+	return 0, false
+}
+
+// floatDuration converts a number of nanoseconds to a duration.
+// Returns false if the value cannot be represented as a time.Duration.
+func floatDuration(v float64) (time.Duration, bool) {
+	if math.IsNaN(v) || v >= math.MaxInt64 || v < math.MinInt64 {
+		return 0, false
+	}
+	return time.Duration(v), true
+}
+
+// isFinite returns true if v is neither infinite nor NaN.
+func isFinite(v float64) bool { return !math.IsInf(v, 0) && !math.IsNaN(v) }
+
 func reduceBinaryExprStringLHS(op Token, lhs *StringLiteral, rhs Expr) Expr {
 	switch rhs := rhs.(type) {
 	case *StringLiteral:
@@ -2195,6 +4353,10 @@ func reduceBinaryExprStringLHS(op Token, lhs *StringLiteral, rhs Expr) Expr {
 			return &BooleanLiteral{Val: lhs.Val != rhs.Val}
 		case ADD:
 			return &StringLiteral{Val: lhs.Val + rhs.Val}
+		case LIKE, NLIKE:
+			if re, err := LikeRegexp(rhs.Val); err == nil {
+				return &BooleanLiteral{Val: re.MatchString(lhs.Val) == (op == LIKE)}
+			}
 		}
 	case *nilLiteral:
 		switch op {
@@ -2265,14 +4427,14 @@ func reduceParenExpr(expr *ParenExpr, valuer Valuer) Expr {
 func reduceVarRef(expr *VarRef, valuer Valuer) Expr {
 	// Ignore if there is no valuer.
 	if valuer == nil {
-		return &VarRef{Val: expr.Val}
+		return &VarRef{Val: expr.Val, Type: expr.Type}
 	}
 
 	// Retrieve the value of the ref.
 	// Ignore if the value doesn't exist.
 	v, ok := valuer.Value(expr.Val)
 	if !ok {
-		return &VarRef{Val: expr.Val}
+		return &VarRef{Val: expr.Val, Type: expr.Type}
 	}
 
 	// Return the value as a literal.
@@ -2299,12 +4461,14 @@ type Valuer interface {
 	Value(key string) (interface{}, bool)
 }
 
-// nowValuer returns only the value for "now()".
-type nowValuer struct {
+// NowValuer returns only the value for "now()".
+// It can be passed to Reduce to fold now() into a time literal.
+type NowValuer struct {
 	Now time.Time
 }
 
-func (v *nowValuer) Value(key string) (interface{}, bool) {
+// Value returns the value for a key. Only "now()" is supported.
+func (v *NowValuer) Value(key string) (interface{}, bool) {
 	if key == "now()" {
 		return v.Now, true
 	}
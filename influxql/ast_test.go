@@ -1,7 +1,10 @@
 package influxql_test
 
 import (
+	"flag"
 	"fmt"
+	"io/ioutil"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -10,6 +13,8 @@ import (
 	"github.com/influxdb/influxdb/influxql"
 )
 
+var update = flag.Bool("update", false, "update golden files in testdata")
+
 // Ensure a value's data type can be retrieved.
 func TestInspectDataType(t *testing.T) {
 	for i, tt := range []struct {
@@ -25,6 +30,151 @@ func TestInspectDataType(t *testing.T) {
 	}
 }
 
+// Ensure each statement reports its type.
+func TestStatement_StatementType(t *testing.T) {
+	for i, tt := range []struct {
+		s   string
+		typ string
+	}{
+		{s: `ALTER RETENTION POLICY policy1 ON testdb DEFAULT`, typ: "ALTER RETENTION POLICY"},
+		{s: `CREATE CONTINUOUS QUERY cq ON db BEGIN SELECT value INTO m FROM cpu END`, typ: "CREATE CONTINUOUS QUERY"},
+		{s: `CREATE DATABASE testdb`, typ: "CREATE DATABASE"},
+		{s: `CREATE RETENTION POLICY policy1 ON testdb DURATION 1h REPLICATION 2`, typ: "CREATE RETENTION POLICY"},
+		{s: `CREATE USER testuser WITH PASSWORD 'pwd1337'`, typ: "CREATE USER"},
+		{s: `DELETE FROM myseries WHERE host = 'a'`, typ: "DELETE"},
+		{s: `DROP CONTINUOUS QUERY cq`, typ: "DROP CONTINUOUS QUERY"},
+		{s: `DROP DATABASE testdb`, typ: "DROP DATABASE"},
+		{s: `DROP MEASUREMENT cpu`, typ: "DROP MEASUREMENT"},
+		{s: `DROP RETENTION POLICY policy1 ON testdb`, typ: "DROP RETENTION POLICY"},
+		{s: `DROP SERIES 1`, typ: "DROP SERIES"},
+		{s: `DROP USER jdoe`, typ: "DROP USER"},
+		{s: `GRANT READ ON testdb TO jdoe`, typ: "GRANT"},
+		{s: `SHOW CONTINUOUS QUERIES`, typ: "SHOW CONTINUOUS QUERIES"},
+		{s: `SHOW DATABASES`, typ: "SHOW DATABASES"},
+		{s: `SHOW FIELD KEYS`, typ: "SHOW FIELD KEYS"},
+		{s: `SHOW FIELD KEY CARDINALITY`, typ: "SHOW FIELD KEY CARDINALITY"},
+		{s: `SHOW MEASUREMENTS`, typ: "SHOW MEASUREMENTS"},
+		{s: `SHOW RETENTION POLICIES testdb`, typ: "SHOW RETENTION POLICIES"},
+		{s: `SHOW SERIES`, typ: "SHOW SERIES"},
+		{s: `SHOW TAG KEYS`, typ: "SHOW TAG KEYS"},
+		{s: `SHOW TAG KEY CARDINALITY`, typ: "SHOW TAG KEY CARDINALITY"},
+		{s: `SHOW TAG VALUES WITH KEY = host`, typ: "SHOW TAG VALUES"},
+		{s: `SHOW TAG VALUES CARDINALITY WITH KEY = host`, typ: "SHOW TAG VALUES CARDINALITY"},
+		{s: `SHOW USERS`, typ: "SHOW USERS"},
+		{s: `REVOKE READ ON testdb FROM jdoe`, typ: "REVOKE"},
+		{s: `SELECT value FROM cpu`, typ: "SELECT"},
+	} {
+		stmt, err := influxql.ParseStatement(tt.s)
+		if err != nil {
+			t.Errorf("%d. %q: unexpected error: %s", i, tt.s, err)
+		} else if typ := stmt.StatementType(); typ != tt.typ {
+			t.Errorf("%d. %q: unexpected type: %s", i, tt.s, typ)
+		} else if !strings.HasPrefix(stmt.String(), typ) {
+			t.Errorf("%d. %q: type %q is not a prefix of %q", i, tt.s, typ, stmt.String())
+		}
+	}
+}
+
+// Ensure a statement returns the database it targets.
+func TestStatement_DefaultDatabase(t *testing.T) {
+	for i, tt := range []struct {
+		s  string
+		db string
+	}{
+		{s: `ALTER RETENTION POLICY policy1 ON testdb DEFAULT`, db: "testdb"},
+		{s: `CREATE CONTINUOUS QUERY cq ON db BEGIN SELECT value INTO m FROM cpu END`, db: "db"},
+		{s: `CREATE DATABASE testdb`, db: "testdb"},
+		{s: `CREATE RETENTION POLICY policy1 ON testdb DURATION 1h REPLICATION 2`, db: "testdb"},
+		{s: `CREATE USER testuser WITH PASSWORD 'pwd1337'`, db: ""},
+		{s: `DELETE FROM "testdb"."rp"."myseries" WHERE host = 'a'`, db: "testdb"},
+		{s: `DELETE FROM myseries WHERE host = 'a'`, db: ""},
+		{s: `DROP CONTINUOUS QUERY cq`, db: ""},
+		{s: `DROP DATABASE testdb`, db: "testdb"},
+		{s: `DROP MEASUREMENT "testdb"."rp"."cpu"`, db: "testdb"},
+		{s: `DROP MEASUREMENT cpu`, db: ""},
+		{s: `DROP RETENTION POLICY policy1 ON testdb`, db: "testdb"},
+		{s: `DROP SERIES FROM "testdb"."rp"."cpu"`, db: "testdb"},
+		{s: `DROP SERIES 1`, db: ""},
+		{s: `DROP USER jdoe`, db: ""},
+		{s: `GRANT READ ON testdb TO jdoe`, db: "testdb"},
+		{s: `GRANT ALL TO jdoe`, db: ""},
+		{s: `SHOW CONTINUOUS QUERIES`, db: ""},
+		{s: `SHOW DATABASES`, db: ""},
+		{s: `SHOW FIELD KEYS FROM "testdb"."rp"."cpu"`, db: "testdb"},
+		{s: `SHOW FIELD KEYS`, db: ""},
+		{s: `SHOW FIELD KEY CARDINALITY ON testdb FROM "db1"."rp"."cpu"`, db: "testdb"},
+		{s: `SHOW FIELD KEY CARDINALITY FROM "db1"."rp"."cpu"`, db: "db1"},
+		{s: `SHOW MEASUREMENTS`, db: ""},
+		{s: `SHOW RETENTION POLICIES testdb`, db: "testdb"},
+		{s: `SHOW RETENTION POLICIES ON testdb`, db: "testdb"},
+		{s: `SHOW RETENTION POLICIES`, db: ""},
+		{s: `SHOW SERIES FROM "testdb"."rp"."cpu"`, db: "testdb"},
+		{s: `SHOW SERIES`, db: ""},
+		{s: `SHOW TAG KEYS FROM "testdb"."rp"."cpu"`, db: "testdb"},
+		{s: `SHOW TAG KEYS`, db: ""},
+		{s: `SHOW TAG KEY CARDINALITY ON testdb`, db: "testdb"},
+		{s: `SHOW TAG KEY CARDINALITY`, db: ""},
+		{s: `SHOW TAG VALUES FROM "testdb"."rp"."cpu" WITH KEY = host`, db: "testdb"},
+		{s: `SHOW TAG VALUES WITH KEY = host`, db: ""},
+		{s: `SHOW TAG VALUES CARDINALITY ON testdb WITH KEY = host`, db: "testdb"},
+		{s: `SHOW TAG VALUES CARDINALITY FROM "testdb"."rp"."cpu" WITH KEY = host`, db: "testdb"},
+		{s: `SHOW USERS`, db: ""},
+		{s: `REVOKE READ ON testdb FROM jdoe`, db: "testdb"},
+		{s: `REVOKE ALL FROM jdoe`, db: ""},
+		{s: `SELECT value FROM "testdb"."rp"."cpu"`, db: "testdb"},
+		{s: `SELECT value FROM "testdb".."cpu"`, db: "testdb"},
+		{s: `SELECT value FROM cpu`, db: ""},
+		{s: `SELECT value FROM join("testdb"."rp"."cpu", "testdb"."rp"."mem")`, db: "testdb"},
+		{s: `SELECT value FROM merge("db0"."rp"."cpu", "db1"."rp"."cpu")`, db: ""},
+		{s: `SELECT 1 + 1`, db: ""},
+	} {
+		stmt, err := influxql.ParseStatement(tt.s)
+		if err != nil {
+			t.Errorf("%d. %q: unexpected error: %s", i, tt.s, err)
+		} else if db := stmt.DefaultDatabase(); db != tt.db {
+			t.Errorf("%d. %q: unexpected database: %q", i, tt.s, db)
+		}
+	}
+}
+
+// Ensure SHOW statements expose their shared clauses.
+func TestShowStatement_Clauses(t *testing.T) {
+	for i, tt := range []struct {
+		s         string
+		source    string
+		condition string
+		sort      string
+	}{
+		{s: `SHOW SERIES FROM cpu WHERE host = 'a' ORDER BY host DESC LIMIT 10 OFFSET 20`, source: `cpu`, condition: `host = 'a'`, sort: `host DESC`},
+		{s: `SHOW MEASUREMENTS WHERE host = 'a' ORDER BY host DESC LIMIT 10 OFFSET 20`, condition: `host = 'a'`, sort: `host DESC`},
+		{s: `SHOW TAG KEYS FROM cpu WHERE host = 'a' ORDER BY host DESC LIMIT 10 OFFSET 20`, source: `cpu`, condition: `host = 'a'`, sort: `host DESC`},
+		{s: `SHOW TAG VALUES FROM cpu WITH KEY = host WHERE host = 'a' ORDER BY host DESC LIMIT 10 OFFSET 20`, source: `cpu`, condition: `host = 'a'`, sort: `host DESC`},
+		{s: `SHOW FIELD KEYS FROM cpu ORDER BY host DESC LIMIT 10 OFFSET 20`, source: `cpu`, sort: `host DESC`},
+	} {
+		stmt, err := influxql.ParseStatement(tt.s)
+		if err != nil {
+			t.Errorf("%d. %q: unexpected error: %s", i, tt.s, err)
+			continue
+		}
+
+		c := stmt.(influxql.ShowStatement).Clauses()
+		if c.Source != nil && c.Source.String() != tt.source || c.Source == nil && tt.source != "" {
+			t.Errorf("%d. %q: unexpected source: %v", i, tt.s, c.Source)
+		} else if c.Condition != nil && c.Condition.String() != tt.condition || c.Condition == nil && tt.condition != "" {
+			t.Errorf("%d. %q: unexpected condition: %v", i, tt.s, c.Condition)
+		} else if c.SortFields.String() != tt.sort {
+			t.Errorf("%d. %q: unexpected sort fields: %s", i, tt.s, c.SortFields)
+		} else if c.Limit != 10 || c.Offset != 20 {
+			t.Errorf("%d. %q: unexpected limit/offset: %d/%d", i, tt.s, c.Limit, c.Offset)
+		}
+	}
+
+	// Statements without shared clauses do not implement the interface.
+	if _, ok := influxql.Statement(&influxql.ShowDatabasesStatement{}).(influxql.ShowStatement); ok {
+		t.Fatal("unexpected ShowStatement: SHOW DATABASES")
+	}
+}
+
 // Ensure the SELECT statement can extract substatements.
 func TestSelectStatement_Substatement(t *testing.T) {
 	var tests = []struct {
@@ -96,6 +246,412 @@ func TestSelectStatement_Substatement(t *testing.T) {
 	}
 }
 
+// Ensure the SELECT statement can validate its dimensions.
+func TestSelectStatement_Validate(t *testing.T) {
+	for i, tt := range []struct {
+		stmt string
+		err  string
+	}{
+		{stmt: `SELECT mean(value) FROM cpu`},
+		{stmt: `SELECT mean(value) FROM cpu GROUP BY host, time(1m)`},
+		{stmt: `SELECT mean(value) FROM cpu GROUP BY *`},
+		{stmt: `SELECT mean(value) FROM cpu GROUP BY *, time(1m)`},
+		{stmt: `SELECT mean(value) FROM cpu GROUP BY time(1m), *`},
+		{stmt: `SELECT mean(value) FROM cpu GROUP BY *, host`, err: `invalid dimension host: GROUP BY * cannot be combined with other tags`},
+		{stmt: `SELECT mean(value) FROM cpu GROUP BY host, *`, err: `invalid dimension *: GROUP BY * cannot be combined with other tags`},
+		{stmt: `SELECT mean(value) FROM cpu GROUP BY *, *`, err: `multiple GROUP BY * not allowed`},
+		{stmt: `SELECT mean(value) FROM cpu GROUP BY 5`, err: `invalid dimension 5.000: only tags and time() allowed in GROUP BY`},
+		{stmt: `SELECT mean(value) FROM cpu GROUP BY 'host'`, err: `invalid dimension 'host': only tags and time() allowed in GROUP BY`},
+		{stmt: `SELECT mean(value) FROM cpu GROUP BY mean(value)`, err: `invalid dimension mean(value): only time() calls allowed in GROUP BY`},
+		{stmt: `SELECT mean(value) FROM cpu GROUP BY time()`, err: `time dimension expected one argument`},
+		{stmt: `SELECT mean(value) FROM cpu GROUP BY time(host)`, err: `time dimension must have one duration argument`},
+		{stmt: `SELECT mean(value) FROM cpu GROUP BY time(1m), time(1h)`, err: `multiple time dimensions not allowed`},
+		{stmt: `SELECT now() - 1h`},
+		{stmt: `SELECT mean(value) * 100 / max(value) FROM cpu GROUP BY time(1m)`},
+		{stmt: `SELECT (mean(value) + 1) * 2, max(value) - min(value) FROM cpu`},
+		{stmt: `SELECT time, mean(value) FROM cpu GROUP BY time(1m)`},
+		{stmt: `SELECT value * 2, value FROM cpu`},
+		{stmt: `SELECT value * mean(value) FROM cpu`, err: `invalid field value * mean(value): mixing aggregate and non-aggregate values is not supported`},
+		{stmt: `SELECT mean(value) - (value) FROM cpu`, err: `invalid field mean(value) - (value): mixing aggregate and non-aggregate values is not supported`},
+		{stmt: `SELECT value, mean(value) FROM cpu`, err: `invalid field value: mixing aggregate and non-aggregate values is not supported`},
+		{stmt: `SELECT time, value FROM cpu`},
+		{stmt: `SELECT mean(time) FROM cpu`, err: `invalid function mean(time): cannot aggregate the time column`},
+		{stmt: `SELECT max(value) - min(TIME) FROM cpu`, err: `invalid function min(TIME): cannot aggregate the time column`},
+		{stmt: `SELECT time, value FROM cpu GROUP BY time(1m)`, err: `invalid dimension time(1m): GROUP BY time() requires an aggregate function`},
+		{stmt: `SELECT value FROM cpu GROUP BY host`},
+		{stmt: `SELECT count(*) FROM cpu`},
+		{stmt: `SELECT a AS x, b AS y FROM m`},
+		{stmt: `SELECT a, a FROM m`},
+		{stmt: `SELECT mean(a), mean(a) AS mean FROM m`},
+		{stmt: `SELECT a AS x, b AS x FROM m`, err: `duplicate alias x: field 1 (a AS x) and field 2 (b AS x)`},
+		{stmt: `SELECT a AS x, c, mean(b) AS x FROM m`, err: `duplicate alias x: field 1 (a AS x) and field 3 (mean(b) AS x)`},
+		{stmt: `SELECT mean(*) FROM cpu GROUP BY time(1m)`},
+		{stmt: `SELECT MAX(*) AS m FROM cpu`},
+		{stmt: `SELECT percentile(*, 90) FROM cpu`, err: `invalid function percentile(*, 90.000): wildcard argument not supported`},
+		{stmt: `SELECT derivative(*) FROM cpu`, err: `invalid function derivative(*): wildcard argument not supported`},
+		{stmt: `SELECT mean(*) + 1 FROM cpu`, err: `invalid function mean(*): wildcard must be the only argument of a field`},
+		{stmt: `SELECT count(*, value) FROM cpu`, err: `invalid function count(*, value): wildcard must be the only argument of a field`},
+		{stmt: `SELECT sum(count(*)) FROM cpu`, err: `invalid function count(*): wildcard must be the only argument of a field`},
+		{stmt: `SELECT sample(value, 10) FROM cpu`},
+		{stmt: `SELECT SAMPLE(value, 1) FROM cpu WHERE host = 'a'`},
+		{stmt: `SELECT sample(value) FROM cpu`, err: `invalid function sample(value): expected 2 arguments, got 1`},
+		{stmt: `SELECT sample(value, 10, 2) FROM cpu`, err: `invalid function sample(value, 10.000, 2.000): expected 2 arguments, got 3`},
+		{stmt: `SELECT sample(mean(value), 10) FROM cpu`, err: `invalid function sample(mean(value), 10.000): first argument must be a field`},
+		{stmt: `SELECT sample(value, 1.5) FROM cpu`, err: `invalid function sample(value, 1.500): second argument must be a positive integer`},
+		{stmt: `SELECT sample(value, 0) FROM cpu`, err: `invalid function sample(value, 0.000): second argument must be a positive integer`},
+		{stmt: `SELECT sample(value, 'a') FROM cpu`, err: `invalid function sample(value, 'a'): second argument must be a positive integer`},
+		{stmt: `SELECT sum(value, host = 'a') FROM cpu`, err: `invalid function sum(value, host = 'a'): condition host = 'a' not allowed as an argument`},
+		{stmt: `SELECT mean(value) + max(value > 1 OR value < 0) FROM cpu`, err: `invalid function max(value > 1.000 OR value < 0.000): condition value > 1.000 OR value < 0.000 not allowed as an argument`},
+		{stmt: `SELECT holt_winters(mean(value), 10, 4) FROM cpu GROUP BY time(1m)`},
+		{stmt: `SELECT holt_winters_with_fit(max(value), 10, 0) FROM cpu GROUP BY time(1m)`},
+		{stmt: `SELECT holt_winters(mean(value), 10) FROM cpu GROUP BY time(1m)`, err: `invalid function holt_winters(mean(value), 10.000): expected 3 arguments, got 2`},
+		{stmt: `SELECT holt_winters(value, 10, 4) FROM cpu`, err: `invalid function holt_winters(value, 10.000, 4.000): first argument must be an aggregate function`},
+		{stmt: `SELECT holt_winters(mean(value), -1, 4) FROM cpu GROUP BY time(1m)`, err: `invalid function holt_winters(mean(value), -1.000, 4.000): second argument must be a positive integer`},
+		{stmt: `SELECT holt_winters(mean(value), 10, 0.5) FROM cpu GROUP BY time(1m)`, err: `invalid function holt_winters(mean(value), 10.000, 0.500): third argument must be a non-negative integer`},
+		{stmt: `SELECT mean(value) * 2 + sample(value) FROM cpu`, err: `invalid function sample(value): expected 2 arguments, got 1`},
+		{stmt: `SELECT derivative(value) FROM cpu`, err: `invalid function derivative(value): GROUP BY time() is required`},
+		{stmt: `SELECT derivative(value) FROM cpu GROUP BY time(1m)`},
+		{stmt: `SELECT derivative(value, 1s) FROM cpu GROUP BY time(1m)`},
+		{stmt: `SELECT non_negative_derivative(mean(value), 10s) FROM cpu GROUP BY time(1m)`},
+		{stmt: `SELECT derivative(value, 1) FROM cpu GROUP BY time(1m)`, err: `invalid function derivative(value, 1.000): second argument must be a duration`},
+		{stmt: `SELECT NON_NEGATIVE_DERIVATIVE(value, 'a') FROM cpu GROUP BY time(1m)`, err: `invalid function NON_NEGATIVE_DERIVATIVE(value, 'a'): second argument must be a duration`},
+		{stmt: `SELECT derivative(value, 1s, 1s) FROM cpu GROUP BY time(1m)`, err: `invalid function derivative(value, 1s, 1s): expected 1 or 2 arguments, got 3`},
+		{stmt: `SELECT derivative() FROM cpu GROUP BY time(1m)`, err: `invalid function derivative(): expected 1 or 2 arguments, got 0`},
+		{stmt: `SELECT elapsed(value) FROM cpu`},
+		{stmt: `SELECT elapsed(value, 1ms) FROM cpu`},
+		{stmt: `SELECT elapsed(value, 1) FROM cpu`, err: `invalid function elapsed(value, 1.000): second argument must be a duration`},
+		{stmt: `SELECT elapsed(mean(value)) FROM cpu`, err: `invalid function elapsed(mean(value)): first argument must be a field`},
+		{stmt: `SELECT moving_average(value, 3) FROM cpu`},
+		{stmt: `SELECT moving_average(mean(value), 2) FROM cpu GROUP BY time(1m)`},
+		{stmt: `SELECT moving_average(value) FROM cpu`, err: `invalid function moving_average(value): expected 2 arguments, got 1`},
+		{stmt: `SELECT moving_average(value, 1) FROM cpu`, err: `invalid function moving_average(value, 1.000): second argument must be an integer greater than 1`},
+		{stmt: `SELECT moving_average(value, 1m) FROM cpu`, err: `invalid function moving_average(value, 1m): second argument must be an integer greater than 1`},
+		{stmt: `SELECT cumulative_sum(value) FROM cpu`},
+		{stmt: `SELECT cumulative_sum(value, 2) FROM cpu`, err: `invalid function cumulative_sum(value, 2.000): expected 1 argument, got 2`},
+		{stmt: `SELECT DIFFERENCE(max(value)) FROM cpu GROUP BY host`, err: `invalid function DIFFERENCE(max(value)): GROUP BY time() is required`},
+		{stmt: `SELECT difference(max(value)) FROM cpu GROUP BY time(1m), host`},
+		{stmt: `SELECT max(value) + non_negative_derivative(mean(value)) FROM cpu`, err: `invalid function non_negative_derivative(mean(value)): GROUP BY time() is required`},
+		{stmt: `SELECT holt_winters(mean(value), 10, 4) FROM cpu`, err: `invalid function holt_winters(mean(value), 10.000, 4.000): GROUP BY time() is required`},
+		{stmt: `SELECT max(value) FROM cpu`},
+	} {
+		stmt := MustParseSelectStatement(tt.stmt)
+		if err := stmt.Validate(); errstring(err) != tt.err {
+			t.Errorf("%d. %q: error mismatch:\n  exp=%s\n  got=%s", i, tt.stmt, tt.err, err)
+		}
+	}
+
+	// Lists must be function arguments with elements of a single type.
+	for i, tt := range []struct {
+		stmt string
+		err  string
+	}{
+		{stmt: `SELECT f(value, [1, 2, 3]) FROM cpu`},
+		{stmt: `SELECT f(value, []) FROM cpu`},
+		{stmt: `SELECT f(value, ['a', 'b']) FROM cpu`},
+		{stmt: `SELECT [1, 2] FROM cpu`, err: `invalid list [1.000, 2.000]: only supported as a function argument`},
+		{stmt: `SELECT f(value, [1] + 2) FROM cpu`, err: `invalid list [1.000]: only supported as a function argument`},
+		{stmt: `SELECT f(value, [1, 'a']) FROM cpu`, err: `invalid list [1.000, 'a']: mixed element types number and string`},
+		{stmt: `SELECT f(value, [1, host]) FROM cpu`, err: `invalid list [1.000, host]: element host is not a literal`},
+		{stmt: `SELECT f(value, [1]) FROM cpu WHERE host = [1, 'a']`, err: `invalid list [1.000, 'a']: only supported as a function argument`},
+		{stmt: `SELECT value FROM cpu WHERE f(value, [1, 'a']) > 0`, err: `invalid list [1.000, 'a']: mixed element types number and string`},
+		{stmt: `SELECT value FROM cpu WHERE f(value, [1, 2]) > 0`},
+		{stmt: `SELECT now() - 1h, 2 * 3`},
+		{stmt: `SELECT sum(1)`, err: `invalid function sum(1.000): only now() can be called without a FROM clause`},
+	} {
+		p := influxql.NewParserWithOptions(strings.NewReader(tt.stmt), influxql.ParserOptions{ListLiterals: true})
+		stmt, err := p.ParseStatement()
+		if err != nil {
+			t.Fatalf("%d. %q: %s", i, tt.stmt, err)
+		}
+		if err := stmt.(*influxql.SelectStatement).Validate(); errstring(err) != tt.err {
+			t.Errorf("%d. %q: error mismatch:\n  exp=%s\n  got=%s", i, tt.stmt, tt.err, err)
+		}
+	}
+
+	// Variable references require a source.
+	stmt := &influxql.SelectStatement{Fields: influxql.Fields{{Expr: &influxql.VarRef{Val: "value"}}}}
+	if err := stmt.Validate(); errstring(err) != `fields require a FROM clause` {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The time column is selected as a variable reference.
+	stmt = MustParseSelectStatement(`SELECT time, value FROM cpu`)
+	if ref, ok := stmt.Fields[0].Expr.(*influxql.VarRef); !ok || ref.Val != "time" {
+		t.Fatalf("unexpected time field: %#v", stmt.Fields[0].Expr)
+	}
+}
+
+// Ensure measurements in the source and target can be renamed without
+// modifying the original statement.
+func TestSelectStatement_RewriteMeasurements(t *testing.T) {
+	prefix := func(m *influxql.Measurement) *influxql.Measurement {
+		return &influxql.Measurement{Name: "t1_" + m.Name}
+	}
+	for i, tt := range []struct {
+		stmt string
+		exp  string
+	}{
+		{stmt: `SELECT value FROM cpu`, exp: `SELECT value FROM t1_cpu`},
+		{stmt: `SELECT value FROM merge(cpu, mem) WHERE host = 'a'`, exp: `SELECT value FROM merge(t1_cpu, t1_mem) WHERE host = 'a'`},
+		{stmt: `SELECT value FROM join(cpu, mem)`, exp: `SELECT value FROM join(t1_cpu, t1_mem)`},
+		{stmt: `SELECT mean(value) INTO cpu_1h FROM cpu GROUP BY time(1h)`, exp: `SELECT mean(value) INTO t1_cpu_1h FROM t1_cpu GROUP BY time(1h)`},
+		{stmt: `SELECT now()`, exp: `SELECT now()`},
+	} {
+		stmt := MustParseSelectStatement(tt.stmt)
+		if other := stmt.RewriteMeasurements(prefix); other.String() != tt.exp {
+			t.Errorf("%d. %q: unexpected statement:\n  exp=%s\n  got=%s", i, tt.stmt, tt.exp, other)
+		} else if stmt.String() != MustParseSelectStatement(tt.stmt).String() {
+			t.Errorf("%d. %q: original modified: %s", i, tt.stmt, stmt)
+		}
+	}
+
+	// A function that modifies its argument does not affect the original.
+	stmt := MustParseSelectStatement(`SELECT value FROM merge(cpu, mem)`)
+	stmt.RewriteMeasurements(func(m *influxql.Measurement) *influxql.Measurement {
+		m.Name = "x"
+		return m
+	})
+	if s := stmt.String(); s != `SELECT value FROM merge(cpu, mem)` {
+		t.Fatalf("original modified: %s", s)
+	}
+}
+
+// Ensure a GROUP BY wildcard is expanded to the given tag dimensions.
+func TestSelectStatement_RewriteWildcards_Dimensions(t *testing.T) {
+	stmt := MustParseSelectStatement(`SELECT mean(value) FROM cpu GROUP BY *, time(1m)`)
+	if d, ok := stmt.Dimensions[0].Expr.(*influxql.Wildcard); !ok {
+		t.Fatalf("unexpected dimension: %#v", d)
+	}
+
+	other := stmt.RewriteWildcards(nil, influxql.Dimensions{
+		{Expr: &influxql.VarRef{Val: "host"}},
+		{Expr: &influxql.VarRef{Val: "region"}},
+	})
+	if s := other.String(); s != `SELECT mean(value) FROM cpu GROUP BY time(1m), host, region` {
+		t.Fatalf("unexpected statement: %s", s)
+	} else if s := stmt.String(); s != `SELECT mean(value) FROM cpu GROUP BY time(1m), *` {
+		t.Fatalf("original statement modified: %s", s)
+	}
+}
+
+// Ensure GRANT and REVOKE statements with privilege lists can be converted to a string.
+func TestPrivileges_String(t *testing.T) {
+	for i, tt := range []struct {
+		in  string
+		out string
+	}{
+		{in: `GRANT read, write ON db0 TO jdoe`, out: `GRANT READ, WRITE ON db0 TO jdoe`},
+		{in: `GRANT ALL TO jdoe`, out: `GRANT ALL PRIVILEGES TO jdoe`},
+		{in: `REVOKE WRITE, READ ON db0 FROM jdoe`, out: `REVOKE WRITE, READ ON db0 FROM jdoe`},
+	} {
+		stmt, err := influxql.ParseStatement(tt.in)
+		if err != nil {
+			t.Errorf("%d. %s: unexpected error: %s", i, tt.in, err)
+		} else if out := stmt.String(); tt.out != out {
+			t.Errorf("%d. %s: unexpected string: %s", i, tt.in, out)
+		}
+	}
+
+	if p := (influxql.Privileges{influxql.ReadPrivilege, influxql.WritePrivilege}).Max(); p != influxql.WritePrivilege {
+		t.Errorf("unexpected max privilege: %s", p)
+	}
+}
+
+// Ensure a continuous query can be validated.
+func TestCreateContinuousQueryStatement_Validate(t *testing.T) {
+	var tests = []struct {
+		database string
+		source   string
+		every    time.Duration
+		for_     time.Duration
+		err      string
+	}{
+		{database: "db0", source: `SELECT count(value) INTO cpu_count FROM cpu GROUP BY time(1m)`},
+		{database: "db0", source: `SELECT value INTO cpu_copy FROM cpu`},
+		{database: "", source: `SELECT count(value) INTO cpu_count FROM cpu GROUP BY time(1m)`, err: `continuous query requires a database`},
+		{database: "db0", source: `SELECT count(value) FROM cpu GROUP BY time(1m)`, err: `continuous query requires an INTO measurement`},
+		{database: "db0", source: `SELECT count(value) INTO cpu_count FROM cpu GROUP BY time(0s)`, err: `continuous query requires a positive GROUP BY time interval`},
+		{database: "db0", source: `SELECT count(value) INTO cpu_count FROM cpu GROUP BY host`, err: `continuous query requires a positive GROUP BY time interval`},
+		{database: "db0", source: `SELECT count(value) INTO cpu_count FROM cpu GROUP BY time(1m, 2m)`, err: `time dimension expected one argument`},
+		{database: "db0", source: `SELECT count(value) INTO cpu_count FROM cpu GROUP BY time(1m)`, every: time.Minute, for_: time.Hour},
+		{database: "db0", source: `SELECT count(value) INTO cpu_count FROM cpu GROUP BY time(1h)`, for_: 30 * time.Minute, err: `RESAMPLE FOR duration 30m must be at least the GROUP BY interval 1h`},
+		{database: "db0", source: `SELECT count(value) INTO cpu_count FROM cpu GROUP BY time(1m)`, every: -time.Minute, err: `RESAMPLE EVERY duration must be positive`},
+		{database: "db0", source: `SELECT count(value) INTO cpu_count FROM cpu GROUP BY time(1m)`, for_: -time.Minute, err: `RESAMPLE FOR duration must be positive`},
+	}
+
+	for i, tt := range tests {
+		stmt := &influxql.CreateContinuousQueryStatement{
+			Name:          "cq0",
+			Database:      tt.database,
+			Source:        MustParseSelectStatement(tt.source),
+			ResampleEvery: tt.every,
+			ResampleFor:   tt.for_,
+		}
+		if err := stmt.Validate(); errstring(err) != tt.err {
+			t.Errorf("%d. %s: error mismatch:\n  exp=%s\n  got=%s", i, tt.source, tt.err, errstring(err))
+		}
+	}
+}
+
+// Ensure fields that are only a string literal are detected.
+func TestField_IsStringConstant(t *testing.T) {
+	for i, tt := range []struct {
+		s   string
+		exp bool
+	}{
+		{s: `SELECT 'value' FROM cpu`, exp: true},
+		{s: `SELECT ('value') AS v FROM cpu`, exp: true},
+		{s: `SELECT value FROM cpu`},
+		{s: `SELECT "value" FROM cpu`},
+		{s: `SELECT 'a' + value FROM cpu`},
+		{s: `SELECT count('value') FROM cpu`},
+		{s: `SELECT '2000-01-01' FROM cpu`},
+		{s: `SELECT 1 FROM cpu`},
+	} {
+		stmt := MustParseSelectStatement(tt.s)
+		if got := stmt.Fields[0].IsStringConstant(); got != tt.exp {
+			t.Errorf("%d. %q: unexpected result: %v", i, tt.s, got)
+		}
+	}
+}
+
+// Ensure fields resolve to unique column names.
+func TestFields_Names(t *testing.T) {
+	for i, tt := range []struct {
+		s     string
+		names []string
+	}{
+		{s: `SELECT value FROM cpu`, names: []string{"value"}},
+		{s: `SELECT value AS v, host FROM cpu`, names: []string{"v", "host"}},
+		{s: `SELECT mean(value), max(value) FROM cpu`, names: []string{"mean", "max"}},
+		{s: `SELECT mean(value) / max(value) FROM cpu`, names: []string{"mean_max"}},
+		{s: `SELECT (value + 1) * 2 FROM cpu`, names: []string{"value"}},
+		{s: `SELECT mean(value), mean(other), mean(x) FROM cpu`, names: []string{"mean", "mean_1", "mean_2"}},
+		{s: `SELECT mean(value), mean(other), mean(x) AS mean_1 FROM cpu`, names: []string{"mean", "mean_2", "mean_1"}},
+		{s: `SELECT value, value AS value FROM cpu`, names: []string{"value", "value_1"}},
+		{s: `SELECT 1 + 1, value FROM cpu`, names: []string{"col0", "value"}},
+		{s: `SELECT * FROM cpu`, names: []string{"col0"}},
+	} {
+		stmt := MustParseSelectStatement(tt.s)
+		if names := stmt.Fields.Names(); !reflect.DeepEqual(names, tt.names) {
+			t.Errorf("%d. %s: unexpected names: %v", i, tt.s, names)
+		}
+	}
+
+	// Wildcards are named by the fields they expand to.
+	stmt := MustParseSelectStatement(`SELECT * FROM cpu`).RewriteWildcards(influxql.Fields{
+		{Expr: &influxql.VarRef{Val: "value"}},
+		{Expr: &influxql.VarRef{Val: "value"}},
+	}, nil)
+	if names := stmt.Fields.Names(); !reflect.DeepEqual(names, []string{"value", "value_1"}) {
+		t.Errorf("unexpected wildcard names: %v", names)
+	}
+}
+
+// Ensure the SELECT statement can list the field names it references.
+func TestSelectStatement_NamesInSelect(t *testing.T) {
+	for i, tt := range []struct {
+		stmt  string
+		names []string
+	}{
+		{stmt: `SELECT value FROM cpu`, names: []string{"value"}},
+		{stmt: `SELECT mean(value), max(value) / min(other) FROM cpu`, names: []string{"value", "other"}},
+		{stmt: `SELECT host::tag, value::field FROM cpu`, names: []string{"value"}},
+		{stmt: `SELECT "host"::tag FROM cpu WHERE "region"::tag = 'us'`},
+	} {
+		stmt := MustParseSelectStatement(tt.stmt)
+		if names := stmt.NamesInSelect(); !reflect.DeepEqual(tt.names, names) {
+			t.Errorf("%d. %q: unexpected names: %v", i, tt.stmt, names)
+		}
+	}
+}
+
+// Ensure queries hash equal only when their normalized forms are equal.
+func TestQuery_Hash(t *testing.T) {
+	for i, tt := range []struct {
+		a, b  string
+		equal bool
+	}{
+		{a: `SELECT mean(value) FROM cpu`, b: "select  MEAN(value)\n\tfrom cpu", equal: true},
+		{a: `SELECT value FROM cpu WHERE host = 'a'`, b: `SELECT value FROM cpu WHERE host='a';`, equal: true},
+		{a: `SHOW TAG KEYS FROM cpu`, b: `show tag keys from cpu`, equal: true},
+		{a: `CREATE CONTINUOUS QUERY cq ON db BEGIN SELECT COUNT(value) INTO c FROM cpu GROUP BY time(1h) END`, b: `create continuous query cq on db begin select count(value) into c from cpu group by TIME(1h) end`, equal: true},
+		{a: `SELECT value FROM cpu; SELECT value FROM mem`, b: "SELECT value FROM cpu;\nSELECT value FROM mem;", equal: true},
+
+		// Identifiers and literals are case-sensitive.
+		{a: `SELECT value FROM cpu`, b: `SELECT Value FROM cpu`},
+		{a: `SELECT value FROM cpu WHERE host = 'a'`, b: `SELECT value FROM cpu WHERE host = 'A'`},
+		{a: `SELECT value FROM cpu`, b: `SELECT value FROM mem`},
+		{a: `SELECT value FROM cpu LIMIT 1`, b: `SELECT value FROM cpu LIMIT 2`},
+		{a: `SELECT value FROM cpu; SELECT value FROM mem`, b: `SELECT value FROM cpu`},
+		{a: `CREATE USER u WITH PASSWORD 'a'`, b: `CREATE USER u WITH PASSWORD 'b'`},
+		{a: `CREATE USER u WITH PASSWORD 'a'`, b: `CREATE USER u WITH PASSWORD HASH 'a'`},
+	} {
+		a, b := influxql.MustParseQuery(tt.a), influxql.MustParseQuery(tt.b)
+		if equal := a.Hash() == b.Hash(); equal != tt.equal {
+			t.Errorf("%d. %q and %q: unexpected hash equality: %v", i, tt.a, tt.b, equal)
+		}
+	}
+
+	// Hashing must not modify the query.
+	q := influxql.MustParseQuery(`SELECT MEAN(value) FROM cpu`)
+	q.Hash()
+	if s := q.String(); s != `SELECT MEAN(value) FROM cpu` {
+		t.Fatalf("unexpected query: %s", s)
+	}
+}
+
+// Ensure the data type of an expression is inferred from its literals and operators.
+func TestExprDataType(t *testing.T) {
+	for i, tt := range []struct {
+		s   string
+		typ influxql.DataType
+	}{
+		// Literals.
+		{s: `1`, typ: influxql.Integer},
+		{s: `1.5`, typ: influxql.Float},
+		{s: `'a'`, typ: influxql.String},
+		{s: `true`, typ: influxql.Boolean},
+		{s: `10s`, typ: influxql.Duration},
+		{s: `now()`, typ: influxql.Unknown},
+
+		// References are only known from a type hint.
+		{s: `value`, typ: influxql.Unknown},
+		{s: `value::float`, typ: influxql.Float},
+		{s: `value::integer + 1`, typ: influxql.Integer},
+		{s: `host::tag`, typ: influxql.Unknown},
+
+		// Numeric promotion.
+		{s: `1 + 2`, typ: influxql.Integer},
+		{s: `(1 + 2) * 3`, typ: influxql.Integer},
+		{s: `1 + 2.5`, typ: influxql.Float},
+		{s: `4 / 2`, typ: influxql.Float},
+		{s: `value + 1`, typ: influxql.Unknown},
+
+		// Time arithmetic.
+		{s: `'2000-01-01T00:00:00Z' + 1h`, typ: influxql.Time},
+		{s: `'2000-01-02T00:00:00Z' - '2000-01-01T00:00:00Z'`, typ: influxql.Duration},
+		{s: `10s + 1m`, typ: influxql.Duration},
+		{s: `10s * 2`, typ: influxql.Duration},
+		{s: `2 * 10s`, typ: influxql.Duration},
+		{s: `10s - 2`, typ: influxql.Unknown},
+
+		// Comparisons.
+		{s: `'a' = 'b'`, typ: influxql.Boolean},
+		{s: `value > 1 AND host = 'a'`, typ: influxql.Boolean},
+		{s: `host =~ /a/`, typ: influxql.Boolean},
+
+		// Invalid combinations.
+		{s: `'a' + 1`, typ: influxql.Unknown},
+		{s: `true + 1`, typ: influxql.Unknown},
+	} {
+		expr := influxql.MustParseExpr(tt.s)
+		if typ := influxql.ExprDataType(expr); typ != tt.typ {
+			t.Errorf("%d. %s: unexpected type: exp=%q got=%q", i, tt.s, tt.typ, typ)
+		}
+	}
+}
+
 // Ensure the SELECT statement can extract GROUP BY interval.
 func TestSelectStatement_GroupByInterval(t *testing.T) {
 	q := "SELECT sum(value) from foo GROUP BY time(10m)"
@@ -114,6 +670,106 @@ func TestSelectStatement_GroupByInterval(t *testing.T) {
 	}
 }
 
+// Ensure the GROUP BY interval can be extracted from calendar unit durations.
+func TestSelectStatement_GroupByInterval_CalendarUnits(t *testing.T) {
+	for i, tt := range []struct {
+		s   string
+		d   time.Duration
+		err string
+	}{
+		{s: `SELECT mean(value) FROM cpu GROUP BY time(1mo)`, d: 30 * 24 * time.Hour},
+		{s: `SELECT mean(value) FROM cpu GROUP BY time(3mo), host`, d: 90 * 24 * time.Hour},
+		{s: `SELECT mean(value) FROM cpu GROUP BY time(1y), host`, d: 365 * 24 * time.Hour},
+		{s: `SELECT mean(value) FROM cpu GROUP BY time(2w)`, d: 14 * 24 * time.Hour},
+		{s: `SELECT mean(value) FROM cpu GROUP BY time(1mo, 1d)`, err: `time dimension expected one argument`},
+	} {
+		stmt := MustParseSelectStatement(tt.s)
+		if d, err := stmt.GroupByInterval(); errstring(err) != tt.err {
+			t.Errorf("%d. %s: error mismatch: exp=%s got=%s", i, tt.s, tt.err, err)
+		} else if d != tt.d {
+			t.Errorf("%d. %s: interval mismatch: exp=%s got=%s", i, tt.s, tt.d, d)
+		}
+
+		// Ensure the interval matches the normalized dimensions.
+		if d, _, err := stmt.Dimensions.Normalize(); errstring(err) != tt.err {
+			t.Errorf("%d. %s: normalize error mismatch: exp=%s got=%s", i, tt.s, tt.err, err)
+		} else if d != tt.d {
+			t.Errorf("%d. %s: normalized interval mismatch: exp=%s got=%s", i, tt.s, tt.d, d)
+		}
+
+		// Ensure the calendar unit is kept when the statement is written.
+		if s := stmt.String(); s != tt.s {
+			t.Errorf("%d. %s: unexpected string: %s", i, tt.s, s)
+		}
+	}
+}
+
+// Ensure a select statement can determine if its condition bounds time.
+func TestSelectStatement_HasTimeRange(t *testing.T) {
+	for i, tt := range []struct {
+		cond string
+		exp  bool
+	}{
+		{cond: ``, exp: false},
+		{cond: `host = 'a'`, exp: false},
+		{cond: `time > now() - 1h`, exp: true},
+		{cond: `time < '2000-01-01T00:00:00Z'`, exp: true},
+		{cond: `time >= 10s`, exp: true},
+		{cond: `host = 'a' AND time > now() - 1h`, exp: true},
+		{cond: `host = 'a' AND (value > 1 AND (time < now()))`, exp: true},
+		{cond: `host = 'a' OR time > now() - 1h`, exp: false},
+		{cond: `(host = 'a' OR time > now() - 1h) AND value > 1`, exp: false},
+		{cond: `(host = 'a' OR time > now() - 1h) AND time < now()`, exp: true},
+		{cond: `value > 10`, exp: false},
+	} {
+		s := `SELECT value FROM cpu`
+		if tt.cond != "" {
+			s += ` WHERE ` + tt.cond
+		}
+		if v := MustParseSelectStatement(s).HasTimeRange(); v != tt.exp {
+			t.Errorf("%d. %s: unexpected result: %v", i, s, v)
+		}
+	}
+}
+
+// Ensure the complexity of a select statement grows with its cost.
+func TestSelectStatement_Complexity(t *testing.T) {
+	for i, tt := range []struct {
+		stmt string
+		exp  int
+	}{
+		{stmt: `SELECT value FROM cpu WHERE time > now() - 1h`, exp: 2},
+		{stmt: `SELECT mean(value), max(value) FROM cpu WHERE time > now() - 1h GROUP BY time(1m)`, exp: 4},
+		{stmt: `SELECT value FROM merge(cpu, mem) WHERE host =~ /^a/ AND time > now() - 1h`, exp: 9},
+		{stmt: `SELECT mean(value) FROM cpu WHERE time > now() - 1h GROUP BY time(1m), host, region`, exp: 7},
+		{stmt: `SELECT mean(value) FROM cpu WHERE time > now() - 1h GROUP BY *`, exp: 13},
+		{stmt: `SELECT value FROM cpu`, exp: 20},
+		{stmt: `SELECT value FROM cpu WHERE time < now()`, exp: 20},
+		{stmt: `SELECT value FROM cpu WHERE host = 'a' OR time > now() - 1h`, exp: 20},
+		{stmt: `SELECT value FROM cpu WHERE (host = 'a' OR host = 'b') AND time > now() - 1h`, exp: 2},
+		{stmt: `SELECT value FROM /.*/ WHERE time > now() - 1h`, exp: 10},
+	} {
+		if n := MustParseSelectStatement(tt.stmt).Complexity(); n != tt.exp {
+			t.Errorf("%d. %s: unexpected complexity: exp=%d got=%d", i, tt.stmt, tt.exp, n)
+		}
+	}
+
+	// Ensure an unbounded regex query across a join with many groups costs
+	// more than a bounded query of a single measurement.
+	cheap := MustParseSelectStatement(`SELECT mean(value) FROM cpu WHERE host = 'a' AND time > now() - 1h GROUP BY time(1m)`)
+	expensive := MustParseSelectStatement(`SELECT mean(value) FROM join(cpu, mem) WHERE host =~ /.*/ GROUP BY *`)
+	if cheap.Complexity() >= expensive.Complexity() {
+		t.Fatalf("expected cheap query to be less complex: %d >= %d", cheap.Complexity(), expensive.Complexity())
+	}
+
+	// Ensure a regex source costs more than a named measurement.
+	named := MustParseSelectStatement(`SELECT value FROM cpu WHERE time > now() - 1h`)
+	regex := MustParseSelectStatement(`SELECT value FROM /^cpu/ WHERE time > now() - 1h`)
+	if named.Complexity() >= regex.Complexity() {
+		t.Fatalf("expected named source to be less complex: %d >= %d", named.Complexity(), regex.Complexity())
+	}
+}
+
 // Ensure the SELECT statment can have its start and end time set
 func TestSelectStatement_SetTimeRange(t *testing.T) {
 	q := "SELECT sum(value) from foo GROUP BY time(10m)"
@@ -159,9 +815,6 @@ func TestSelectStatement_SetTimeRange(t *testing.T) {
 	s.SetTimeRange(start, end)
 	min, max = influxql.TimeRange(s.Condition)
 
-	// TODO: right now the SetTimeRange can't override the start time if it's more recent than what they're trying to set it to.
-	//       shouldn't matter for our purposes with continuous queries, but fix this later
-
 	if min != start {
 		t.Fatalf("start time wasn't set properly.\n  exp: %s\n  got: %s", start, min)
 	}
@@ -215,6 +868,59 @@ func TestSelectStatement_SetTimeRange(t *testing.T) {
 	}
 }
 
+// Ensure now() is replaced in time comparisons but not elsewhere.
+func TestSelectStatement_RewriteTimeCondition(t *testing.T) {
+	now := mustParseTime("2000-01-01T12:00:00Z")
+	for i, tt := range []struct {
+		s   string
+		exp string
+	}{
+		{s: `SELECT value FROM cpu`, exp: `SELECT value FROM cpu`},
+		{s: `SELECT value FROM cpu WHERE time < now()`, exp: `SELECT value FROM cpu WHERE time < '2000-01-01T12:00:00Z'`},
+		{s: `SELECT value FROM cpu WHERE time > now() - 30m`, exp: `SELECT value FROM cpu WHERE time > '2000-01-01T11:30:00Z'`},
+		{s: `SELECT value FROM cpu WHERE now() + 1h >= TIME`, exp: `SELECT value FROM cpu WHERE '2000-01-01T13:00:00Z' >= TIME`},
+		{s: `SELECT value FROM cpu WHERE host = 'a' AND (time > now() - 1h OR time < '1999-01-01')`, exp: `SELECT value FROM cpu WHERE host = 'a' AND (time > '2000-01-01T11:00:00Z' OR time < '1999-01-01T00:00:00Z')`},
+		{s: `SELECT value FROM cpu WHERE value > now() AND time > now() - 1h`, exp: `SELECT value FROM cpu WHERE value > now() AND time > '2000-01-01T11:00:00Z'`},
+		{s: `SELECT value FROM cpu WHERE time > now() - value`, exp: `SELECT value FROM cpu WHERE time > now() - value`},
+		{s: `SELECT now() - 1h FROM cpu WHERE time > 10s`, exp: `SELECT now() - 1h FROM cpu WHERE time > 10s`},
+	} {
+		stmt := MustParseSelectStatement(tt.s)
+		stmt.RewriteTimeCondition(now)
+		if s := stmt.String(); s != tt.exp {
+			t.Errorf("%d. %q: unexpected statement:\n  exp=%s\n  got=%s", i, tt.s, tt.exp, s)
+		}
+	}
+}
+
+// Ensure setting a time range replaces existing time conditions and keeps the rest.
+func TestSelectStatement_SetTimeRange_Condition(t *testing.T) {
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	for i, tt := range []struct {
+		stmt string
+		cond string
+	}{
+		{stmt: `SELECT value FROM cpu`, cond: `time >= '2000-01-01T00:00:00Z' AND time < '2000-01-01T01:00:00Z'`},
+		{stmt: `SELECT value FROM cpu WHERE host = 'a'`, cond: `host = 'a' AND time >= '2000-01-01T00:00:00Z' AND time < '2000-01-01T01:00:00Z'`},
+		{stmt: `SELECT value FROM cpu WHERE host = 'a' OR host = 'b'`, cond: `(host = 'a' OR host = 'b') AND time >= '2000-01-01T00:00:00Z' AND time < '2000-01-01T01:00:00Z'`},
+		{stmt: `SELECT value FROM cpu WHERE time > now() - 1h`, cond: `time >= '2000-01-01T00:00:00Z' AND time < '2000-01-01T01:00:00Z'`},
+		{stmt: `SELECT value FROM cpu WHERE time >= '2010-01-01' AND host = 'a' AND '2011-01-01' > TIME`, cond: `host = 'a' AND time >= '2000-01-01T00:00:00Z' AND time < '2000-01-01T01:00:00Z'`},
+		{stmt: `SELECT value FROM cpu WHERE (host = 'a' AND time > 10s)`, cond: `host = 'a' AND time >= '2000-01-01T00:00:00Z' AND time < '2000-01-01T01:00:00Z'`},
+		{stmt: `SELECT value FROM cpu WHERE host = 'a' OR time > now() - 1h`, cond: `host = 'a' AND time >= '2000-01-01T00:00:00Z' AND time < '2000-01-01T01:00:00Z'`},
+		{stmt: `SELECT value FROM cpu WHERE time > 10s AND (host = 'a' OR time < 20s)`, cond: `host = 'a' AND time >= '2000-01-01T00:00:00Z' AND time < '2000-01-01T01:00:00Z'`},
+		{stmt: `SELECT value FROM cpu WHERE (host = 'a' OR time < 20s) OR region = 'b'`, cond: `(host = 'a' OR region = 'b') AND time >= '2000-01-01T00:00:00Z' AND time < '2000-01-01T01:00:00Z'`},
+	} {
+		stmt := MustParseSelectStatement(tt.stmt)
+		if err := stmt.SetTimeRange(start, end); err != nil {
+			t.Errorf("%d. %q: unexpected error: %s", i, tt.stmt, err)
+		} else if stmt.Condition.String() != tt.cond {
+			t.Errorf("%d. %q: unexpected condition:\n  exp=%s\n  got=%s", i, tt.stmt, tt.cond, stmt.Condition)
+		} else if min, max := influxql.TimeRange(stmt.Condition); !min.Equal(start) || !max.Equal(end.Add(-time.Microsecond)) {
+			t.Errorf("%d. %q: unexpected range: %s - %s", i, tt.stmt, min, max)
+		}
+	}
+}
+
 func TestSelectStatement_HasWildcard(t *testing.T) {
 	var tests = []struct {
 		stmt     string
@@ -273,6 +979,18 @@ func TestSelectStatement_HasWildcard(t *testing.T) {
 			stmt:     `SELECT * FROM cpu GROUP BY *`,
 			wildcard: true,
 		},
+
+		// Aggregate over a wildcard
+		{
+			stmt:     `SELECT count(*) FROM cpu`,
+			wildcard: true,
+		},
+
+		// Aggregate over a field
+		{
+			stmt:     `SELECT count(value) FROM cpu`,
+			wildcard: false,
+		},
 	}
 
 	for i, tt := range tests {
@@ -352,7 +1070,7 @@ func TestSelectStatement_RewriteWildcards(t *testing.T) {
 		// GROUP BY wildcard with time
 		{
 			stmt:    `SELECT value FROM cpu GROUP BY *,time(1m)`,
-			rewrite: `SELECT value FROM cpu GROUP BY host, region, time(1m)`,
+			rewrite: `SELECT value FROM cpu GROUP BY time(1m), host, region`,
 		},
 
 		// GROUP BY wildcard with explicit
@@ -372,6 +1090,18 @@ func TestSelectStatement_RewriteWildcards(t *testing.T) {
 			stmt:    `SELECT * FROM cpu GROUP BY *`,
 			rewrite: `SELECT value1, value2 FROM cpu GROUP BY host, region`,
 		},
+
+		// Aggregate over a wildcard
+		{
+			stmt:    `SELECT mean(*) FROM cpu GROUP BY time(1m)`,
+			rewrite: `SELECT mean(value1) AS mean_value1, mean(value2) AS mean_value2 FROM cpu GROUP BY time(1m)`,
+		},
+
+		// Aliased aggregate over a wildcard
+		{
+			stmt:    `SELECT count(*) AS n, max(value1) FROM cpu`,
+			rewrite: `SELECT count(value1) AS n_value1, count(value2) AS n_value2, max(value1) FROM cpu`,
+		},
 	}
 
 	for i, tt := range tests {
@@ -394,6 +1124,30 @@ func TestSelectStatement_RewriteWildcards(t *testing.T) {
 	}
 }
 
+// Ensure numeric aggregates over a wildcard skip fields of other types.
+func TestSelectStatement_RewriteWildcards_Types(t *testing.T) {
+	fields := influxql.Fields{
+		{Expr: &influxql.VarRef{Val: "f", Type: influxql.Float}},
+		{Expr: &influxql.VarRef{Val: "i", Type: influxql.Integer}},
+		{Expr: &influxql.VarRef{Val: "s", Type: influxql.String}},
+		{Expr: &influxql.VarRef{Val: "b", Type: influxql.Boolean}},
+		{Expr: &influxql.VarRef{Val: "host", Type: influxql.Tag}},
+	}
+
+	for i, tt := range []struct {
+		stmt    string
+		rewrite string
+	}{
+		{stmt: `SELECT mean(*) FROM cpu`, rewrite: `SELECT mean(f::float) AS mean_f, mean(i::integer) AS mean_i FROM cpu`},
+		{stmt: `SELECT count(*) FROM cpu`, rewrite: `SELECT count(f::float) AS count_f, count(i::integer) AS count_i, count(s::string) AS count_s, count(b::boolean) AS count_b FROM cpu`},
+	} {
+		rw := MustParseSelectStatement(tt.stmt).RewriteWildcards(fields, nil)
+		if s := rw.String(); s != tt.rewrite {
+			t.Errorf("%d. %q: unexpected rewrite: %s", i, tt.stmt, s)
+		}
+	}
+}
+
 // Ensure the time range of an expression can be extracted.
 func TestTimeRange(t *testing.T) {
 	for i, tt := range []struct {
@@ -423,6 +1177,9 @@ func TestTimeRange(t *testing.T) {
 
 		// Absolute time
 		{expr: `time = 1388534400s`, min: `2014-01-01 00:00:00`, max: `2014-01-01 00:00:00`},
+		{expr: `time = 1388534400000000000`, min: `2014-01-01 00:00:00`, max: `2014-01-01 00:00:00`},
+		{expr: `time >= 1434055562000000000 AND time < 1434055563000ms`, min: `2015-06-11 20:46:02`, max: `2015-06-11 20:46:02.999999`},
+		{expr: `time > 0`, min: `1970-01-01 00:00:00.000001`, max: `0001-01-01 00:00:00`},
 
 		// Non-comparative expressions.
 		{expr: `time`, min: `0001-01-01 00:00:00`, max: `0001-01-01 00:00:00`},
@@ -431,7 +1188,7 @@ func TestTimeRange(t *testing.T) {
 		{expr: `time AND '2000-01-01 00:00:00'`, min: `0001-01-01 00:00:00`, max: `0001-01-01 00:00:00`},
 	} {
 		// Extract time range.
-		expr := MustParseExpr(tt.expr)
+		expr := influxql.MustParseExpr(tt.expr)
 		min, max := influxql.TimeRange(expr)
 
 		// Compare with expected min/max.
@@ -486,9 +1243,159 @@ func TestSelectStatement_OnlyTimeDimensions(t *testing.T) {
 	}
 }
 
+// Ensure a query can be dumped as an indented tree.
+func TestDump(t *testing.T) {
+	q := influxql.MustParseQuery(`SELECT mean(value) AS avg, max(value) * 2 FROM cpu WHERE host = 'serverA' AND (region =~ /us-.*/ OR time > now() - 1h) GROUP BY time(10m), host ORDER BY DESC LIMIT 10 OFFSET 5;
+		SELECT count(value) FROM merge(cpu, mem) WHERE ok = true AND time > '2000-01-01T00:00:00Z';
+		CREATE CONTINUOUS QUERY cq ON db BEGIN SELECT sum(value) INTO cpu_1h FROM cpu GROUP BY time(1h) END;
+		SHOW TAG VALUES FROM cpu WITH KEY = host WHERE region::tag = 'us' LIMIT 5;
+		SELECT value FROM cpu OFFSET 0;
+		SELECT value FROM cpu LIMIT 0, 10;
+		DROP DATABASE foo`)
+
+	path := filepath.Join("testdata", "dump.golden")
+	got := influxql.Dump(q)
+	if *update {
+		if err := ioutil.WriteFile(path, []byte(got), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	exp, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	} else if got != string(exp) {
+		t.Fatalf("unexpected dump:\n\nexp:\n%s\ngot:\n%s", exp, got)
+	}
+}
+
+// Ensure a query can be formatted across multiple lines and parsed back.
+func TestFormat(t *testing.T) {
+	q := influxql.MustParseQuery(`SELECT mean(value) AS avg, max(value) * 2, "f.g" INTO db.rp.out FROM merge(cpu, mem) WHERE host = 'serverA' AND (region =~ /us-.*/ OR time > now() - 1h) AND value > 10 GROUP BY time(10m), host ORDER BY DESC LIMIT 10 OFFSET 5;
+		SELECT * FROM cpu;
+		CREATE CONTINUOUS QUERY cq ON db RESAMPLE EVERY 1m FOR 2h BEGIN SELECT sum(value), count(value) INTO cpu_1h FROM cpu WHERE host = 'a' AND region = 'b' GROUP BY time(1h), * END;
+		DROP DATABASE foo`)
+
+	path := filepath.Join("testdata", "format.golden")
+	got := influxql.Format(q, influxql.FormatOptions{})
+	if *update {
+		if err := ioutil.WriteFile(path, []byte(got), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	exp, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	} else if got != string(exp) {
+		t.Fatalf("unexpected format:\n\nexp:\n%s\ngot:\n%s", exp, got)
+	}
+
+	// Verify the formatted query parses to the same statements.
+	if other := influxql.MustParseQuery(got); other.String() != q.String() {
+		t.Fatalf("unexpected re-parse:\n\nexp=%s\n\ngot=%s", q, other)
+	}
+}
+
+// Ensure the indentation of a formatted continuous query can be changed.
+func TestFormat_Indent(t *testing.T) {
+	stmt, err := influxql.ParseStatement(`CREATE CONTINUOUS QUERY cq ON db BEGIN SELECT count(value) INTO c FROM cpu GROUP BY time(1h) END`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := "CREATE CONTINUOUS QUERY cq ON db\nBEGIN\n\tSELECT count(value)\n\tINTO c\n\tFROM cpu\n\tGROUP BY time(1h)\nEND"
+	if got := influxql.Format(stmt, influxql.FormatOptions{Indent: "\t"}); got != exp {
+		t.Fatalf("unexpected format:\n\nexp:\n%s\n\ngot:\n%s", exp, got)
+	}
+
+	// Nodes other than statements are written on a single line.
+	if got := influxql.Format(influxql.MustParseExpr(`a AND (b OR c)`), influxql.FormatOptions{}); got != `a AND (b OR c)` {
+		t.Fatalf("unexpected format: %s", got)
+	}
+}
+
+// Ensure every statement can be deep copied.
+func TestCloneStatement(t *testing.T) {
+	for i, s := range []string{
+		`SELECT mean(value) AS m, max(value) * 2 INTO db.rp.out FROM cpu WHERE host =~ /^a/ AND time > now() - 1h GROUP BY time(10m), host ORDER BY DESC LIMIT 10 OFFSET 5`,
+		`SELECT value FROM join(cpu, mem) WHERE value = true`,
+		`DELETE FROM cpu WHERE host = 'a'`,
+		`DROP SERIES FROM merge(cpu, mem) WHERE host = 'a'`,
+		`SHOW SERIES FROM cpu WHERE host = 'a' ORDER BY ASC LIMIT 1 OFFSET 2`,
+		`SHOW SERIES FROM /cpu.*/ WHERE host =~ /web/`,
+		`SHOW MEASUREMENTS WHERE host = 'a' LIMIT 1`,
+		`SHOW TAG KEYS FROM cpu WHERE host = 'a' LIMIT 1`,
+		`SHOW TAG VALUES FROM cpu WITH KEY IN (host, region) WHERE host = 'a'`,
+		`SHOW FIELD KEYS FROM cpu LIMIT 1`,
+		`SHOW TAG KEY CARDINALITY ON db FROM cpu WHERE host = 'a'`,
+		`SHOW TAG VALUES CARDINALITY ON db FROM cpu WITH KEY IN (host, region) WHERE host = 'a'`,
+		`SHOW FIELD KEY CARDINALITY FROM cpu`,
+		`CREATE USER u WITH PASSWORD 'p' WITH ALL PRIVILEGES`,
+		`CREATE USER u WITH PASSWORD HASH 'p'`,
+		`GRANT READ, WRITE ON db TO u`,
+		`REVOKE ALL PRIVILEGES FROM u`,
+		`ALTER RETENTION POLICY rp ON db DURATION 1h REPLICATION 2 DEFAULT`,
+		`ALTER RETENTION POLICY rp ON db RENAME TO rp2`,
+		`CREATE RETENTION POLICY rp ON db DURATION 1h REPLICATION 2 DEFAULT`,
+		`CREATE DATABASE db`,
+		`DROP DATABASE db`,
+		`DROP RETENTION POLICY rp ON db`,
+		`DROP USER u`,
+		`DROP MEASUREMENT cpu`,
+		`DROP CONTINUOUS QUERY cq`,
+		`SHOW CONTINUOUS QUERIES`,
+		`SHOW DATABASES`,
+		`SHOW RETENTION POLICIES ON db`,
+		`SHOW USERS`,
+	} {
+		stmt, err := influxql.ParseStatement(s)
+		if err != nil {
+			t.Fatalf("%d. %q: unexpected error: %s", i, s, err)
+		}
+
+		other := influxql.CloneStatement(stmt)
+		if !reflect.DeepEqual(stmt, other) {
+			t.Errorf("%d. %q: clone mismatch:\n\nexp=%s\n\ngot=%s", i, s, influxql.Dump(stmt), influxql.Dump(other))
+		}
+	}
+
+	// Ensure a continuous query copies its source statement.
+	stmt, err := influxql.ParseStatement(`CREATE CONTINUOUS QUERY cq ON db RESAMPLE EVERY 1m BEGIN SELECT count(value) INTO c FROM cpu GROUP BY time(1h) END`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := influxql.CloneStatement(stmt).(*influxql.CreateContinuousQueryStatement)
+	if other.String() != stmt.String() {
+		t.Fatalf("unexpected clone: %s", other)
+	} else if other.Source == stmt.(*influxql.CreateContinuousQueryStatement).Source {
+		t.Fatal("expected source to be copied")
+	}
+}
+
+// Ensure modifying a cloned statement does not affect the original.
+func TestCloneStatement_Modify(t *testing.T) {
+	s := `SELECT mean(value) FROM cpu WHERE host = 'a' GROUP BY time(1m), host ORDER BY DESC`
+	stmt := MustParseSelectStatement(s)
+	other := influxql.CloneStatement(stmt).(*influxql.SelectStatement)
+
+	other.Condition = influxql.MustParseExpr(`host = 'b'`)
+	other.Fields[0].Expr.(*influxql.Call).Args[0].(*influxql.VarRef).Val = "other"
+	other.Fields = append(other.Fields, &influxql.Field{Expr: &influxql.VarRef{Val: "x"}})
+	other.Dimensions[1].Expr.(*influxql.VarRef).Val = "region"
+	other.SortFields[0].Ascending = true
+	other.Source.(*influxql.Measurement).Name = "mem"
+
+	if stmt.String() != s {
+		t.Fatalf("original modified: %s", stmt)
+	} else if exp := `SELECT mean(other), x FROM mem WHERE host = 'b' GROUP BY time(1m), region ORDER BY ASC`; other.String() != exp {
+		t.Fatalf("unexpected clone: %s", other)
+	}
+}
+
 // Ensure an AST node can be rewritten.
 func TestRewrite(t *testing.T) {
-	expr := MustParseExpr(`time > 1 OR foo = 2`)
+	expr := influxql.MustParseExpr(`time > 1 OR foo = 2`)
 
 	// Flip LHS & RHS in all binary expressions.
 	act := influxql.RewriteFunc(expr, func(n influxql.Node) influxql.Node {
@@ -506,6 +1413,218 @@ func TestRewrite(t *testing.T) {
 	}
 }
 
+// Ensure a regex literal is written between slashes and can be parsed back.
+func TestRegexLiteral_String(t *testing.T) {
+	for i, tt := range []struct {
+		in  string
+		out string
+	}{
+		{in: `host =~ /a\/b/`, out: `host =~ /a\/b/`},
+		{in: `host =~ /a\\/`, out: `host =~ /a\\/`},
+		{in: `host =~ /(?i)web/`, out: `host =~ /(?i)web/`},
+		{in: `host =~ 'us.*'`, out: `host =~ /us.*/`},
+		{in: `host =~ //`, out: `host =~ //`},
+		{in: `host !~ ''`, out: `host !~ //`},
+	} {
+		expr := influxql.MustParseExpr(tt.in)
+		if out := expr.String(); tt.out != out {
+			t.Errorf("%d. %s: unexpected string: %s", i, tt.in, out)
+		} else if other := influxql.MustParseExpr(out); !reflect.DeepEqual(expr, other) {
+			t.Errorf("%d. %s: round trip mismatch: %s", i, tt.in, other)
+		}
+	}
+
+	// Verify an escaped slash in a string pattern isn't escaped twice.
+	expr := influxql.MustParseExpr(`host =~ 'a\\/b'`)
+	if out := expr.String(); out != `host =~ /a\/b/` {
+		t.Fatalf("unexpected string: %s", out)
+	} else if other := influxql.MustParseExpr(out); other.String() != out {
+		t.Fatalf("round trip mismatch: %s", other)
+	} else if !other.(*influxql.BinaryExpr).RHS.(*influxql.RegexLiteral).Val.MatchString("a/b") {
+		t.Fatal("expected round trip to match a/b")
+	}
+
+	// Verify inline flags are honored.
+	re := influxql.MustParseExpr(`host =~ /(?i)web/`).(*influxql.BinaryExpr).RHS.(*influxql.RegexLiteral)
+	if !re.Val.MatchString("WEB01") {
+		t.Fatal("expected case-insensitive match")
+	}
+}
+
+// Ensure regexes that match every value are detected.
+func TestRegexLiteral_MatchesAll(t *testing.T) {
+	for i, tt := range []struct {
+		s   string
+		exp bool
+	}{
+		{s: `host =~ //`, exp: true},
+		{s: `host =~ /.*/`, exp: true},
+		{s: `host =~ /(?s).*/`, exp: true},
+		{s: `host =~ /a*/`, exp: true},
+		{s: `host =~ /.+/`, exp: false},
+		{s: `host =~ /web/`, exp: false},
+		{s: `host =~ /^$/`, exp: false},
+		{s: `host =~ /^.*$/`, exp: false},
+		{s: `host =~ /\b/`, exp: false},
+	} {
+		re := influxql.MustParseExpr(tt.s).(*influxql.BinaryExpr).RHS.(*influxql.RegexLiteral)
+		if got := re.MatchesAll(); got != tt.exp {
+			t.Errorf("%d. %s: unexpected result: exp=%v got=%v", i, tt.s, tt.exp, got)
+		}
+	}
+}
+
+// Ensure a LIKE expression can be converted to a string and parsed back.
+func TestBinaryExpr_String_Like(t *testing.T) {
+	for i, tt := range []struct {
+		in  string
+		out string
+	}{
+		{in: `host LIKE 'web%'`, out: `host LIKE 'web%'`},
+		{in: `host not like 'web\\_%'`, out: `host NOT LIKE 'web\\_%'`},
+		{in: `host LIKE 'a%' AND region NOT LIKE 'us%'`, out: `host LIKE 'a%' AND region NOT LIKE 'us%'`},
+	} {
+		expr := influxql.MustParseExpr(tt.in)
+		if out := expr.String(); tt.out != out {
+			t.Errorf("%d. %s: unexpected string: %s", i, tt.in, out)
+		} else if other := influxql.MustParseExpr(out); !reflect.DeepEqual(expr, other) {
+			t.Errorf("%d. %s: round trip mismatch: %s", i, tt.in, other)
+		}
+	}
+}
+
+// Ensure arithmetic on aggregates parses into calls and round-trips with its precedence.
+func TestSelectStatement_String_AggregateArithmetic(t *testing.T) {
+	for i, tt := range []struct {
+		s   string
+		out string
+	}{
+		{s: `SELECT mean(value) * 100 / max(value) FROM cpu GROUP BY time(1m)`, out: `SELECT mean(value) * 100.000 / max(value) FROM cpu GROUP BY time(1m)`},
+		{s: `SELECT mean(value) * (100 / max(value)) FROM cpu`, out: `SELECT mean(value) * (100.000 / max(value)) FROM cpu`},
+		{s: `SELECT (mean(value) + 1) * 2 FROM cpu`, out: `SELECT (mean(value) + 1.000) * 2.000 FROM cpu`},
+		{s: `SELECT sum(value) - count(value) + min(value) FROM cpu`, out: `SELECT sum(value) - count(value) + min(value) FROM cpu`},
+		{s: `SELECT max(value) - (min(value) - 1) AS spread FROM cpu`, out: `SELECT max(value) - (min(value) - 1.000) AS spread FROM cpu`},
+	} {
+		stmt := MustParseSelectStatement(tt.s)
+		if s := stmt.String(); s != tt.out {
+			t.Errorf("%d. %s: unexpected string: %s", i, tt.s, s)
+		} else if other := MustParseSelectStatement(s); !reflect.DeepEqual(stmt.Fields, other.Fields) {
+			t.Errorf("%d. %s: round trip mismatch: %s", i, tt.s, other)
+		} else if err := stmt.Validate(); err != nil {
+			t.Errorf("%d. %s: unexpected error: %s", i, tt.s, err)
+		}
+	}
+
+	// The first statement is a tree of binary expressions with calls as leaves.
+	expr := MustParseSelectStatement(`SELECT mean(value) * 100 / max(value) FROM cpu`).Fields[0].Expr
+	exp := &influxql.BinaryExpr{
+		Op: influxql.DIV,
+		LHS: &influxql.BinaryExpr{
+			Op:  influxql.MUL,
+			LHS: &influxql.Call{Name: "mean", Args: []influxql.Expr{&influxql.VarRef{Val: "value"}}},
+			RHS: &influxql.NumberLiteral{Val: 100},
+		},
+		RHS: &influxql.Call{Name: "max", Args: []influxql.Expr{&influxql.VarRef{Val: "value"}}},
+	}
+	if !reflect.DeepEqual(expr, exp) {
+		t.Fatalf("unexpected expr: %s", influxql.Dump(expr))
+	}
+}
+
+// Ensure the SQL-style not-equal operator is parsed as != and formatted canonically.
+func TestBinaryExpr_String_NotEqual(t *testing.T) {
+	expr := influxql.MustParseExpr(`host <> 'a' AND 1 <> 2`)
+	if !reflect.DeepEqual(expr, influxql.MustParseExpr(`host != 'a' AND 1 != 2`)) {
+		t.Fatalf("unexpected expr: %#v", expr)
+	} else if s := expr.String(); s != `host != 'a' AND 1.000 != 2.000` {
+		t.Fatalf("unexpected string: %s", s)
+	}
+
+	stmt := MustParseSelectStatement(`SELECT value FROM cpu WHERE host<>'a'`)
+	if s := stmt.String(); s != `SELECT value FROM cpu WHERE host != 'a'` {
+		t.Fatalf("unexpected statement: %s", s)
+	} else if other := MustParseSelectStatement(s); !reflect.DeepEqual(stmt, other) {
+		t.Fatalf("round trip mismatch: %s", other)
+	}
+}
+
+// Ensure dimensions are written with time() first and round trip.
+func TestDimensions_String(t *testing.T) {
+	for i, tt := range []struct {
+		s   string
+		exp string
+	}{
+		{s: `SELECT mean(value) FROM cpu GROUP BY time(1m)`, exp: `time(1m)`},
+		{s: `SELECT mean(value) FROM cpu GROUP BY host, region`, exp: `host, region`},
+		{s: `SELECT mean(value) FROM cpu GROUP BY host, TIME(1m), region`, exp: `TIME(1m), host, region`},
+		{s: `SELECT mean(value) FROM cpu GROUP BY *, time(1m)`, exp: `time(1m), *`},
+		{s: `SELECT mean(value) FROM cpu GROUP BY "host name", time(1m) AS t`, exp: `time(1m) AS t, "host name"`},
+		{s: `SELECT mean(value) FROM cpu GROUP BY host::tag AS h`, exp: `host::tag AS h`},
+		{s: `SELECT mean(value) FROM cpu GROUP BY "key", "cpu"."host"`, exp: `"key", cpu.host`},
+		{s: `SELECT mean(value) FROM cpu GROUP BY cpu.host, "cpu"."host name"`, exp: `cpu.host, cpu."host name"`},
+	} {
+		stmt := MustParseSelectStatement(tt.s)
+		if s := stmt.Dimensions.String(); s != tt.exp {
+			t.Errorf("%d. %q: unexpected string: %s", i, tt.s, s)
+		} else if other := MustParseSelectStatement(stmt.String()); other.String() != stmt.String() {
+			t.Errorf("%d. %q: round trip mismatch: %s", i, tt.s, other)
+		} else if !reflect.DeepEqual(dimensionSegments(other.Dimensions), dimensionSegments(stmt.Dimensions)) {
+			t.Errorf("%d. %q: segments mismatch: %q", i, tt.s, dimensionSegments(other.Dimensions))
+		}
+	}
+
+	// Tag keys that are not bare, non-keyword identifiers are quoted.
+	dims := influxql.Dimensions{
+		{Expr: &influxql.VarRef{Val: "host name"}},
+		{Expr: &influxql.VarRef{Val: `a"b`, Type: influxql.Tag}},
+		{Expr: &influxql.VarRef{Val: "key"}},
+		{Expr: &influxql.VarRef{Val: "LIMIT"}},
+		{Expr: &influxql.VarRef{Val: "hash"}},
+		{Expr: &influxql.VarRef{Val: "1host"}},
+		{Expr: &influxql.VarRef{Val: "host_1"}},
+		{Expr: &influxql.Wildcard{}},
+	}
+	if s := dims.String(); s != `"host name", "a\"b"::tag, "key", "LIMIT", hash, "1host", host_1, *` {
+		t.Fatalf("unexpected string: %s", s)
+	}
+
+	// Each quoted tag key parses back as a single segment.
+	stmt := MustParseSelectStatement(`SELECT mean(value) FROM cpu GROUP BY ` + dims.String())
+	if segments := dimensionSegments(stmt.Dimensions); !reflect.DeepEqual(segments, [][]string{{"host name"}, {`a"b`}, {"key"}, {"LIMIT"}, {"hash"}, {"1host"}, {"host_1"}}) {
+		t.Fatalf("unexpected segments: %q", segments)
+	}
+}
+
+// dimensionSegments returns the segments of each reference dimension.
+func dimensionSegments(dims influxql.Dimensions) [][]string {
+	var a [][]string
+	for _, dim := range dims {
+		if ref, ok := dim.Expr.(*influxql.VarRef); ok {
+			a = append(a, ref.Segments())
+		}
+	}
+	return a
+}
+
+// Ensure both spellings of a merge are written with merge() and round trip.
+func TestMerge_String(t *testing.T) {
+	for i, tt := range []struct {
+		s   string
+		exp string
+	}{
+		{s: `SELECT value FROM merge(cpu, mem) GROUP BY time(1m)`, exp: `SELECT value FROM merge(cpu, mem) GROUP BY time(1m)`},
+		{s: `SELECT value FROM (cpu, mem) GROUP BY time(1m)`, exp: `SELECT value FROM merge(cpu, mem) GROUP BY time(1m)`},
+		{s: `SELECT value FROM ( cpu )`, exp: `SELECT value FROM merge(cpu)`},
+	} {
+		stmt := MustParseSelectStatement(tt.s)
+		if s := stmt.String(); s != tt.exp {
+			t.Errorf("%d. %q: unexpected string: %s", i, tt.s, s)
+		} else if other := MustParseSelectStatement(s); !reflect.DeepEqual(stmt, other) {
+			t.Errorf("%d. %q: round trip mismatch: %s", i, tt.s, other)
+		}
+	}
+}
+
 // Ensure an expression can be reduced.
 func TestEval(t *testing.T) {
 	for i, tt := range []struct {
@@ -538,9 +1657,15 @@ func TestEval(t *testing.T) {
 		{in: `foo = 'bar'`, out: true, data: map[string]interface{}{"foo": "bar"}},
 		{in: `foo = 'bar'`, out: nil, data: map[string]interface{}{"foo": nil}},
 		{in: `foo <> 'bar'`, out: true, data: map[string]interface{}{"foo": "xxx"}},
+		{in: `foo =~ /^web/`, out: true, data: map[string]interface{}{"foo": "web01"}},
+		{in: `foo !~ /^web/`, out: true, data: map[string]interface{}{"foo": "db01"}},
+		{in: `foo =~ /^web/`, out: nil, data: map[string]interface{}{"foo": 1.0}},
+		{in: `foo LIKE 'web%'`, out: true, data: map[string]interface{}{"foo": "web01"}},
+		{in: `foo LIKE 'web%'`, out: false, data: map[string]interface{}{"foo": "db01"}},
+		{in: `foo NOT LIKE 'web_1'`, out: false, data: map[string]interface{}{"foo": "web01"}},
 	} {
-		// Evaluate expression.
-		out := influxql.Eval(MustParseExpr(tt.in), tt.data)
+		// Evaluate the reduced expression, as the planner does.
+		out := influxql.Eval(influxql.Reduce(influxql.MustParseExpr(tt.in), nil), tt.data)
 
 		// Compare with expected output.
 		if !reflect.DeepEqual(tt.out, out) {
@@ -571,10 +1696,13 @@ func TestReduce(t *testing.T) {
 		{in: `4 < 6`, out: `true`},
 		{in: `4 <= 4`, out: `true`},
 		{in: `4 AND 5`, out: `4.000 AND 5.000`},
+		{in: `1.5 * 2 + 0.25`, out: `3.250`},
 
 		// Boolean literals.
 		{in: `true AND false`, out: `false`},
 		{in: `true OR false`, out: `true`},
+		{in: `true AND foo`, out: `foo`},
+		{in: `foo OR false`, out: `foo`},
 		{in: `true OR (foo = bar AND 1 > 2)`, out: `true`},
 		{in: `(foo = bar AND 1 > 2) OR true`, out: `true`},
 		{in: `false OR (foo = bar AND 1 > 2)`, out: `false`},
@@ -584,9 +1712,9 @@ func TestReduce(t *testing.T) {
 		{in: `true + false`, out: `true + false`},
 
 		// Time literals.
-		{in: `now() + 2h`, out: `"2000-01-01 02:00:00"`, data: map[string]interface{}{"now()": now}},
-		{in: `now() / 2h`, out: `"2000-01-01 00:00:00" / 2h`, data: map[string]interface{}{"now()": now}},
-		{in: `4µ + now()`, out: `"2000-01-01 00:00:00.000004"`, data: map[string]interface{}{"now()": now}},
+		{in: `now() + 2h`, out: `'2000-01-01T02:00:00Z'`, data: map[string]interface{}{"now()": now}},
+		{in: `now() / 2h`, out: `'2000-01-01T00:00:00Z' / 2h`, data: map[string]interface{}{"now()": now}},
+		{in: `4µ + now()`, out: `'2000-01-01T00:00:00.000004Z'`, data: map[string]interface{}{"now()": now}},
 		{in: `now() = now()`, out: `true`, data: map[string]interface{}{"now()": now}},
 		{in: `now() <> now()`, out: `false`, data: map[string]interface{}{"now()": now}},
 		{in: `now() < now() + 1h`, out: `true`, data: map[string]interface{}{"now()": now}},
@@ -594,7 +1722,7 @@ func TestReduce(t *testing.T) {
 		{in: `now() >= now() - 1h`, out: `true`, data: map[string]interface{}{"now()": now}},
 		{in: `now() > now() - 1h`, out: `true`, data: map[string]interface{}{"now()": now}},
 		{in: `now() - (now() - 60s)`, out: `1m`, data: map[string]interface{}{"now()": now}},
-		{in: `now() AND now()`, out: `"2000-01-01 00:00:00" AND "2000-01-01 00:00:00"`, data: map[string]interface{}{"now()": now}},
+		{in: `now() AND now()`, out: `'2000-01-01T00:00:00Z' AND '2000-01-01T00:00:00Z'`, data: map[string]interface{}{"now()": now}},
 		{in: `now()`, out: `now()`},
 
 		// Duration literals.
@@ -609,9 +1737,21 @@ func TestReduce(t *testing.T) {
 		{in: `60s AND 1m`, out: `1m AND 1m`},
 		{in: `60m / 0`, out: `0s`},
 		{in: `60m + 50`, out: `1h + 50.000`},
+		{in: `10m * 1.5`, out: `15m`},
+		{in: `2 * 1h`, out: `2h`},
+		{in: `1h / 2`, out: `30m`},
+		{in: `1h / 0.5`, out: `2h`},
+		{in: `15000w + 15000w`, out: `15000w + 15000w`},
+		{in: `15000w - -15000w`, out: `15000w - -15000w`},
+		{in: `15000w * 2`, out: `15000w * 2.000`},
+		{in: `3 * 15000w`, out: `3.000 * 15000w`},
 
 		// String literals.
 		{in: `'foo' + 'bar'`, out: `'foobar'`},
+		{in: `'web01' LIKE 'web%'`, out: `true`},
+		{in: `'web01' NOT LIKE 'web%'`, out: `false`},
+		{in: `host LIKE 'web%'`, out: `host =~ /(?s)^web.*$/`},
+		{in: `host NOT LIKE 'web\\_1'`, out: `host !~ /(?s)^web_1$/`},
 
 		// Variable references.
 		{in: `foo`, out: `'bar'`, data: map[string]interface{}{"foo": "bar"}},
@@ -620,13 +1760,226 @@ func TestReduce(t *testing.T) {
 		{in: `foo <> 'bar'`, out: `false`, data: map[string]interface{}{"foo": nil}},
 	} {
 		// Fold expression.
-		expr := influxql.Reduce(MustParseExpr(tt.in), tt.data)
+		in := influxql.MustParseExpr(tt.in)
+		s := in.String()
+		expr := influxql.Reduce(in, tt.data)
 
 		// Compare with expected output.
 		if out := expr.String(); tt.out != out {
 			t.Errorf("%d. %s: unexpected expr:\n\nexp=%s\n\ngot=%s\n\n", i, tt.in, tt.out, out)
 			continue
 		}
+
+		// Verify the original expression was not modified.
+		if in.String() != s {
+			t.Errorf("%d. %s: input modified: %s", i, tt.in, in.String())
+		}
+	}
+}
+
+// Ensure now() can be folded using a fixed clock.
+func TestReduce_NowValuer(t *testing.T) {
+	v := &influxql.NowValuer{Now: mustParseTime("2000-01-01T00:00:00Z")}
+	if out := influxql.Reduce(influxql.MustParseExpr(`time > now() - 1h`), v).String(); out != `time > '1999-12-31T23:00:00Z'` {
+		t.Fatalf("unexpected expr: %s", out)
+	}
+}
+
+// Ensure numeric arithmetic that overflows is not folded.
+func TestReduce_NumberOverflow(t *testing.T) {
+	n := strings.Repeat("9", 300)
+	expr := influxql.Reduce(influxql.MustParseExpr(n+" * "+n), nil)
+	if expr, ok := expr.(*influxql.BinaryExpr); !ok || expr.Op != influxql.MUL {
+		t.Fatalf("unexpected expr: %#v", expr)
+	}
+}
+
+// Ensure redundant parentheses can be removed from an expression.
+func TestSimplify(t *testing.T) {
+	for i, tt := range []struct {
+		in  string
+		out string
+	}{
+		{in: `a`, out: `a`},
+		{in: `((a))`, out: `a`},
+		{in: `(a AND b) AND c`, out: `a AND b AND c`},
+		{in: `a AND (b AND c)`, out: `a AND (b AND c)`},
+		{in: `(a AND b) OR c`, out: `a AND b OR c`},
+		{in: `a OR (b AND c)`, out: `a OR b AND c`},
+		{in: `(a OR b) AND c`, out: `(a OR b) AND c`},
+		{in: `a AND (b OR c)`, out: `a AND (b OR c)`},
+		{in: `(a = 1) AND (b = 2)`, out: `a = 1.000 AND b = 2.000`},
+		{in: `(a * 2) + (b / 3)`, out: `a * 2.000 + b / 3.000`},
+		{in: `(a + b) * c`, out: `(a + b) * c`},
+		{in: `a - (b - c)`, out: `a - (b - c)`},
+		{in: `(a - b) - c`, out: `a - b - c`},
+		{in: `a - (-1)`, out: `a - -1.000`},
+		{in: `(((a + b))) * ((c))`, out: `(a + b) * c`},
+		{in: `mean((value)) * (2)`, out: `mean(value) * 2.000`},
+		{in: `percentile((value), (1 + 2))`, out: `percentile(value, 1.000 + 2.000)`},
+		{in: `(host = 'a' OR (host = 'b')) AND (time > now() - 1h)`, out: `(host = 'a' OR host = 'b') AND time > now() - 1h`},
+	} {
+		in := influxql.MustParseExpr(tt.in)
+		orig := in.String()
+		expr := influxql.Simplify(in)
+
+		// Verify the simplified tree matches the parsed output.
+		if out := influxql.MustParseExpr(tt.out); !reflect.DeepEqual(expr, out) {
+			t.Errorf("%d. %s: unexpected expr:\n\nexp=%s\n\ngot=%s\n\n", i, tt.in, out, expr)
+		}
+
+		// Verify the simplified expression re-parses to the same tree.
+		if other := influxql.MustParseExpr(expr.String()); !reflect.DeepEqual(expr, other) {
+			t.Errorf("%d. %s: re-parse mismatch: %s", i, tt.in, expr)
+		}
+
+		// Verify the original expression was not modified.
+		if in.String() != orig {
+			t.Errorf("%d. %s: original modified: %s", i, tt.in, in)
+		}
+	}
+}
+
+// Ensure AND and OR chains are flattened without crossing other operators.
+func TestConjuncts_Disjuncts(t *testing.T) {
+	for i, tt := range []struct {
+		expr      string
+		conjuncts []string
+		disjuncts []string
+	}{
+		{expr: `a AND (b OR c) AND d`, conjuncts: []string{`a`, `b OR c`, `d`}, disjuncts: []string{`a AND (b OR c) AND d`}},
+		{expr: `a OR b AND c OR d`, conjuncts: []string{`a OR b AND c OR d`}, disjuncts: []string{`a`, `b AND c`, `d`}},
+		{expr: `a AND (b AND (c AND d))`, conjuncts: []string{`a`, `b`, `c`, `d`}, disjuncts: []string{`a AND (b AND (c AND d))`}},
+		{expr: `((a OR b))`, conjuncts: []string{`a OR b`}, disjuncts: []string{`a`, `b`}},
+		{expr: `a = 1 + 2`, conjuncts: []string{`a = 1.000 + 2.000`}, disjuncts: []string{`a = 1.000 + 2.000`}},
+	} {
+		expr := influxql.MustParseExpr(tt.expr)
+		if a := exprStrings(influxql.Conjuncts(expr)); !reflect.DeepEqual(a, tt.conjuncts) {
+			t.Errorf("%d. %q: unexpected conjuncts: %q", i, tt.expr, a)
+		}
+		if a := exprStrings(influxql.Disjuncts(expr)); !reflect.DeepEqual(a, tt.disjuncts) {
+			t.Errorf("%d. %q: unexpected disjuncts: %q", i, tt.expr, a)
+		}
+	}
+
+	if a := influxql.Conjuncts(nil); a != nil {
+		t.Fatalf("unexpected conjuncts: %v", a)
+	}
+}
+
+// exprStrings returns the string representation of each expression.
+func exprStrings(a []influxql.Expr) []string {
+	var other []string
+	for _, expr := range a {
+		other = append(other, expr.String())
+	}
+	return other
+}
+
+// Ensure time predicates are found through AND and parentheses but not OR.
+func TestTimePredicates(t *testing.T) {
+	for i, tt := range []struct {
+		expr string
+		exp  []string
+	}{
+		{expr: `host = 'a'`},
+		{expr: `time > now() - 1h`, exp: []string{`time > now() - 1h`}},
+		{expr: `host = 'a' AND time > 10s AND value > 1 AND time < 20s`, exp: []string{`time > 10s`, `time < 20s`}},
+		{expr: `(host = 'a' AND (TIME >= 10s AND (value > 1 AND 20s > time)))`, exp: []string{`TIME >= 10s`, `20s > time`}},
+		{expr: `time > 10s AND (host = 'a' OR time < 20s)`, exp: []string{`time > 10s`, `host = 'a' OR time < 20s`}},
+		{expr: `(time > 10s OR time < 5s) AND (host = 'a' OR host = 'b')`, exp: []string{`time > 10s OR time < 5s`}},
+		{expr: `host = 'a' OR time > 10s`, exp: []string{`host = 'a' OR time > 10s`}},
+	} {
+		var a []string
+		for _, expr := range influxql.TimePredicates(influxql.MustParseExpr(tt.expr)) {
+			a = append(a, expr.String())
+		}
+		if !reflect.DeepEqual(a, tt.exp) {
+			t.Errorf("%d. %q: unexpected predicates: %q", i, tt.expr, a)
+		}
+	}
+
+	// The predicates are the nodes of the expression so they can be rewritten.
+	expr := influxql.MustParseExpr(`host = 'a' AND time > 10s`)
+	if a := influxql.TimePredicates(expr); len(a) != 1 || a[0] != expr.(*influxql.BinaryExpr).RHS {
+		t.Fatalf("unexpected predicates: %v", a)
+	}
+}
+
+// Ensure a condition can be split into tag and field predicates.
+func TestSplitByTags(t *testing.T) {
+	tagKeys := map[string]bool{"host": true, "region": true}
+
+	for i, tt := range []struct {
+		cond  string
+		tag   string
+		field string
+	}{
+		{cond: `host = 'a'`, tag: `host = 'a'`},
+		{cond: `value > 1`, field: `value > 1.000`},
+		{cond: `host = 'a' AND value > 1`, tag: `host = 'a'`, field: `value > 1.000`},
+		{cond: `value > 1 AND host = 'a' AND time > now() - 1h AND region =~ /us/`, tag: `host = 'a' AND region =~ /us/`, field: `value > 1.000 AND time > now() - 1h`},
+		{cond: `(host = 'a' AND (value > 1 AND region = 'b'))`, tag: `host = 'a' AND region = 'b'`, field: `value > 1.000`},
+		{cond: `(host = 'a' OR host = 'b') AND value > 1`, tag: `host = 'a' OR host = 'b'`, field: `value > 1.000`},
+		{cond: `(host = 'a' OR value > 1) AND region = 'b'`, tag: `region = 'b'`, field: `host = 'a' OR value > 1.000`},
+		{cond: `host = 'a' OR value > 1`, field: `host = 'a' OR value > 1.000`},
+		{cond: `(host = 'a' OR host = 'b') AND (region = 'c' OR region = 'd')`, tag: `(host = 'a' OR host = 'b') AND (region = 'c' OR region = 'd')`},
+		{cond: `host::tag = 'a' AND host::field = 'b' AND dc::tag = 'c'`, tag: `host::tag = 'a' AND dc::tag = 'c'`, field: `host::field = 'b'`},
+		{cond: `host = region`, tag: `host = region`},
+		{cond: `host = value`, field: `host = value`},
+		{cond: `true AND host = 'a'`, tag: `host = 'a'`, field: `true`},
+	} {
+		cond := influxql.MustParseExpr(tt.cond)
+		tag, field := influxql.SplitByTags(cond, tagKeys)
+		if s := exprString(tag); s != tt.tag {
+			t.Errorf("%d. %s: unexpected tag expr: %s", i, tt.cond, s)
+		}
+		if s := exprString(field); s != tt.field {
+			t.Errorf("%d. %s: unexpected field expr: %s", i, tt.cond, s)
+		}
+	}
+
+	// Ensure a nil condition has no predicates.
+	if tag, field := influxql.SplitByTags(nil, tagKeys); tag != nil || field != nil {
+		t.Errorf("unexpected split of nil condition: %v, %v", tag, field)
+	}
+}
+
+// exprString returns the string representation of expr or blank if nil.
+func exprString(expr influxql.Expr) string {
+	if expr == nil {
+		return ""
+	}
+	return expr.String()
+}
+
+// Ensure constant boolean conditions can be detected.
+func TestIsBooleanLiteral(t *testing.T) {
+	for i, tt := range []struct {
+		expr string
+		ok   bool
+		val  bool
+	}{
+		{expr: `true`, ok: true, val: true},
+		{expr: `false`, ok: true, val: false},
+		{expr: `1 = 1`, ok: true, val: true},
+		{expr: `1 = 2`, ok: true, val: false},
+		{expr: `(1 < 2) AND ('a' = 'a')`, ok: true, val: true},
+		{expr: `false AND host = 'a'`, ok: true, val: false},
+		{expr: `true OR value > 1`, ok: true, val: true},
+		{expr: `true AND host = 'a'`, ok: false},
+		{expr: `host = 'a'`, ok: false},
+		{expr: `1 + 2`, ok: false},
+		{expr: `time > now() - 1h`, ok: false},
+	} {
+		ok, val := influxql.IsBooleanLiteral(influxql.MustParseExpr(tt.expr))
+		if ok != tt.ok || val != tt.val {
+			t.Errorf("%d. %s: unexpected result: ok=%v, val=%v", i, tt.expr, ok, val)
+		}
+	}
+
+	if ok, _ := influxql.IsBooleanLiteral(nil); ok {
+		t.Errorf("unexpected literal for nil expression")
 	}
 }
 
@@ -71,10 +71,15 @@ func NewPlanner(db DB) *Planner {
 func (p *Planner) Plan(stmt *SelectStatement) (*Executor, error) {
 	now := p.Now().UTC()
 
+	// Statements without a source only contain constant expressions.
+	if stmt.Source == nil {
+		return p.planConstant(stmt, now)
+	}
+
 	// Clone the statement to be planned.
 	// Replace instances of "now()" with the current time.
 	stmt = stmt.Clone()
-	stmt.Condition = Reduce(stmt.Condition, &nowValuer{Now: now})
+	stmt.Condition = Reduce(stmt.Condition, &NowValuer{Now: now})
 
 	// Begin an unopened transaction.
 	tx, err := p.DB.Begin()
@@ -142,6 +147,35 @@ func (p *Planner) planExpr(e *Executor, expr Expr) (Processor, error) {
 	panic("unreachable")
 }
 
+// planConstant evaluates the fields of a statement without a source, such as
+// "SELECT now()", and returns an executor that emits them as a single row.
+func (p *Planner) planConstant(stmt *SelectStatement, now time.Time) (*Executor, error) {
+	values := []interface{}{time.Unix(0, 0).UTC()}
+	for _, f := range stmt.Fields {
+		switch lit := Reduce(f.Expr, &NowValuer{Now: now}).(type) {
+		case *NumberLiteral:
+			values = append(values, lit.Val)
+		case *StringLiteral:
+			values = append(values, lit.Val)
+		case *BooleanLiteral:
+			values = append(values, lit.Val)
+		case *TimeLiteral:
+			values = append(values, lit.Val.UTC())
+		case *DurationLiteral:
+			values = append(values, lit.String())
+		default:
+			return nil, fmt.Errorf("unable to evaluate field without a FROM clause: %s", f)
+		}
+	}
+
+	e := newExecutor(nil, stmt)
+	e.row = &Row{
+		Columns: append([]string{"time"}, stmt.Fields.Names()...),
+		Values:  [][]interface{}{values},
+	}
+	return e, nil
+}
+
 // planCall generates a processor for a function call.
 func (p *Planner) planRawQuery(e *Executor, v *VarRef) (Processor, error) {
 	stmt := e.stmt
@@ -249,13 +283,13 @@ func (p *Planner) planBinaryExpr(e *Executor, expr *BinaryExpr) (Processor, erro
 	// Create processor for LHS.
 	lhs, err := p.planExpr(e, expr.LHS)
 	if err != nil {
-		return nil, fmt.Errorf("lhs: %s", err)
+		return nil, fmt.Errorf("lhs: %w", err)
 	}
 
 	// Create processor for RHS.
 	rhs, err := p.planExpr(e, expr.RHS)
 	if err != nil {
-		return nil, fmt.Errorf("rhs: %s", err)
+		return nil, fmt.Errorf("rhs: %w", err)
 	}
 
 	// Combine processors.
@@ -270,6 +304,7 @@ type Executor struct {
 	processors []Processor      // per-field processors
 	interval   time.Duration    // group by interval
 	tags       []string         // dimensional tag keys
+	row        *Row             // result of a statement without a source
 }
 
 // newExecutor returns an executor associated with a transaction and statement.
@@ -282,6 +317,14 @@ func newExecutor(tx Tx, stmt *SelectStatement) *Executor {
 
 // Execute begins execution of the query and returns a channel to receive rows.
 func (e *Executor) Execute() (<-chan *Row, error) {
+	// A statement without a source has already been evaluated.
+	if e.row != nil {
+		out := make(chan *Row, 1)
+		out <- e.row
+		close(out)
+		return out, nil
+	}
+
 	// Open transaction.
 	if err := e.tx.Open(); err != nil {
 		return nil, err
@@ -382,15 +425,7 @@ func (e *Executor) createRowValuesIfNotExists(rows map[string]*Row, name string,
 		}
 
 		// Create column names.
-		row.Columns = make([]string, 1, len(e.stmt.Fields)+1)
-		row.Columns[0] = "time"
-		for i, f := range e.stmt.Fields {
-			name := f.Name()
-			if name == "" {
-				name = fmt.Sprintf("col%d", i)
-			}
-			row.Columns = append(row.Columns, name)
-		}
+		row.Columns = append([]string{"time"}, e.stmt.Fields.Names()...)
 
 		// Save to lookup.
 		rows[tagset] = row
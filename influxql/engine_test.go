@@ -634,6 +634,17 @@ func TestPlanner_Plan_GroupByIntervalAndTag(t *testing.T) {
 	}
 }
 
+// Ensure the planner evaluates a statement without a source.
+func TestPlanner_Plan_NoSource(t *testing.T) {
+	rs := MustPlanAndExecute(NewDB(NewTx()), `2000-01-01T12:00:00Z`,
+		`SELECT now(), 2 * 3 AS n, 'a' + 'b' AS s, true AS b`)
+
+	exp := minify(`[{"columns":["time","now","n","s","b"],"values":[["1970-01-01T00:00:00Z","2000-01-01T12:00:00Z",6,"ab",true]]}]`)
+	if act := minify(jsonify(rs)); exp != act {
+		t.Fatalf("unexpected resultset: %s", act)
+	}
+}
+
 // Ensure the planner sends the correct simplified statements to the iterator creator.
 func TestPlanner_CreateIterators(t *testing.T) {
 	var flag0, flag1 bool
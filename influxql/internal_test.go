@@ -0,0 +1,30 @@
+package influxql
+
+// This file is run within the "influxql" package and allows for internal unit tests.
+
+import (
+	"testing"
+)
+
+// Ensure a function registered as a predicate function accepts conditions.
+func TestSelectStatement_Validate_PredicateFunctions(t *testing.T) {
+	predicateFunctions["test_if"] = true
+	defer delete(predicateFunctions, "test_if")
+
+	for i, tt := range []struct {
+		stmt string
+		err  string
+	}{
+		{stmt: `SELECT test_if(value, host = 'a') FROM cpu`},
+		{stmt: `SELECT TEST_IF(value, (value > 1 AND host = 'a')) FROM cpu GROUP BY time(1m)`},
+		{stmt: `SELECT sum(value, host = 'a') FROM cpu`, err: `invalid function sum(value, host = 'a'): condition host = 'a' not allowed as an argument`},
+	} {
+		var errstr string
+		if err := MustParseStatement(tt.stmt).(*SelectStatement).Validate(); err != nil {
+			errstr = err.Error()
+		}
+		if errstr != tt.err {
+			t.Errorf("%d. %s: error mismatch:\n  exp=%s\n  got=%s", i, tt.stmt, tt.err, errstr)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package influxql
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Lint warning codes. A code can be passed to Lint to suppress its warnings.
+const (
+	// LintGroupByTimeWithoutAggregate flags a GROUP BY time() interval on a
+	// query that has no aggregate function to apply to each interval.
+	LintGroupByTimeWithoutAggregate = "group-by-time-without-aggregate"
+
+	// LintUnboundedTimeRange flags a query whose condition does not bound
+	// time so every point in the source is read.
+	LintUnboundedTimeRange = "unbounded-time-range"
+
+	// LintTimeEquality flags "time = <literal>" which only matches points
+	// written at exactly that instant.
+	LintTimeEquality = "time-equality"
+
+	// LintUncommonInterval flags a GROUP BY time() interval that does not
+	// evenly divide an hour or a day so buckets drift across those windows.
+	LintUncommonInterval = "uncommon-interval"
+)
+
+// LintWarning represents a likely mistake in a statement.
+// The AST does not record source positions so a warning refers to the node
+// it applies to instead.
+type LintWarning struct {
+	Code    string
+	Message string
+	Node    Node
+}
+
+// String returns a string representation of the warning.
+func (w LintWarning) String() string {
+	return fmt.Sprintf("%s: %s: %s", w.Code, w.Message, w.Node)
+}
+
+// Lint returns warnings for statements that are valid but likely mistakes.
+// Warnings with a code in disabled are not returned.
+func Lint(stmt Statement, disabled ...string) []LintWarning {
+	var s *SelectStatement
+	var cq bool
+	switch stmt := stmt.(type) {
+	case *SelectStatement:
+		s = stmt
+	case *CreateContinuousQueryStatement:
+		s, cq = stmt.Source, true
+	default:
+		return nil
+	}
+
+	var a []LintWarning
+	warn := func(code, msg string, n Node) {
+		for _, c := range disabled {
+			if c == code {
+				return
+			}
+		}
+		a = append(a, LintWarning{Code: code, Message: msg, Node: n})
+	}
+
+	for _, dim := range s.Dimensions {
+		if call, ok := dim.Expr.(*Call); !ok || strings.ToLower(call.Name) != "time" {
+			continue
+		}
+
+		// Raw values cannot be combined into time intervals.
+		if !s.Aggregated() {
+			warn(LintGroupByTimeWithoutAggregate, "GROUP BY time() has no aggregate function to apply to each interval", dim)
+		}
+
+		if d, err := s.GroupByInterval(); err == nil && d > 0 && !IsCommonInterval(d) {
+			warn(LintUncommonInterval, "GROUP BY time() interval does not evenly divide an hour or a day", dim)
+		}
+	}
+
+	// Queries without a source don't read any points and continuous queries
+	// set the time range of each run themselves.
+	if s.Source != nil && !cq && !s.HasTimeRange() {
+		warn(LintUnboundedTimeRange, "no time range in WHERE clause so every point is read", s)
+	}
+
+	WalkFunc(s.Condition, func(n Node) {
+		if expr, ok := n.(*BinaryExpr); ok && expr.Op == EQ && isTimeLiteralComparison(expr) {
+			warn(LintTimeEquality, "time equality only matches points at exactly that instant", expr)
+		}
+	})
+
+	return a
+}
+
+// IsCommonInterval returns true if d evenly divides an hour, evenly divides
+// a day, or is a whole number of days. Buckets of other intervals start at a
+// different offset within each hour or day.
+func IsCommonInterval(d time.Duration) bool {
+	switch {
+	case d <= 0:
+		return false
+	case d <= time.Hour:
+		return time.Hour%d == 0
+	case d <= 24*time.Hour:
+		return (24*time.Hour)%d == 0
+	default:
+		return d%(24*time.Hour) == 0
+	}
+}
+
+// isTimeLiteralComparison returns true if one side of expr is the time
+// column and the other side is a literal.
+func isTimeLiteralComparison(expr *BinaryExpr) bool {
+	isTime := func(e Expr) bool {
+		ref, ok := e.(*VarRef)
+		return ok && strings.ToLower(ref.Val) == "time"
+	}
+	isLiteral := func(e Expr) bool {
+		switch e.(type) {
+		case *TimeLiteral, *NumberLiteral, *DurationLiteral, *StringLiteral:
+			return true
+		}
+		return false
+	}
+	return (isTime(expr.LHS) && isLiteral(expr.RHS)) || (isLiteral(expr.LHS) && isTime(expr.RHS))
+}
@@ -0,0 +1,127 @@
+package influxql_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdb/influxdb/influxql"
+)
+
+// Ensure each lint rule reports its warning and only when it applies.
+func TestLint(t *testing.T) {
+	for i, tt := range []struct {
+		s        string
+		warnings []string
+	}{
+		// Well-formed queries.
+		{s: `SELECT mean(value) FROM cpu WHERE time > now() - 1h GROUP BY time(1m)`},
+		{s: `SELECT value FROM cpu WHERE time >= '2000-01-01' AND time < '2000-01-02'`},
+		{s: `SELECT now()`},
+		{s: `SHOW SERIES FROM cpu`},
+
+		// GROUP BY time() without an aggregate.
+		{
+			s:        `SELECT * FROM cpu WHERE time > now() - 1h GROUP BY time(1m)`,
+			warnings: []string{`group-by-time-without-aggregate: GROUP BY time() has no aggregate function to apply to each interval: time(1m)`},
+		},
+		{
+			s:        `SELECT value FROM cpu WHERE time > now() - 1h GROUP BY host, time(1m)`,
+			warnings: []string{`group-by-time-without-aggregate: GROUP BY time() has no aggregate function to apply to each interval: time(1m)`},
+		},
+
+		// Unbounded time range.
+		{
+			s:        `SELECT value FROM cpu`,
+			warnings: []string{`unbounded-time-range: no time range in WHERE clause so every point is read: SELECT value FROM cpu`},
+		},
+		{
+			s:        `SELECT value FROM cpu WHERE host = 'a' OR time > now() - 1h`,
+			warnings: []string{`unbounded-time-range: no time range in WHERE clause so every point is read: SELECT value FROM cpu WHERE host = 'a' OR time > now() - 1h`},
+		},
+
+		// Time equality.
+		{
+			s:        `SELECT value FROM cpu WHERE time = '2000-01-01T00:00:00Z'`,
+			warnings: []string{`time-equality: time equality only matches points at exactly that instant: time = '2000-01-01T00:00:00Z'`},
+		},
+		{
+			s:        `SELECT value FROM cpu WHERE host = 'a' AND 1000 = TIME`,
+			warnings: []string{`time-equality: time equality only matches points at exactly that instant: 1000.000 = TIME`},
+		},
+		{s: `SELECT value FROM cpu WHERE time > '2000-01-01' AND value = 1`},
+
+		// Uncommon GROUP BY intervals.
+		{s: `SELECT mean(value) FROM cpu WHERE time > now() - 1h GROUP BY time(1m)`},
+		{s: `SELECT mean(value) FROM cpu WHERE time > now() - 1d GROUP BY time(1h)`},
+		{
+			s:        `SELECT mean(value) FROM cpu WHERE time > now() - 1h GROUP BY time(7m)`,
+			warnings: []string{`uncommon-interval: GROUP BY time() interval does not evenly divide an hour or a day: time(7m)`},
+		},
+		{
+			s:        `CREATE CONTINUOUS QUERY cq ON db BEGIN SELECT mean(value) INTO cpu_7m FROM cpu GROUP BY time(7m) END`,
+			warnings: []string{`uncommon-interval: GROUP BY time() interval does not evenly divide an hour or a day: time(7m)`},
+		},
+		{s: `CREATE CONTINUOUS QUERY cq ON db BEGIN SELECT mean(value) INTO cpu_1h FROM cpu GROUP BY time(1h) END`},
+
+		// Multiple rules.
+		{
+			s: `SELECT value FROM cpu WHERE time = 10s GROUP BY time(1m)`,
+			warnings: []string{
+				`group-by-time-without-aggregate: GROUP BY time() has no aggregate function to apply to each interval: time(1m)`,
+				`time-equality: time equality only matches points at exactly that instant: time = 10s`,
+			},
+		},
+	} {
+		var a []string
+		for _, w := range influxql.Lint(influxql.MustParseStatement(tt.s)) {
+			a = append(a, w.String())
+		}
+		if !reflect.DeepEqual(a, tt.warnings) {
+			t.Errorf("%d. %q: unexpected warnings:\n  exp=%q\n  got=%q", i, tt.s, tt.warnings, a)
+		}
+	}
+}
+
+// Ensure warnings can be suppressed by code.
+func TestLint_Disabled(t *testing.T) {
+	stmt := influxql.MustParseStatement(`SELECT value FROM cpu WHERE time = 10s GROUP BY time(1m)`)
+	if a := influxql.Lint(stmt, influxql.LintTimeEquality); len(a) != 1 || a[0].Code != influxql.LintGroupByTimeWithoutAggregate {
+		t.Fatalf("unexpected warnings: %v", a)
+	}
+	if a := influxql.Lint(stmt, influxql.LintTimeEquality, influxql.LintGroupByTimeWithoutAggregate); len(a) != 0 {
+		t.Fatalf("unexpected warnings: %v", a)
+	}
+
+	// The warning refers to the offending node.
+	a := influxql.Lint(stmt)
+	if expr, ok := a[1].Node.(*influxql.BinaryExpr); !ok || expr != stmt.(*influxql.SelectStatement).Condition {
+		t.Fatalf("unexpected node: %#v", a[1].Node)
+	}
+}
+
+// Ensure intervals are common only if they line up with hours and days.
+func TestIsCommonInterval(t *testing.T) {
+	for i, tt := range []struct {
+		d   time.Duration
+		exp bool
+	}{
+		{d: 0, exp: false},
+		{d: time.Second, exp: true},
+		{d: time.Minute, exp: true},
+		{d: 7 * time.Minute, exp: false},
+		{d: 15 * time.Minute, exp: true},
+		{d: time.Hour, exp: true},
+		{d: 45 * time.Minute, exp: false},
+		{d: 90 * time.Minute, exp: true},
+		{d: 6 * time.Hour, exp: true},
+		{d: 5 * time.Hour, exp: false},
+		{d: 24 * time.Hour, exp: true},
+		{d: 36 * time.Hour, exp: false},
+		{d: 7 * 24 * time.Hour, exp: true},
+	} {
+		if got := influxql.IsCommonInterval(tt.d); got != tt.exp {
+			t.Errorf("%d. %s: unexpected result: exp=%v got=%v", i, tt.d, tt.exp, got)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package influxql
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -9,6 +10,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -20,52 +22,386 @@ const (
 	DateTimeFormat = "2006-01-02 15:04:05.999999"
 )
 
+// DefaultMaxExprDepth is the default maximum nesting depth of parenthesized
+// expressions and function calls.
+const DefaultMaxExprDepth = 1000
+
+// DefaultMaxRegexLength is the default maximum length of a regex pattern.
+const DefaultMaxRegexLength = 4096
+
 // Parser represents an InfluxQL parser.
 type Parser struct {
-	s *bufScanner
+	s          *bufScanner
+	opts       ParserOptions
+	depth      int  // current expression nesting depth
+	terminated bool // last statement ended with a semicolon
+
+	// Optional tokens that were not found at the furthest position reached.
+	// ParsePartial suggests them along with a ParseError's expected tokens.
+	expected    []string
+	expectedPos Pos
+}
+
+// ParserOptions represents optional behavior of the parser.
+// The zero value parses standard InfluxQL.
+type ParserOptions struct {
+	// OperatorAliases allows "&&" and "||" to be used in place of AND and OR.
+	OperatorAliases bool
+
+	// Location is the time zone used for date and date time literals that
+	// do not specify an offset. Defaults to UTC if nil. RFC3339 literals
+	// always use their own offset.
+	Location *time.Location
+
+	// MaxExprDepth is the maximum nesting depth of parenthesized expressions
+	// and function calls. Defaults to DefaultMaxExprDepth if zero.
+	MaxExprDepth int
+
+	// MaxRegexLength is the maximum length, in bytes, of a regex pattern.
+	// Defaults to DefaultMaxRegexLength if zero.
+	MaxRegexLength int
+
+	// ListLiterals allows bracketed lists of values, such as "[1, 2, 3]",
+	// to be used as function arguments.
+	ListLiterals bool
+
+	// RequireLimitWithOffset rejects a SELECT with an OFFSET but no LIMIT.
+	RequireLimitWithOffset bool
+
+	// RejectStringFields rejects a SELECT field that is only a string
+	// literal since it is usually a single-quoted field name.
+	RejectStringFields bool
+}
+
+// maxExprDepth returns the maximum expression nesting depth.
+func (opts *ParserOptions) maxExprDepth() int {
+	if opts.MaxExprDepth == 0 {
+		return DefaultMaxExprDepth
+	}
+	return opts.MaxExprDepth
+}
+
+// maxRegexLength returns the maximum length of a regex pattern.
+func (opts *ParserOptions) maxRegexLength() int {
+	if opts.MaxRegexLength == 0 {
+		return DefaultMaxRegexLength
+	}
+	return opts.MaxRegexLength
+}
+
+// location returns the time zone for literals without an explicit offset.
+func (opts *ParserOptions) location() *time.Location {
+	if opts.Location == nil {
+		return time.UTC
+	}
+	return opts.Location
 }
 
 // NewParser returns a new instance of Parsr.
 func NewParser(r io.Reader) *Parser {
-	return &Parser{s: newBufScanner(r)}
+	return NewParserWithOptions(r, ParserOptions{})
+}
+
+// NewParserWithOptions returns a new instance of Parser configured with opts.
+func NewParserWithOptions(r io.Reader, opts ParserOptions) *Parser {
+	p := &Parser{s: newBufScanner(r), opts: opts}
+	p.s.s.operatorAliases = opts.OperatorAliases
+	return p
+}
+
+// Reset discards the parser's state and begins parsing from r.
+// The parser's options are retained.
+func (p *Parser) Reset(r io.Reader) {
+	p.s.reset(r)
+	p.depth = 0
+	p.terminated = false
+	p.expected, p.expectedPos = p.expected[:0], Pos{}
+}
+
+// ParserPool is a set of reusable parsers that share the same options.
+// It is safe for concurrent use.
+type ParserPool struct {
+	opts ParserOptions
+	pool sync.Pool
+}
+
+// NewParserPool returns a new pool of parsers configured with opts.
+func NewParserPool(opts ParserOptions) *ParserPool {
+	pp := &ParserPool{opts: opts}
+	pp.pool.New = func() interface{} { return NewParserWithOptions(nil, pp.opts) }
+	return pp
+}
+
+// Get returns a parser from the pool that reads from r.
+func (pp *ParserPool) Get(r io.Reader) *Parser {
+	p := pp.pool.Get().(*Parser)
+	p.Reset(r)
+	return p
+}
+
+// Put returns a parser to the pool. The parser must not be used afterward.
+func (pp *ParserPool) Put(p *Parser) {
+	p.Reset(nil)
+	pp.pool.Put(p)
 }
 
 // ParseQuery parses a query string and returns its AST representation.
 func ParseQuery(s string) (*Query, error) { return NewParser(strings.NewReader(s)).ParseQuery() }
 
+// ParseQueryBytes parses a query from a byte slice like ParseQuery without
+// converting it to a string first. The slice must not be modified until
+// parsing returns.
+func ParseQueryBytes(b []byte) (*Query, error) { return NewParser(bytes.NewReader(b)).ParseQuery() }
+
+// ParseQueryContext parses a query string like ParseQuery but checks ctx
+// before each statement and returns ctx.Err() once the context is done.
+// Use ParserOptions.MaxExprDepth to bound the cost of a single statement.
+func ParseQueryContext(ctx context.Context, s string) (*Query, error) {
+	p := NewParser(strings.NewReader(s))
+
+	var statements Statements
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		stmt, err := p.NextStatement()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		statements = append(statements, stmt)
+	}
+	return &Query{Statements: statements, Terminated: p.Terminated()}, nil
+}
+
+// ParsePartial parses s up to the cursor, a byte offset, for autocompletion.
+// It returns the statements before the cursor that parsed successfully and
+// the error from parsing the rest of the text before the cursor, if any.
+//
+// It also returns the tokens that are valid at the cursor, named as they
+// are in ParseError.Expected with the addition of "duration" and "regex"
+// for those literals. They are the tokens the parser expected at the end of
+// the text. If the cursor is within or at the end of a word then only
+// keywords beginning with that word are suggested, along with the token
+// classes that the word could belong to.
+func ParsePartial(s string, cursor int) (*Query, *ParseError, []string) {
+	if cursor < 0 {
+		cursor = 0
+	} else if cursor > len(s) {
+		cursor = len(s)
+	}
+	q, perr, p := parsePartial(s[:cursor])
+
+	// Separate a partially written word from the text before it and find
+	// the tokens the parser expected where the word begins.
+	start := cursor
+	for start > 0 && isIdentChar(rune(s[start-1])) {
+		start--
+	}
+	wq, werr := q, perr
+	if start < cursor {
+		wq, werr, p = parsePartial(s[:start])
+	}
+
+	var expected []string
+	pos := eofPos(s[:start])
+	if werr != nil && werr.Pos == pos {
+		expected = append(expected, werr.Expected...)
+	}
+	if p.expectedPos == pos {
+		expected = append(expected, p.expected...)
+	}
+	if werr == nil && (len(wq.Statements) == 0 || wq.Terminated) {
+		expected = append(expected, statementKeywords...)
+	}
+	return q, perr, completions(expected, s[start:cursor])
+}
+
+// parsePartial parses as many statements of s as possible. It returns the
+// parser so the tokens it expected at the end of s can be inspected.
+func parsePartial(s string) (*Query, *ParseError, *Parser) {
+	q := &Query{}
+	var perr *ParseError
+	p := NewParser(strings.NewReader(s))
+	for {
+		stmt, err := p.NextStatement()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			if !errors.As(err, &perr) {
+				perr = &ParseError{Message: err.Error(), Err: err}
+			}
+			break
+		}
+		q.Statements = append(q.Statements, stmt)
+		q.Terminated = p.Terminated()
+	}
+	return q, perr, p
+}
+
+// eofPos returns the position of the end of s.
+func eofPos(s string) Pos {
+	scanner := NewScanner(strings.NewReader(s))
+	for {
+		if tok, pos, _ := scanner.Scan(); tok == EOF {
+			return pos
+		}
+	}
+}
+
+// statementKeywords are the keywords that begin a statement.
+var statementKeywords = []string{"ALTER", "CREATE", "DELETE", "DROP", "GRANT", "REVOKE", "SELECT", "SHOW"}
+
+// completionClasses are the token classes suggested by ParsePartial.
+var completionClasses = []string{"identifier", "string", "number", "duration", "bool", "regex"}
+
+// completions returns the token classes and tokens in expected, in a fixed
+// order. If word is not blank then only the tokens that could begin with
+// word are returned.
+func completions(expected []string, word string) []string {
+	m := make(map[string]struct{}, len(expected))
+	for _, tok := range expected {
+		m[tok] = struct{}{}
+	}
+
+	var a []string
+	for _, class := range completionClasses {
+		if _, ok := m[class]; ok && classMatches(class, word) {
+			a = append(a, class)
+		}
+	}
+
+	// Add operators, punctuation, and keywords.
+	upper := strings.ToUpper(word)
+	for tok := operator_beg + 1; tok < keyword_end; tok++ {
+		lit := tokens[tok]
+		if _, ok := m[lit]; ok && lit != "" && strings.HasPrefix(lit, upper) {
+			a = append(a, lit)
+		}
+	}
+	return a
+}
+
+// classMatches returns true if a token of the named class could begin with word.
+func classMatches(class, word string) bool {
+	if word == "" {
+		return true
+	}
+
+	switch class {
+	case "identifier":
+		return isLetter(rune(word[0]))
+	case "number", "duration":
+		return isDigit(rune(word[0]))
+	case "bool":
+		lower := strings.ToLower(word)
+		return strings.HasPrefix("true", lower) || strings.HasPrefix("false", lower)
+	}
+	return false
+}
+
+// ParseStatement parses a single statement string and returns its AST representation.
+// An optional trailing semicolon is allowed but any other trailing tokens are an error.
+func ParseStatement(s string) (Statement, error) {
+	p := NewParser(strings.NewReader(s))
+	stmt, err := p.ParseStatement()
+	if err != nil {
+		return nil, err
+	}
+
+	// Allow a single trailing semicolon and then expect EOF.
+	tok, pos, lit := p.scanIgnoreWhitespace()
+	if tok == SEMICOLON {
+		tok, pos, lit = p.scanIgnoreWhitespace()
+	}
+	if tok != EOF {
+		return nil, newParseError(tokstr(tok, lit), []string{"EOF"}, pos)
+	}
+	return stmt, nil
+}
+
 // ParseExpr parses an expression string and returns its AST representation.
 func ParseExpr(s string) (Expr, error) { return NewParser(strings.NewReader(s)).ParseExpr() }
 
-// ParseQuery parses an InfluxQL string and returns a Query AST object.
-func (p *Parser) ParseQuery() (*Query, error) {
-	// If there's only whitespace then return no statements.
-	if tok, _, _ := p.scanIgnoreWhitespace(); tok == EOF {
-		return &Query{}, nil
+// MustParseQuery parses a query string and returns its AST. Panic on error.
+func MustParseQuery(s string) *Query {
+	q, err := ParseQuery(s)
+	if err != nil {
+		panic(err.Error())
 	}
-	p.unscan()
+	return q
+}
+
+// MustParseStatement parses a single statement string and returns its AST. Panic on error.
+func MustParseStatement(s string) Statement {
+	stmt, err := ParseStatement(s)
+	if err != nil {
+		panic(err.Error())
+	}
+	return stmt
+}
+
+// MustParseExpr parses an expression string and returns its AST. Panic on error.
+func MustParseExpr(s string) Expr {
+	expr, err := ParseExpr(s)
+	if err != nil {
+		panic(err.Error())
+	}
+	return expr
+}
 
-	// Otherwise parse statements until EOF.
+// ParseQuery parses an InfluxQL string and returns a Query AST object.
+func (p *Parser) ParseQuery() (*Query, error) {
 	var statements Statements
 	for {
-
-		// Read the next statement.
-		s, err := p.ParseStatement()
-		if err != nil {
+		s, err := p.NextStatement()
+		if err == io.EOF {
+			break
+		} else if err != nil {
 			return nil, err
 		}
 		statements = append(statements, s)
+	}
+	return &Query{Statements: statements, Terminated: p.Terminated()}, nil
+}
 
-		// Expect a semicolon or EOF after the statement.
-		if tok, pos, lit := p.scanIgnoreWhitespace(); tok != SEMICOLON && tok != EOF {
-			return nil, newParseError(tokstr(tok, lit), []string{";", "EOF"}, pos)
-		} else if tok == EOF {
-			break
-		}
+// NextStatement parses the next statement of a multi-statement query along
+// with its trailing semicolon. Returns io.EOF when no statements remain.
+func (p *Parser) NextStatement() (Statement, error) {
+	// Only whitespace remaining means there are no more statements.
+	if tok, pos, _ := p.scanIgnoreWhitespace(); tok == EOF {
+		return nil, io.EOF
+	} else if tok == SEMICOLON {
+		return nil, &ParseError{Message: "empty statement", Pos: pos}
+	}
+	p.unscan()
+
+	// Read the next statement.
+	s, err := p.ParseStatement()
+	if err != nil {
+		return nil, err
 	}
 
-	return &Query{Statements: statements}, nil
+	// Expect a semicolon or EOF after the statement.
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok == EOF {
+		p.expect(pos, ";")
+		p.unscan()
+		p.terminated = false
+	} else if tok != SEMICOLON {
+		return nil, newParseError(tokstr(tok, lit), []string{";", "EOF"}, pos)
+	} else {
+		p.terminated = true
+	}
+	return s, nil
 }
 
+// Terminated returns true if the last statement returned by NextStatement
+// ended with a semicolon. An interactive shell can use this to decide
+// whether to execute the input or prompt for more.
+func (p *Parser) Terminated() bool { return p.terminated }
+
 // ParseStatement parses an InfluxQL string and returns a Statement AST object.
 func (p *Parser) ParseStatement() (Statement, error) {
 	// Inspect the first token.
@@ -105,8 +441,10 @@ func (p *Parser) parseShowStatement() (Statement, error) {
 		tok, pos, lit := p.scanIgnoreWhitespace()
 		if tok == KEYS {
 			return p.parseShowFieldKeysStatement()
+		} else if tok == KEY {
+			return p.parseShowFieldKeyCardinalityStatement()
 		}
-		return nil, newParseError(tokstr(tok, lit), []string{"KEYS", "VALUES"}, pos)
+		return nil, newParseError(tokstr(tok, lit), []string{"KEYS", "KEY"}, pos)
 	case MEASUREMENTS:
 		return p.parseShowMeasurementsStatement()
 	case RETENTION:
@@ -121,10 +459,16 @@ func (p *Parser) parseShowStatement() (Statement, error) {
 		tok, pos, lit := p.scanIgnoreWhitespace()
 		if tok == KEYS {
 			return p.parseShowTagKeysStatement()
+		} else if tok == KEY {
+			return p.parseShowTagKeyCardinalityStatement()
 		} else if tok == VALUES {
+			if tok, _, _ := p.scanIgnoreWhitespace(); tok == CARDINALITY {
+				return p.parseShowTagValuesCardinalityStatement()
+			}
+			p.unscan()
 			return p.parseShowTagValuesStatement()
 		}
-		return nil, newParseError(tokstr(tok, lit), []string{"KEYS", "VALUES"}, pos)
+		return nil, newParseError(tokstr(tok, lit), []string{"KEYS", "KEY", "VALUES"}, pos)
 	case USERS:
 		return p.parseShowUsersStatement()
 	}
@@ -275,7 +619,7 @@ func (p *Parser) parseAlterRetentionPolicyStatement() (*AlterRetentionPolicyStat
 	stmt.Database = ident
 
 	// Loop through option tokens (DURATION, REPLICATION, DEFAULT, etc.).
-	maxNumOptions := 3
+	maxNumOptions := 4
 Loop:
 	for i := 0; i < maxNumOptions; i++ {
 		tok, pos, lit := p.scanIgnoreWhitespace()
@@ -294,9 +638,18 @@ Loop:
 			stmt.Replication = &n
 		case DEFAULT:
 			stmt.Default = true
+		case RENAME:
+			if tok, pos, lit := p.scanIgnoreWhitespace(); tok != TO {
+				return nil, newParseError(tokstr(tok, lit), []string{"TO"}, pos)
+			}
+			ident, err := p.parseIdent()
+			if err != nil {
+				return nil, err
+			}
+			stmt.NewName = ident
 		default:
 			if i < 1 {
-				return nil, newParseError(tokstr(tok, lit), []string{"DURATION", "RETENTION", "DEFAULT"}, pos)
+				return nil, newParseError(tokstr(tok, lit), []string{"DURATION", "REPLICATION", "DEFAULT", "RENAME"}, pos)
 			}
 			p.unscan()
 			break Loop
@@ -321,7 +674,7 @@ func (p *Parser) parseInt(min, max int) (int, error) {
 	// Convert string to int.
 	n, err := strconv.Atoi(lit)
 	if err != nil {
-		return 0, &ParseError{Message: err.Error(), Pos: pos}
+		return 0, &ParseError{Message: err.Error(), Pos: pos, Err: err}
 	} else if min > n || n > max {
 		return 0, &ParseError{
 			Message: fmt.Sprintf("invalid value %d: must be %d <= n <= %d", n, min, max),
@@ -342,7 +695,7 @@ func (p *Parser) parseUInt32() (uint32, error) {
 	// Convert string to unsigned 32-bit integer
 	n, err := strconv.ParseUint(lit, 10, 32)
 	if err != nil {
-		return 0, &ParseError{Message: err.Error(), Pos: pos}
+		return 0, &ParseError{Message: err.Error(), Pos: pos, Err: err}
 	}
 
 	return uint32(n), nil
@@ -357,7 +710,7 @@ func (p *Parser) parseDuration() (time.Duration, error) {
 	}
 	d, err := ParseDuration(lit)
 	if err != nil {
-		return 0, &ParseError{Message: err.Error(), Pos: pos}
+		return 0, &ParseError{Message: err.Error(), Pos: pos, Err: err}
 	}
 
 	return d, nil
@@ -410,12 +763,12 @@ func (p *Parser) parseString() (string, error) {
 func (p *Parser) parseRevokeStatement() (*RevokeStatement, error) {
 	stmt := &RevokeStatement{}
 
-	// Parse the privilege to be revoked.
-	priv, err := p.parsePrivilege()
+	// Parse the privileges to be revoked.
+	privs, err := p.parsePrivileges()
 	if err != nil {
 		return nil, err
 	}
-	stmt.Privilege = priv
+	stmt.Privileges = privs
 
 	// Parse ON clause.
 	tok, pos, lit := p.scanIgnoreWhitespace()
@@ -428,7 +781,7 @@ func (p *Parser) parseRevokeStatement() (*RevokeStatement, error) {
 		stmt.On = lit
 
 		tok, pos, lit = p.scanIgnoreWhitespace()
-	} else if priv != AllPrivileges {
+	} else if privs[0] != AllPrivileges {
 		// ALL PRIVILEGES is the only privilege allowed cluster-wide.
 		// No ON clause means query is requesting cluster-wide.
 		return nil, newParseError(tokstr(tok, lit), []string{"ON"}, pos)
@@ -454,12 +807,12 @@ func (p *Parser) parseRevokeStatement() (*RevokeStatement, error) {
 func (p *Parser) parseGrantStatement() (*GrantStatement, error) {
 	stmt := &GrantStatement{}
 
-	// Parse the privilege to be granted.
-	priv, err := p.parsePrivilege()
+	// Parse the privileges to be granted.
+	privs, err := p.parsePrivileges()
 	if err != nil {
 		return nil, err
 	}
-	stmt.Privilege = priv
+	stmt.Privileges = privs
 
 	// Parse ON clause.
 	tok, pos, lit := p.scanIgnoreWhitespace()
@@ -472,7 +825,7 @@ func (p *Parser) parseGrantStatement() (*GrantStatement, error) {
 		stmt.On = lit
 
 		tok, pos, lit = p.scanIgnoreWhitespace()
-	} else if priv != AllPrivileges {
+	} else if privs[0] != AllPrivileges {
 		// ALL PRIVILEGES is the only privilege allowed cluster-wide.
 		// No ON clause means query is requesting cluster-wide.
 		return nil, newParseError(tokstr(tok, lit), []string{"ON"}, pos)
@@ -493,6 +846,32 @@ func (p *Parser) parseGrantStatement() (*GrantStatement, error) {
 	return stmt, nil
 }
 
+// parsePrivileges parses a comma-separated list of privileges.
+// ALL PRIVILEGES cannot be combined with any other privilege.
+func (p *Parser) parsePrivileges() (Privileges, error) {
+	var privs Privileges
+	for {
+		_, pos, _ := p.scanIgnoreWhitespace()
+		p.unscan()
+
+		priv, err := p.parsePrivilege()
+		if err != nil {
+			return nil, err
+		} else if priv == AllPrivileges && len(privs) > 0 {
+			return nil, &ParseError{Message: "ALL PRIVILEGES cannot be combined with other privileges", Pos: pos}
+		}
+		privs = append(privs, priv)
+
+		// ALL PRIVILEGES must be the only privilege in the list.
+		if tok, pos, _ := p.scanIgnoreWhitespace(); tok != COMMA {
+			p.unscan()
+			return privs, nil
+		} else if priv == AllPrivileges {
+			return nil, &ParseError{Message: "ALL PRIVILEGES cannot be combined with other privileges", Pos: pos}
+		}
+	}
+}
+
 // parsePrivilege parses a string and returns a Privilege
 func (p *Parser) parsePrivilege() (Privilege, error) {
 	tok, pos, lit := p.scanIgnoreWhitespace()
@@ -509,7 +888,7 @@ func (p *Parser) parsePrivilege() (Privilege, error) {
 		}
 		return AllPrivileges, nil
 	}
-	return 0, newParseError(tokstr(tok, lit), []string{"READ", "WRITE", "ALL [PRIVILEGES]"}, pos)
+	return 0, newParseError(tokstr(tok, lit), []string{"READ", "WRITE", "ALL"}, pos)
 }
 
 // parseSelectStatement parses a select string and returns a Statement AST object.
@@ -529,8 +908,15 @@ func (p *Parser) parseSelectStatement(tr targetRequirement) (*SelectStatement, e
 	}
 
 	// Parse source.
+	// The source can be omitted at the end of the statement if the fields
+	// don't reference any variables, such as "SELECT now()".
 	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != FROM {
-		return nil, newParseError(tokstr(tok, lit), []string{"FROM"}, pos)
+		if (tok != EOF && tok != SEMICOLON) || tr == targetRequired || stmt.Fields.hasVarRefs() {
+			return nil, newParseError(tokstr(tok, lit), []string{"FROM"}, pos)
+		}
+		p.expect(pos, "FROM")
+		p.unscan()
+		return stmt, nil
 	}
 	if stmt.Source, err = p.parseSource(); err != nil {
 		return nil, err
@@ -551,14 +937,22 @@ func (p *Parser) parseSelectStatement(tr targetRequirement) (*SelectStatement, e
 		return nil, err
 	}
 
-	// Parse limit: "LIMIT <n>".
-	if stmt.Limit, err = p.parseOptionalTokenAndInt(LIMIT); err != nil {
+	// Parse limit: "LIMIT <n>" or "LIMIT <offset>, <n>".
+	if err := p.parseLimitClause(stmt); err != nil {
 		return nil, err
 	}
 
 	// Parse offset: "OFFSET <n>".
-	if stmt.Offset, err = p.parseOptionalTokenAndInt(OFFSET); err != nil {
+	_, pos, _ := p.scanIgnoreWhitespace()
+	p.unscan()
+	if offset, hasOffset, err := p.parseOptionalTokenAndInt(OFFSET); err != nil {
 		return nil, err
+	} else if hasOffset && stmt.HasOffset {
+		return nil, &ParseError{Message: "OFFSET cannot be combined with LIMIT <offset>, <count>", Pos: pos}
+	} else if hasOffset && !stmt.HasLimit && p.opts.RequireLimitWithOffset {
+		return nil, &ParseError{Message: "OFFSET requires LIMIT", Pos: pos}
+	} else if hasOffset {
+		stmt.Offset, stmt.HasOffset = offset, true
 	}
 
 	return stmt, nil
@@ -578,6 +972,7 @@ func (p *Parser) parseTarget(tr targetRequirement) (*Target, error) {
 		if tr == targetRequired {
 			return nil, newParseError(tokstr(tok, lit), []string{"INTO"}, pos)
 		}
+		p.expect(pos, "INTO")
 		p.unscan()
 		return nil, nil
 	}
@@ -635,37 +1030,9 @@ func (p *Parser) parseDeleteStatement() (*DeleteStatement, error) {
 // This function assumes the "SHOW SERIES" tokens have already been consumed.
 func (p *Parser) parseShowSeriesStatement() (*ShowSeriesStatement, error) {
 	stmt := &ShowSeriesStatement{}
-	var err error
-
-	// Parse optional FROM.
-	if tok, _, _ := p.scanIgnoreWhitespace(); tok == FROM {
-		if stmt.Source, err = p.parseSource(); err != nil {
-			return nil, err
-		}
-	} else {
-		p.unscan()
-	}
-
-	// Parse condition: "WHERE EXPR".
-	if stmt.Condition, err = p.parseCondition(); err != nil {
-		return nil, err
-	}
-
-	// Parse sort: "ORDER BY FIELD+".
-	if stmt.SortFields, err = p.parseOrderBy(); err != nil {
-		return nil, err
-	}
-
-	// Parse limit: "LIMIT <n>".
-	if stmt.Limit, err = p.parseOptionalTokenAndInt(LIMIT); err != nil {
-		return nil, err
-	}
-
-	// Parse offset: "OFFSET <n>".
-	if stmt.Offset, err = p.parseOptionalTokenAndInt(OFFSET); err != nil {
+	if err := p.parseShowClauses(stmt, showFrom|showWhere); err != nil {
 		return nil, err
 	}
-
 	return stmt, nil
 }
 
@@ -673,28 +1040,9 @@ func (p *Parser) parseShowSeriesStatement() (*ShowSeriesStatement, error) {
 // This function assumes the "SHOW MEASUREMENTS" tokens have already been consumed.
 func (p *Parser) parseShowMeasurementsStatement() (*ShowMeasurementsStatement, error) {
 	stmt := &ShowMeasurementsStatement{}
-	var err error
-
-	// Parse condition: "WHERE EXPR".
-	if stmt.Condition, err = p.parseCondition(); err != nil {
-		return nil, err
-	}
-
-	// Parse sort: "ORDER BY FIELD+".
-	if stmt.SortFields, err = p.parseOrderBy(); err != nil {
-		return nil, err
-	}
-
-	// Parse limit: "LIMIT <n>".
-	if stmt.Limit, err = p.parseOptionalTokenAndInt(LIMIT); err != nil {
-		return nil, err
-	}
-
-	// Parse offset: "OFFSET <n>".
-	if stmt.Offset, err = p.parseOptionalTokenAndInt(OFFSET); err != nil {
+	if err := p.parseShowClauses(stmt, showWhere); err != nil {
 		return nil, err
 	}
-
 	return stmt, nil
 }
 
@@ -703,6 +1051,16 @@ func (p *Parser) parseShowMeasurementsStatement() (*ShowMeasurementsStatement, e
 func (p *Parser) parseShowRetentionPoliciesStatement() (*ShowRetentionPoliciesStatement, error) {
 	stmt := &ShowRetentionPoliciesStatement{}
 
+	// The database is optional and may follow ON or, in the legacy form,
+	// immediately follow POLICIES.
+	tok, _, _ := p.scanIgnoreWhitespace()
+	if tok != ON {
+		p.unscan()
+		if tok != IDENT {
+			return stmt, nil
+		}
+	}
+
 	ident, err := p.parseIdent()
 	if err != nil {
 		return nil, err
@@ -716,80 +1074,109 @@ func (p *Parser) parseShowRetentionPoliciesStatement() (*ShowRetentionPoliciesSt
 // This function assumes the "SHOW TAG KEYS" tokens have already been consumed.
 func (p *Parser) parseShowTagKeysStatement() (*ShowTagKeysStatement, error) {
 	stmt := &ShowTagKeysStatement{}
-	var err error
-
-	// Parse optional source.
-	if tok, _, _ := p.scanIgnoreWhitespace(); tok == FROM {
-		if stmt.Source, err = p.parseSource(); err != nil {
-			return nil, err
-		}
-	} else {
-		p.unscan()
+	if err := p.parseShowClauses(stmt, showFrom|showWhere); err != nil {
+		return nil, err
 	}
+	return stmt, nil
+}
 
-	// Parse condition: "WHERE EXPR".
-	if stmt.Condition, err = p.parseCondition(); err != nil {
+// parseShowTagKeyCardinalityStatement parses a string and returns a ShowTagKeyCardinalityStatement.
+// This function assumes the "SHOW TAG KEY" tokens have already been consumed.
+func (p *Parser) parseShowTagKeyCardinalityStatement() (*ShowTagKeyCardinalityStatement, error) {
+	if err := p.parseTokens([]Token{CARDINALITY}); err != nil {
 		return nil, err
 	}
 
-	// Parse sort: "ORDER BY FIELD+".
-	if stmt.SortFields, err = p.parseOrderBy(); err != nil {
+	stmt := &ShowTagKeyCardinalityStatement{}
+	var err error
+	if stmt.Database, stmt.Source, _, stmt.Condition, err = p.parseCardinalityClauses(false); err != nil {
 		return nil, err
 	}
+	return stmt, nil
+}
 
-	// Parse limit: "LIMIT <n>".
-	if stmt.Limit, err = p.parseOptionalTokenAndInt(LIMIT); err != nil {
+// parseShowFieldKeyCardinalityStatement parses a string and returns a ShowFieldKeyCardinalityStatement.
+// This function assumes the "SHOW FIELD KEY" tokens have already been consumed.
+func (p *Parser) parseShowFieldKeyCardinalityStatement() (*ShowFieldKeyCardinalityStatement, error) {
+	if err := p.parseTokens([]Token{CARDINALITY}); err != nil {
 		return nil, err
 	}
 
-	// Parse offset: "OFFSET <n>".
-	if stmt.Offset, err = p.parseOptionalTokenAndInt(OFFSET); err != nil {
+	stmt := &ShowFieldKeyCardinalityStatement{}
+	var err error
+	if stmt.Database, stmt.Source, _, stmt.Condition, err = p.parseCardinalityClauses(false); err != nil {
 		return nil, err
 	}
-
 	return stmt, nil
 }
 
-// parseShowTagValuesStatement parses a string and returns a ShowSeriesStatement.
-// This function assumes the "SHOW TAG VALUES" tokens have already been consumed.
-func (p *Parser) parseShowTagValuesStatement() (*ShowTagValuesStatement, error) {
-	stmt := &ShowTagValuesStatement{}
-	var err error
-
-	// Parse optional source.
-	if tok, _, _ := p.scanIgnoreWhitespace(); tok == FROM {
-		if stmt.Source, err = p.parseSource(); err != nil {
-			return nil, err
+// parseCardinalityClauses parses the optional ON, FROM and WHERE clauses of a
+// cardinality statement. If withKeys is true then the required WITH KEY clause
+// is parsed between the FROM and WHERE clauses.
+func (p *Parser) parseCardinalityClauses(withKeys bool) (database string, source Source, tagKeys []string, condition Expr, err error) {
+	// Parse optional database: "ON <db>".
+	if tok, _, _ := p.scanIgnoreWhitespace(); tok == ON {
+		if database, err = p.parseIdent(); err != nil {
+			return "", nil, nil, nil, err
 		}
 	} else {
 		p.unscan()
 	}
 
+	// Parse optional source.
+	if source, err = p.parseOptionalSource(); err != nil {
+		return "", nil, nil, nil, err
+	}
+
 	// Parse required WITH KEY.
-	if stmt.TagKeys, err = p.parseTagKeys(); err != nil {
-		return nil, err
+	if withKeys {
+		if tagKeys, err = p.parseTagKeys(); err != nil {
+			return "", nil, nil, nil, err
+		}
 	}
 
 	// Parse condition: "WHERE EXPR".
-	if stmt.Condition, err = p.parseCondition(); err != nil {
-		return nil, err
+	if condition, err = p.parseCondition(); err != nil {
+		return "", nil, nil, nil, err
 	}
 
-	// Parse sort: "ORDER BY FIELD+".
-	if stmt.SortFields, err = p.parseOrderBy(); err != nil {
+	return database, source, tagKeys, condition, nil
+}
+
+// parseShowTagValuesStatement parses a string and returns a ShowSeriesStatement.
+// This function assumes the "SHOW TAG VALUES" tokens have already been consumed.
+func (p *Parser) parseShowTagValuesStatement() (*ShowTagValuesStatement, error) {
+	stmt := &ShowTagValuesStatement{}
+	var c ShowClauses
+	var err error
+
+	// Parse optional source.
+	if c.Source, err = p.parseOptionalSource(); err != nil {
 		return nil, err
 	}
 
-	// Parse limit: "LIMIT <n>".
-	if stmt.Limit, err = p.parseOptionalTokenAndInt(LIMIT); err != nil {
+	// Parse required WITH KEY.
+	if stmt.TagKeys, err = p.parseTagKeys(); err != nil {
 		return nil, err
 	}
 
-	// Parse offset: "OFFSET <n>".
-	if stmt.Offset, err = p.parseOptionalTokenAndInt(OFFSET); err != nil {
+	// Parse the remaining clauses.
+	if err := p.parseShowTrailingClauses(&c, showWhere); err != nil {
 		return nil, err
 	}
+	stmt.setClauses(c)
+
+	return stmt, nil
+}
 
+// parseShowTagValuesCardinalityStatement parses a string and returns a ShowTagValuesCardinalityStatement.
+// This function assumes the "SHOW TAG VALUES CARDINALITY" tokens have already been consumed.
+func (p *Parser) parseShowTagValuesCardinalityStatement() (*ShowTagValuesCardinalityStatement, error) {
+	stmt := &ShowTagValuesCardinalityStatement{}
+	var err error
+	if stmt.Database, stmt.Source, stmt.TagKeys, stmt.Condition, err = p.parseCardinalityClauses(true); err != nil {
+		return nil, err
+	}
 	return stmt, nil
 }
 
@@ -844,33 +1231,80 @@ func (p *Parser) parseShowUsersStatement() (*ShowUsersStatement, error) {
 // This function assumes the "SHOW FIELD KEYS" tokens have already been consumed.
 func (p *Parser) parseShowFieldKeysStatement() (*ShowFieldKeysStatement, error) {
 	stmt := &ShowFieldKeysStatement{}
+	if err := p.parseShowClauses(stmt, showFrom); err != nil {
+		return nil, err
+	}
+	return stmt, nil
+}
+
+// showClause is a set of optional clauses accepted by a SHOW statement in
+// addition to ORDER BY, LIMIT and OFFSET.
+type showClause int
+
+const (
+	showFrom showClause = 1 << iota
+	showWhere
+)
+
+// parseShowClauses parses the clauses of a SHOW statement into stmt.
+func (p *Parser) parseShowClauses(stmt ShowStatement, allowed showClause) error {
+	var c ShowClauses
 	var err error
 
 	// Parse optional source.
-	if tok, _, _ := p.scanIgnoreWhitespace(); tok == FROM {
-		if stmt.Source, err = p.parseSource(); err != nil {
-			return nil, err
+	if allowed&showFrom != 0 {
+		if c.Source, err = p.parseOptionalSource(); err != nil {
+			return err
+		}
+	}
+
+	// Parse the remaining clauses.
+	if err := p.parseShowTrailingClauses(&c, allowed); err != nil {
+		return err
+	}
+	stmt.setClauses(c)
+
+	return nil
+}
+
+// parseShowTrailingClauses parses the optional WHERE, ORDER BY, LIMIT and
+// OFFSET clauses that follow the source of a SHOW statement into c.
+func (p *Parser) parseShowTrailingClauses(c *ShowClauses, allowed showClause) error {
+	var err error
+
+	// Parse condition: "WHERE EXPR".
+	if allowed&showWhere != 0 {
+		if c.Condition, err = p.parseCondition(); err != nil {
+			return err
 		}
-	} else {
-		p.unscan()
 	}
 
 	// Parse sort: "ORDER BY FIELD+".
-	if stmt.SortFields, err = p.parseOrderBy(); err != nil {
-		return nil, err
+	if c.SortFields, err = p.parseOrderBy(); err != nil {
+		return err
 	}
 
 	// Parse limit: "LIMIT <n>".
-	if stmt.Limit, err = p.parseOptionalTokenAndInt(LIMIT); err != nil {
-		return nil, err
+	if c.Limit, _, err = p.parseOptionalTokenAndInt(LIMIT); err != nil {
+		return err
 	}
 
 	// Parse offset: "OFFSET <n>".
-	if stmt.Offset, err = p.parseOptionalTokenAndInt(OFFSET); err != nil {
-		return nil, err
+	if c.Offset, _, err = p.parseOptionalTokenAndInt(OFFSET); err != nil {
+		return err
 	}
 
-	return stmt, nil
+	return nil
+}
+
+// parseOptionalSource parses a source if the next token is FROM.
+// Returns a nil source otherwise.
+func (p *Parser) parseOptionalSource() (Source, error) {
+	if tok, _, _ := p.scanIgnoreWhitespace(); tok != FROM {
+		p.unscan()
+		return nil, nil
+	}
+	return p.parseSource()
 }
 
 // parseDropMeasurementStatement parses a string and returns a DropMeasurementStatement.
@@ -894,13 +1328,9 @@ func (p *Parser) parseDropSeriesStatement() (*DropSeriesStatement, error) {
 	stmt := &DropSeriesStatement{}
 	var err error
 
-	if tok, _, _ := p.scanIgnoreWhitespace(); tok == FROM {
-		// Parse source.
-		if stmt.Source, err = p.parseSource(); err != nil {
-			return nil, err
-		}
-	} else {
-		p.unscan()
+	// Parse optional source.
+	if stmt.Source, err = p.parseOptionalSource(); err != nil {
+		return nil, err
 	}
 
 	// Parse condition: "WHERE EXPR".
@@ -910,6 +1340,17 @@ func (p *Parser) parseDropSeriesStatement() (*DropSeriesStatement, error) {
 
 	// If they didn't provide a FROM or a WHERE, they need to provide the SeriesID
 	if stmt.Condition == nil && stmt.Source == nil {
+		tok, pos, lit := p.scanIgnoreWhitespace()
+		p.unscan()
+		if tok != NUMBER {
+			return nil, &ParseError{
+				Message:  fmt.Sprintf("found %s, expected FROM, WHERE, or series id", tokstr(tok, lit)),
+				Found:    tokstr(tok, lit),
+				Expected: []string{"FROM", "WHERE", "series id"},
+				Pos:      pos,
+			}
+		}
+
 		id, err := p.parseUInt32()
 		if err != nil {
 			return nil, err
@@ -933,7 +1374,8 @@ func (p *Parser) parseShowContinuousQueriesStatement() (*ShowContinuousQueriesSt
 }
 
 // parseShowDatabasesStatement parses a string and returns a ShowDatabasesStatement.
-// This function assumes the "SHOW DATABASE" tokens have already been consumed.
+// This function assumes the "SHOW DATABASES" tokens have already been consumed.
+// The statement takes no arguments so any trailing tokens are reported by the caller.
 func (p *Parser) parseShowDatabasesStatement() (*ShowDatabasesStatement, error) {
 	stmt := &ShowDatabasesStatement{}
 	return stmt, nil
@@ -967,6 +1409,15 @@ func (p *Parser) parseCreateContinuousQueryStatement() (*CreateContinuousQuerySt
 	}
 	stmt.Database = ident
 
+	// Parse optional "RESAMPLE" clause.
+	if tok, _, _ := p.scanIgnoreWhitespace(); tok == RESAMPLE {
+		if stmt.ResampleEvery, stmt.ResampleFor, err = p.parseResample(); err != nil {
+			return nil, err
+		}
+	} else {
+		p.unscan()
+	}
+
 	// Expect a "BEGIN SELECT" tokens.
 	if err := p.parseTokens([]Token{BEGIN, SELECT}); err != nil {
 		return nil, err
@@ -983,9 +1434,8 @@ func (p *Parser) parseCreateContinuousQueryStatement() (*CreateContinuousQuerySt
 	if source.Aggregated() {
 		d, err := source.GroupByInterval()
 		if d == 0 || err != nil {
-			// rewind so we can output an error with some info
-			p.unscan() // unscan the whitespace
-			p.unscan() // unscan the last token
+			// Report the token that ended the source where the interval
+			// was expected.
 			tok, pos, lit := p.scanIgnoreWhitespace()
 			expected := []string{"GROUP BY time(...)"}
 			if err != nil {
@@ -1003,6 +1453,46 @@ func (p *Parser) parseCreateContinuousQueryStatement() (*CreateContinuousQuerySt
 	return stmt, nil
 }
 
+// parseResample parses the EVERY and FOR durations of a RESAMPLE clause.
+// At least one of them must be present and both must be positive.
+// This function assumes the RESAMPLE token has already been consumed.
+func (p *Parser) parseResample() (every, forDur time.Duration, err error) {
+	tok, pos, lit := p.scanIgnoreWhitespace()
+	if tok != EVERY && tok != FOR {
+		return 0, 0, newParseError(tokstr(tok, lit), []string{"EVERY", "FOR"}, pos)
+	}
+
+	if tok == EVERY {
+		if every, err = p.parsePositiveDuration("RESAMPLE EVERY"); err != nil {
+			return 0, 0, err
+		}
+		if tok, _, _ = p.scanIgnoreWhitespace(); tok != FOR {
+			p.unscan()
+			return every, 0, nil
+		}
+	}
+
+	if forDur, err = p.parsePositiveDuration("RESAMPLE FOR"); err != nil {
+		return 0, 0, err
+	}
+	return every, forDur, nil
+}
+
+// parsePositiveDuration parses a duration and returns an error naming the
+// clause if the duration is not greater than zero.
+func (p *Parser) parsePositiveDuration(clause string) (time.Duration, error) {
+	_, pos, _ := p.scanIgnoreWhitespace()
+	p.unscan()
+
+	d, err := p.parseDuration()
+	if err != nil {
+		return 0, err
+	} else if d <= 0 {
+		return 0, &ParseError{Message: clause + " duration must be positive", Pos: pos}
+	}
+	return d, nil
+}
+
 // parseCreateDatabaseStatement parses a string and returns a CreateDatabaseStatement.
 // This function assumes the "CREATE DATABASE" tokens have already been consumed.
 func (p *Parser) parseCreateDatabaseStatement() (*CreateDatabaseStatement, error) {
@@ -1075,6 +1565,14 @@ func (p *Parser) parseCreateUserStatement() (*CreateUserStatement, error) {
 		return nil, err
 	}
 
+	// Check for optional HASH marking the password as pre-hashed. HASH is not
+	// a reserved keyword so it is matched as an identifier.
+	if tok, _, lit := p.scanIgnoreWhitespace(); tok == IDENT && strings.ToUpper(lit) == "HASH" {
+		stmt.Hashed = true
+	} else {
+		p.unscan()
+	}
+
 	// Parse new user's password
 	if ident, err = p.parseString(); err != nil {
 		return nil, err
@@ -1185,7 +1683,8 @@ func (p *Parser) parseFields() (Fields, error) {
 		fields = append(fields, f)
 
 		// If there's not a comma next then stop parsing fields.
-		if tok, _, _ := p.scan(); tok != COMMA {
+		if tok, pos, _ := p.scan(); tok != COMMA {
+			p.expect(pos, ",")
 			p.unscan()
 			break
 		}
@@ -1198,12 +1697,18 @@ func (p *Parser) parseField() (*Field, error) {
 	f := &Field{}
 
 	// Parse the expression first.
+	_, pos, _ := p.scanIgnoreWhitespace()
+	p.unscan()
 	expr, err := p.ParseExpr()
 	if err != nil {
 		return nil, err
 	}
 	f.Expr = expr
 
+	if p.opts.RejectStringFields && f.IsStringConstant() {
+		return nil, &ParseError{Message: fmt.Sprintf("found string %s, expected field (identifiers are double quoted)", expr), Pos: pos}
+	}
+
 	// Parse the alias if the current and next tokens are "WS AS".
 	alias, err := p.parseAlias()
 	if err != nil {
@@ -1220,7 +1725,8 @@ func (p *Parser) parseField() (*Field, error) {
 // parseAlias parses the "AS (IDENT|STRING)" alias for fields and dimensions.
 func (p *Parser) parseAlias() (string, error) {
 	// Check if the next token is "AS". If not, then unscan and exit.
-	if tok, _, _ := p.scanIgnoreWhitespace(); tok != AS {
+	if tok, pos, _ := p.scanIgnoreWhitespace(); tok != AS {
+		p.expect(pos, "AS")
 		p.unscan()
 		return "", nil
 	}
@@ -1235,9 +1741,29 @@ func (p *Parser) parseAlias() (string, error) {
 
 // parseSource parses the "FROM" clause of the query.
 func (p *Parser) parseSource() (Source, error) {
-	// The first token can either be the series name or a join/merge call.
+	// A regex matches measurements by name.
+	if re, err := p.parseRegex(); err != nil {
+		return nil, err
+	} else if re != nil {
+		return &Measurement{Regex: re}, nil
+	}
+
+	// The first token can either be the series name, a join/merge call, or a
+	// parenthesized list of measurements to merge.
 	tok, pos, lit := p.scanIgnoreWhitespace()
-	if tok != IDENT {
+	if tok == LPAREN {
+		if tok, pos, _ := p.scanIgnoreWhitespace(); tok == SELECT {
+			return nil, &ParseError{Message: "subqueries are not supported", Pos: pos}
+		}
+		p.unscan()
+
+		measurements, err := p.parseSourceMeasurements()
+		if err != nil {
+			return nil, err
+		}
+		return &Merge{Measurements: measurements}, nil
+	} else if tok != IDENT {
+		p.expect(pos, "regex", "(")
 		return nil, newParseError(tokstr(tok, lit), []string{"identifier"}, pos)
 	}
 
@@ -1253,13 +1779,28 @@ func (p *Parser) parseSource() (Source, error) {
 		return nil, &ParseError{Message: "unknown merge type: " + sourceType, Pos: pos}
 	}
 
-	// Parse measurement list.
+	measurements, err := p.parseSourceMeasurements()
+	if err != nil {
+		return nil, err
+	}
+
+	// Return the appropriate source type.
+	if sourceType == "join" {
+		return &Join{Measurements: measurements}, nil
+	}
+	return &Merge{Measurements: measurements}, nil
+}
+
+// parseSourceMeasurements parses a comma-separated list of measurement names
+// and the closing right paren. This function assumes the opening paren has
+// been consumed.
+func (p *Parser) parseSourceMeasurements() ([]*Measurement, error) {
 	var measurements []*Measurement
 	for {
 		// Scan the measurement name.
 		tok, pos, lit := p.scanIgnoreWhitespace()
 		if tok != IDENT {
-			return nil, newParseError(tokstr(tok, lit), []string{"measurement name"}, pos)
+			return nil, newParseError(tokstr(tok, lit), []string{"identifier"}, pos)
 		}
 		measurements = append(measurements, &Measurement{Name: lit})
 
@@ -1274,18 +1815,14 @@ func (p *Parser) parseSource() (Source, error) {
 	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != RPAREN {
 		return nil, newParseError(tokstr(tok, lit), []string{")"}, pos)
 	}
-
-	// Return the appropriate source type.
-	if sourceType == "join" {
-		return &Join{Measurements: measurements}, nil
-	}
-	return &Merge{Measurements: measurements}, nil
+	return measurements, nil
 }
 
 // parseCondition parses the "WHERE" clause of the query, if it exists.
 func (p *Parser) parseCondition() (Expr, error) {
 	// Check if the WHERE token exists.
-	if tok, _, _ := p.scanIgnoreWhitespace(); tok != WHERE {
+	if tok, pos, _ := p.scanIgnoreWhitespace(); tok != WHERE {
+		p.expect(pos, "WHERE")
 		p.unscan()
 		return nil, nil
 	}
@@ -1302,7 +1839,8 @@ func (p *Parser) parseCondition() (Expr, error) {
 // parseDimensions parses the "GROUP BY" clause of the query, if it exists.
 func (p *Parser) parseDimensions() (Dimensions, error) {
 	// If the next token is not GROUP then exit.
-	if tok, _, _ := p.scanIgnoreWhitespace(); tok != GROUP {
+	if tok, pos, _ := p.scanIgnoreWhitespace(); tok != GROUP {
+		p.expect(pos, "GROUP")
 		p.unscan()
 		return nil, nil
 	}
@@ -1324,7 +1862,8 @@ func (p *Parser) parseDimensions() (Dimensions, error) {
 		dimensions = append(dimensions, d)
 
 		// If there's not a comma next then stop parsing dimensions.
-		if tok, _, _ := p.scan(); tok != COMMA {
+		if tok, pos, _ := p.scan(); tok != COMMA {
+			p.expect(pos, ",")
 			p.unscan()
 			break
 		}
@@ -1340,37 +1879,103 @@ func (p *Parser) parseDimension() (*Dimension, error) {
 		return nil, err
 	}
 
+	// Parse the optional alias.
+	alias, err := p.parseAlias()
+	if err != nil {
+		return nil, err
+	}
+
 	// Consume all trailing whitespace.
 	p.consumeWhitespace()
 
-	return &Dimension{Expr: expr}, nil
+	return &Dimension{Expr: expr, Alias: alias}, nil
 }
 
 // parseOptionalTokenAndInt parses the specified token followed
-// by an int, if it exists.
-func (p *Parser) parseOptionalTokenAndInt(t Token) (int, error) {
+// by an int, if it exists. The returned bool reports whether the token was found.
+// LIMIT must be greater than zero but an OFFSET of zero is allowed.
+func (p *Parser) parseOptionalTokenAndInt(t Token) (int, bool, error) {
 	// Check if the token exists.
-	if tok, _, _ := p.scanIgnoreWhitespace(); tok != t {
+	if tok, pos, _ := p.scanIgnoreWhitespace(); tok != t {
+		p.expect(pos, tokens[t])
 		p.unscan()
-		return 0, nil
+		return 0, false, nil
 	}
 
 	// Scan the number.
 	tok, pos, lit := p.scanIgnoreWhitespace()
 	if tok != NUMBER {
-		return 0, newParseError(tokstr(tok, lit), []string{"number"}, pos)
+		return 0, false, newParseError(tokstr(tok, lit), []string{"number"}, pos)
+	}
+
+	n, err := parseClauseInt(t, lit, pos)
+	if err != nil {
+		return 0, false, err
+	}
+	return n, true, nil
+}
+
+// parseLimitClause parses "LIMIT <n>" or the "LIMIT <offset>, <n>" form
+// used by MySQL, if it exists, and sets the statement's limit and offset.
+func (p *Parser) parseLimitClause(stmt *SelectStatement) error {
+	if tok, pos, _ := p.scanIgnoreWhitespace(); tok != LIMIT {
+		p.expect(pos, "LIMIT")
+		p.unscan()
+		return nil
+	}
+
+	// Scan the first number.
+	tok, pos, lit := p.scanIgnoreWhitespace()
+	if tok != NUMBER {
+		return newParseError(tokstr(tok, lit), []string{"number"}, pos)
+	}
+
+	// Without a comma the number is the limit.
+	if tok, _, _ := p.scanIgnoreWhitespace(); tok != COMMA {
+		p.unscan()
+		n, err := parseClauseInt(LIMIT, lit, pos)
+		if err != nil {
+			return err
+		}
+		stmt.Limit, stmt.HasLimit = n, true
+		return nil
+	}
+
+	// Otherwise the first number is the offset and the second is the limit.
+	offset, err := parseClauseInt(OFFSET, lit, pos)
+	if err != nil {
+		return err
+	}
+	if tok, pos, lit = p.scanIgnoreWhitespace(); tok != NUMBER {
+		return newParseError(tokstr(tok, lit), []string{"number"}, pos)
+	}
+	limit, err := parseClauseInt(LIMIT, lit, pos)
+	if err != nil {
+		return err
 	}
+	stmt.Limit, stmt.HasLimit = limit, true
+	stmt.Offset, stmt.HasOffset = offset, true
+	return nil
+}
 
+// parseClauseInt parses lit as the int value of the clause specified by t.
+func parseClauseInt(t Token, lit string, pos Pos) (int, error) {
 	// Return an error if the number has a fractional part.
 	if strings.Contains(lit, ".") {
 		msg := fmt.Sprintf("fractional parts not allowed in %s", t.String())
 		return 0, &ParseError{Message: msg, Pos: pos}
+	} else if strings.ContainsAny(lit, "eE") {
+		msg := fmt.Sprintf("exponents not allowed in %s", t.String())
+		return 0, &ParseError{Message: msg, Pos: pos}
 	}
 
 	// Parse number.
 	n, _ := strconv.ParseInt(lit, 10, 64)
 
-	if n < 1 {
+	if t == OFFSET && n < 0 {
+		msg := fmt.Sprintf("%s must be >= 0", t.String())
+		return 0, &ParseError{Message: msg, Pos: pos}
+	} else if t != OFFSET && n < 1 {
 		msg := fmt.Sprintf("%s must be > 0", t.String())
 		return 0, &ParseError{Message: msg, Pos: pos}
 	}
@@ -1381,7 +1986,8 @@ func (p *Parser) parseOptionalTokenAndInt(t Token) (int, error) {
 // parseOrderBy parses the "ORDER BY" clause of a query, if it exists.
 func (p *Parser) parseOrderBy() (SortFields, error) {
 	// Return nil result and nil error if no ORDER token at this position.
-	if tok, _, _ := p.scanIgnoreWhitespace(); tok != ORDER {
+	if tok, pos, _ := p.scanIgnoreWhitespace(); tok != ORDER {
+		p.expect(pos, "ORDER")
 		p.unscan()
 		return nil, nil
 	}
@@ -1446,7 +2052,7 @@ func (p *Parser) parseSortField() (*SortField, error) {
 			return field, nil
 		}
 	} else if tok != ASC && tok != DESC {
-		return nil, newParseError(tokstr(tok, lit), []string{"identifier, ASC, or DESC"}, pos)
+		return nil, newParseError(tokstr(tok, lit), []string{"identifier", "ASC", "DESC"}, pos)
 	}
 
 	field.Ascending = (tok == ASC)
@@ -1463,38 +2069,73 @@ func (p *Parser) ParseExpr() (Expr, error) {
 		return nil, err
 	}
 
+	// Wrap the expression in a placeholder root so the tree can be rewritten
+	// without special casing the top node.
+	root := &BinaryExpr{RHS: expr}
+
 	// Loop over operations and unary exprs and build a tree based on precendence.
 	for {
 		// If the next token is NOT an operator then return the expression.
-		op, _, _ := p.scanIgnoreWhitespace()
-		if !op.isOperator() {
+		op, pos, _ := p.scanIgnoreWhitespace()
+		if op == NOT {
+			// NOT is only used as an operator in "NOT LIKE".
+			if tok, pos, lit := p.scanIgnoreWhitespace(); tok != LIKE {
+				return nil, newParseError(tokstr(tok, lit), []string{"LIKE"}, pos)
+			}
+			op = NLIKE
+		} else if !op.IsOperator() {
+			p.expect(pos, operatorTokens...)
 			p.unscan()
-			return expr, nil
+			return root.RHS, nil
 		}
 
+		// Regex operators can be followed by a regex literal.
 		// Otherwise parse the next unary expression.
-		rhs, err := p.parseUnaryExpr()
-		if err != nil {
-			return nil, err
+		var rhs Expr
+		if IsRegexOp(op) {
+			re, err := p.parseRegex()
+			if err != nil {
+				return nil, err
+			} else if re != nil {
+				rhs = re
+			} else {
+				_, pos, _ := p.scanIgnoreWhitespace()
+				p.expect(pos, "regex")
+				p.unscan()
+			}
+		}
+		if rhs == nil {
+			if rhs, err = p.parseUnaryExpr(); err != nil {
+				return nil, err
+			}
 		}
 
-		// Assign the new root based on the precendence of the LHS and RHS operators.
-		if lhs, ok := expr.(*BinaryExpr); ok && lhs.Op.Precedence() < op.Precedence() {
-			expr = &BinaryExpr{
-				LHS: lhs.LHS,
-				RHS: &BinaryExpr{LHS: lhs.RHS, RHS: rhs, Op: op},
-				Op:  lhs.Op,
+		// Validate LIKE patterns that are known when parsing.
+		if lit, ok := rhs.(*StringLiteral); ok && (op == LIKE || op == NLIKE) {
+			if _, err := LikeRegexp(lit.Val); err != nil {
+				return nil, &ParseError{Message: err.Error(), Pos: pos, Err: err}
 			}
-		} else {
-			expr = &BinaryExpr{LHS: expr, RHS: rhs, Op: op}
 		}
 
-		// If the operator was =~ or !~, parse the regular expression.
-		b, ok := expr.(*BinaryExpr)
-		if ok && (b.Op == EQREGEX || b.Op == NEQREGEX) {
-			if expr, err = p.parseRegexExpr(b); err != nil {
-				return nil, err
+		// Descend the right side of the tree until we find a node whose
+		// operator binds at least as tightly as the new operator. The new
+		// operator takes that node's place and the node becomes its LHS.
+		for node := root; ; {
+			r, ok := node.RHS.(*BinaryExpr)
+			if !ok || r.Op.Precedence() >= op.Precedence() {
+				expr := &BinaryExpr{LHS: node.RHS, RHS: rhs, Op: op}
+
+				// If the operator was =~ or !~, parse the regular expression.
+				if IsRegexOp(op) {
+					if expr, err = p.parseRegexExpr(expr, pos); err != nil {
+						return nil, err
+					}
+				}
+
+				node.RHS = expr
+				break
 			}
+			node = r
 		}
 	}
 }
@@ -1502,7 +2143,12 @@ func (p *Parser) ParseExpr() (Expr, error) {
 // parseUnaryExpr parses an non-binary expression.
 func (p *Parser) parseUnaryExpr() (Expr, error) {
 	// If the first token is a LPAREN then parse it as its own grouped expression.
-	if tok, _, _ := p.scanIgnoreWhitespace(); tok == LPAREN {
+	if tok, pos, _ := p.scanIgnoreWhitespace(); tok == LPAREN {
+		if err := p.enter(pos); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+
 		expr, err := p.ParseExpr()
 		if err != nil {
 			return nil, err
@@ -1519,19 +2165,32 @@ func (p *Parser) parseUnaryExpr() (Expr, error) {
 
 	// Read next token.
 	tok, pos, lit := p.scanIgnoreWhitespace()
+	if tok == LBRACKET && p.opts.ListLiterals {
+		return p.parseListLiteral(pos)
+	}
+
 	switch tok {
 	case IDENT:
 		// If the next immediate token is a left parentheses, parse as function call.
 		// Otherwise parse as a variable reference.
 		if tok0, _, _ := p.scan(); tok0 == LPAREN {
+			if strings.ToLower(lit) == "cast" {
+				return p.parseCast()
+			}
 			return p.parseCall(lit)
 		}
 		p.unscan()
-		return &VarRef{Val: lit}, nil
+		return p.parseVarRef(lit, pos)
 	case STRING:
 		// If literal looks like a date time then parse it as a time literal.
 		if isDateTimeString(lit) {
-			t, err := time.Parse(DateTimeFormat, lit)
+			// A trailing "Z" or "UTC" overrides the configured location.
+			s, loc := lit, p.opts.location()
+			if v, ok := trimUTCSuffix(lit); ok {
+				s, loc = v, time.UTC
+			}
+
+			t, err := time.ParseInLocation(DateTimeFormat, s, loc)
 			if err != nil {
 				// try to parse it as an RFCNano time
 				t, err := time.Parse(time.RFC3339Nano, lit)
@@ -1542,7 +2201,7 @@ func (p *Parser) parseUnaryExpr() (Expr, error) {
 			}
 			return &TimeLiteral{Val: t}, nil
 		} else if isDateString(lit) {
-			t, err := time.Parse(DateFormat, lit)
+			t, err := time.ParseInLocation(DateFormat, lit, p.opts.location())
 			if err != nil {
 				return nil, &ParseError{Message: "unable to parse date", Pos: pos}
 			}
@@ -1550,27 +2209,139 @@ func (p *Parser) parseUnaryExpr() (Expr, error) {
 		}
 		return &StringLiteral{Val: lit}, nil
 	case NUMBER:
+		// The scanner only produces digits so NaN and Inf, which compare
+		// unexpectedly, can only come from a number too large to represent.
 		v, err := strconv.ParseFloat(lit, 64)
-		if err != nil {
+		if err != nil || math.IsInf(v, 0) || math.IsNaN(v) {
 			return nil, &ParseError{Message: "unable to parse number", Pos: pos}
 		}
 		return &NumberLiteral{Val: v}, nil
 	case TRUE, FALSE:
 		return &BooleanLiteral{Val: (tok == TRUE)}, nil
 	case DURATION_VAL:
-		v, _ := ParseDuration(lit)
+		v, err := ParseDuration(lit)
+		if err != nil {
+			return nil, &ParseError{Message: err.Error(), Pos: pos, Err: err}
+		}
 		return &DurationLiteral{Val: v}, nil
 	case MUL:
 		return &Wildcard{}, nil
 	default:
+		p.expect(pos, "duration", "*", "(")
+		if p.opts.ListLiterals {
+			p.expect(pos, "[")
+		}
 		return nil, newParseError(tokstr(tok, lit), []string{"identifier", "string", "number", "bool"}, pos)
 	}
 }
 
+// parseVarRef parses a variable reference with an optional "::type" suffix.
+// The name may be qualified by its measurement, retention policy and database.
+// This function assumes the identifier has already been consumed.
+func (p *Parser) parseVarRef(name string, pos Pos) (*VarRef, error) {
+	ref := &VarRef{Val: name}
+	if n := len(ref.Segments()); n > maxVarRefSegments {
+		return nil, &ParseError{Message: fmt.Sprintf("identifier %s has %d segments, expected at most %d", name, n, maxVarRefSegments), Pos: pos}
+	}
+
+	// If the next immediate token is not a double colon then there is no type.
+	if tok, _, _ := p.scan(); tok != DOUBLECOLON {
+		p.unscan()
+		return ref, nil
+	}
+
+	typ, err := parseVarRefType(p.scan())
+	if err != nil {
+		return nil, err
+	}
+	ref.Type = typ
+	return ref, nil
+}
+
+// parseVarRefType returns the type named by a token.
+// TAG and FIELD are keywords so accept them as well.
+func parseVarRefType(tok Token, pos Pos, lit string) (DataType, error) {
+	switch tok {
+	case IDENT:
+		switch typ := DataType(strings.ToLower(lit)); typ {
+		case Float, Integer, String, Boolean:
+			return typ, nil
+		}
+		return Unknown, &ParseError{Message: "invalid type " + lit, Pos: pos}
+	case TAG:
+		return Tag, nil
+	case FIELD:
+		return AnyField, nil
+	}
+	return Unknown, newParseError(tokstr(tok, lit), []string{"float", "integer", "string", "boolean", "tag", "field"}, pos)
+}
+
+// parseCast parses "CAST(ref AS type)" and returns the reference with the
+// type, the same as "ref::type". This function assumes "CAST(" has been consumed.
+func (p *Parser) parseCast() (*VarRef, error) {
+	tok, pos, lit := p.scanIgnoreWhitespace()
+	if tok != IDENT {
+		return nil, newParseError(tokstr(tok, lit), []string{"identifier"}, pos)
+	}
+	ref, err := p.parseVarRef(lit, pos)
+	if err != nil {
+		return nil, err
+	} else if ref.Type != Unknown {
+		return nil, &ParseError{Message: fmt.Sprintf("cannot cast %s which already has a type", ref), Pos: pos}
+	}
+
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != AS {
+		return nil, newParseError(tokstr(tok, lit), []string{"AS"}, pos)
+	}
+
+	if ref.Type, err = parseVarRefType(p.scanIgnoreWhitespace()); err != nil {
+		return nil, err
+	}
+
+	if tok, pos, lit := p.scanIgnoreWhitespace(); tok != RPAREN {
+		return nil, newParseError(tokstr(tok, lit), []string{")"}, pos)
+	}
+	return ref, nil
+}
+
+// parseRegex parses a regex literal delimited by forward slashes.
+// Returns nil if the next token is not a regex literal.
+func (p *Parser) parseRegex() (*RegexLiteral, error) {
+	// Only scan a regex if the next non-whitespace character is a slash.
+	if p.s.peekRuneIgnoreWhitespace() != '/' {
+		return nil, nil
+	}
+
+	tok, pos, lit := p.s.ScanRegex()
+	if tok == BADREGEX {
+		return nil, &ParseError{Message: "unterminated regex", Pos: pos}
+	}
+
+	re, err := p.compileRegex(lit, pos)
+	if err != nil {
+		return nil, err
+	}
+	return &RegexLiteral{Val: re}, nil
+}
+
+// compileRegex compiles a regex pattern found at pos.
+// Returns an error if the pattern exceeds the maximum allowed length.
+func (p *Parser) compileRegex(pattern string, pos Pos) (*regexp.Regexp, error) {
+	if max := p.opts.maxRegexLength(); len(pattern) > max {
+		return nil, &ParseError{Message: fmt.Sprintf("regex pattern exceeds %d bytes", max), Pos: pos}
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, &ParseError{Message: err.Error(), Pos: pos, Err: err}
+	}
+	return re, nil
+}
+
 // parseRegexExpr parses the string literal on one side of a binary expression
 // and returns a new binary expression with a regex literal in place of the
-// string literal.
-func (p *Parser) parseRegexExpr(expr *BinaryExpr) (*BinaryExpr, error) {
+// string literal. The position of the operator is used for error reporting.
+func (p *Parser) parseRegexExpr(expr *BinaryExpr, pos Pos) (*BinaryExpr, error) {
 	if expr.Op != EQREGEX && expr.Op != NEQREGEX {
 		// Don't call parseRegex unless you have a regex operator!
 		panic("parseRegex only works with =~ or !~ regex operators")
@@ -1578,9 +2349,17 @@ func (p *Parser) parseRegexExpr(expr *BinaryExpr) (*BinaryExpr, error) {
 
 	newExpr := &BinaryExpr{Op: expr.Op}
 
-	if regex, ok := expr.RHS.(*StringLiteral); ok {
+	if regex, ok := expr.RHS.(*RegexLiteral); ok {
+		// Found regex literal on right side of operator.
+		newExpr.RHS = regex
+
+		// Make sure left side of operator is an identifier.
+		if newExpr.LHS, ok = expr.LHS.(*VarRef); !ok {
+			return nil, &ParseError{Message: fmt.Sprintf("left operand of operator %s must be an identifier", expr.Op.String()), Pos: pos}
+		}
+	} else if regex, ok := expr.RHS.(*StringLiteral); ok {
 		// Found regex text on right side of operator.
-		re, err := regexp.Compile(regex.Val)
+		re, err := p.compileRegex(regex.Val, pos)
 		if err != nil {
 			return nil, err
 		}
@@ -1588,11 +2367,11 @@ func (p *Parser) parseRegexExpr(expr *BinaryExpr) (*BinaryExpr, error) {
 
 		// Make sure left side of operator is an identifier.
 		if newExpr.LHS, ok = expr.LHS.(*VarRef); !ok {
-			return nil, fmt.Errorf("left operand of operator %s must be an identifier", expr.Op.String())
+			return nil, &ParseError{Message: fmt.Sprintf("left operand of operator %s must be an identifier", expr.Op.String()), Pos: pos}
 		}
 	} else if regex, ok = expr.LHS.(*StringLiteral); ok {
 		// Found regex text on left side of operator.
-		re, err := regexp.Compile(regex.Val)
+		re, err := p.compileRegex(regex.Val, pos)
 		if err != nil {
 			return nil, err
 		}
@@ -1600,10 +2379,10 @@ func (p *Parser) parseRegexExpr(expr *BinaryExpr) (*BinaryExpr, error) {
 
 		// Make sure right side of operator is an identifer.
 		if newExpr.RHS, ok = expr.RHS.(*VarRef); !ok {
-			return nil, fmt.Errorf("right operand of operator %s must be an identifier", expr.Op.String())
+			return nil, &ParseError{Message: fmt.Sprintf("right operand of operator %s must be an identifier", expr.Op.String()), Pos: pos}
 		}
 	} else {
-		return nil, fmt.Errorf("operator %s requires one string operand", expr.Op.String())
+		return nil, &ParseError{Message: fmt.Sprintf("operator %s requires one string operand", expr.Op.String()), Pos: pos}
 	}
 
 	return newExpr, nil
@@ -1612,6 +2391,13 @@ func (p *Parser) parseRegexExpr(expr *BinaryExpr) (*BinaryExpr, error) {
 // parseCall parses a function call.
 // This function assumes the function name and LPAREN have been consumed.
 func (p *Parser) parseCall(name string) (*Call, error) {
+	// Track the call as a level of nesting.
+	_, pos, _ := p.s.curr()
+	if err := p.enter(pos); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
 	// If there's a right paren then just return immediately.
 	if tok, _, _ := p.scan(); tok == RPAREN {
 		return &Call{Name: name}, nil
@@ -1643,6 +2429,56 @@ func (p *Parser) parseCall(name string) (*Call, error) {
 	return &Call{Name: name, Args: args}, nil
 }
 
+// parseListLiteral parses a bracketed list of expressions.
+// This function assumes the opening bracket has already been consumed.
+func (p *Parser) parseListLiteral(pos Pos) (*ListLiteral, error) {
+	// Track the list as a level of nesting.
+	if err := p.enter(pos); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	// If there's a right bracket then the list is empty.
+	if tok, _, _ := p.scanIgnoreWhitespace(); tok == RBRACKET {
+		return &ListLiteral{}, nil
+	}
+	p.unscan()
+
+	var vals []Expr
+	for {
+		val, err := p.ParseExpr()
+		if err != nil {
+			return nil, err
+		}
+		vals = append(vals, val)
+
+		// Continue on a comma and stop at the closing bracket.
+		tok, pos, lit := p.scanIgnoreWhitespace()
+		if tok == RBRACKET {
+			return &ListLiteral{Vals: vals}, nil
+		} else if tok != COMMA {
+			return nil, newParseError(tokstr(tok, lit), []string{",", "]"}, pos)
+		}
+	}
+}
+
+// maxVarRefSegments is the number of segments in a fully qualified
+// variable reference: database, retention policy, measurement and field.
+const maxVarRefSegments = 4
+
+// enter increments the expression nesting depth.
+// Returns an error if the maximum depth has been exceeded.
+func (p *Parser) enter(pos Pos) error {
+	if p.depth++; p.depth > p.opts.maxExprDepth() {
+		p.depth--
+		return &ParseError{Message: fmt.Sprintf("expression nested more than %d levels deep", p.opts.maxExprDepth()), Pos: pos}
+	}
+	return nil
+}
+
+// leave decrements the expression nesting depth.
+func (p *Parser) leave() { p.depth-- }
+
 // scan returns the next token from the underlying scanner.
 func (p *Parser) scan() (tok Token, pos Pos, lit string) { return p.s.Scan() }
 
@@ -1662,12 +2498,53 @@ func (p *Parser) consumeWhitespace() {
 	}
 }
 
+// expect records that any of toks would have been accepted at pos. Only the
+// tokens for the furthest position reached are kept.
+func (p *Parser) expect(pos Pos, toks ...string) {
+	if pos.Line > p.expectedPos.Line || (pos.Line == p.expectedPos.Line && pos.Char > p.expectedPos.Char) {
+		p.expected, p.expectedPos = p.expected[:0], pos
+	} else if pos != p.expectedPos {
+		return
+	}
+	p.expected = append(p.expected, toks...)
+}
+
+// operatorTokens are the tokens that can follow an expression to extend it.
+// NOT begins the "NOT LIKE" operator.
+var operatorTokens = []string{"+", "-", "*", "/", "AND", "OR", "=", "!=", "=~", "!~", "LIKE", "<", "<=", ">", ">=", "NOT"}
+
 // unscan pushes the previously read token back onto the buffer.
 func (p *Parser) unscan() { p.s.Unscan() }
 
 // ParseDuration parses a time duration from a string.
+//
+// Months ("mo") and years ("y") are fixed-length approximations of 30 days
+// and 365 days respectively. They do not account for calendar months or
+// leap years.
+//
+// Units are case-insensitive and may be separated from the number by
+// whitespace. Months are always written as "mo" so "M" means minutes.
+//
+// If s is not a valid InfluxQL duration then it is parsed with Go's
+// time.ParseDuration so that fractional and compound durations, such as
+// "1.5s" or "2h45m", are accepted. The InfluxQL form always takes
+// precedence so "1m" is one minute and "1M" is not rejected as a Go
+// duration. Query text only supports the InfluxQL form.
 func ParseDuration(s string) (time.Duration, error) {
+	d, err := parseDuration(s)
+	if err == ErrInvalidDuration {
+		if d, err := time.ParseDuration(strings.TrimSpace(s)); err == nil {
+			return d, nil
+		}
+	}
+	return d, err
+}
+
+// parseDuration parses a duration using the InfluxQL grammar only.
+func parseDuration(s string) (time.Duration, error) {
+	// Ignore leading and trailing whitespace.
 	// Return an error if the string is blank.
+	s = strings.TrimSpace(s)
 	if len(s) == 0 {
 		return 0, ErrInvalidDuration
 	}
@@ -1680,20 +2557,18 @@ func ParseDuration(s string) (time.Duration, error) {
 		return 0, ErrInvalidDuration
 	}
 
-	// Split string into individual runes.
-	a := split(s)
-
-	// Extract the unit of measure.
-	// If the last character is a digit then parse the whole string as microseconds.
-	// If the last two characters are "ms" the parse as milliseconds.
-	// Otherwise just use the last character as the unit of measure.
-	var num, uom string
-	if isDigit(rune(a[len(a)-1])) {
-		num, uom = s, "u"
-	} else if len(s) > 2 && s[len(s)-2:] == "ms" {
-		num, uom = string(a[:len(a)-2]), "ms"
-	} else {
-		num, uom = string(a[:len(a)-1]), string(a[len(a)-1:])
+	// Split the numeric part from the unit of measure, allowing whitespace
+	// between them. If there is no unit then parse as microseconds.
+	i := 0
+	if s[0] == '+' || s[0] == '-' {
+		i++
+	}
+	for i < len(s) && isDigit(rune(s[i])) {
+		i++
+	}
+	num, uom := s[:i], strings.ToLower(strings.TrimLeft(s[i:], " \t"))
+	if uom == "" {
+		uom = "u"
 	}
 
 	// Parse the numeric part.
@@ -1702,33 +2577,59 @@ func ParseDuration(s string) (time.Duration, error) {
 		return 0, ErrInvalidDuration
 	}
 
-	// Multiply by the unit of measure.
+	// Determine the unit of measure.
+	var unit time.Duration
 	switch uom {
 	case "u", "µ":
-		return time.Duration(n) * time.Microsecond, nil
+		unit = time.Microsecond
 	case "ms":
-		return time.Duration(n) * time.Millisecond, nil
+		unit = time.Millisecond
 	case "s":
-		return time.Duration(n) * time.Second, nil
+		unit = time.Second
 	case "m":
-		return time.Duration(n) * time.Minute, nil
+		unit = time.Minute
 	case "h":
-		return time.Duration(n) * time.Hour, nil
+		unit = time.Hour
 	case "d":
-		return time.Duration(n) * 24 * time.Hour, nil
+		unit = 24 * time.Hour
 	case "w":
-		return time.Duration(n) * 7 * 24 * time.Hour, nil
+		unit = 7 * 24 * time.Hour
+	case "mo":
+		unit = 30 * 24 * time.Hour
+	case "y":
+		unit = 365 * 24 * time.Hour
 	default:
 		return 0, ErrInvalidDuration
 	}
+
+	// Multiply by the unit of measure, rejecting values that overflow.
+	if n > int64(math.MaxInt64/unit) || n < int64(math.MinInt64/unit) {
+		return 0, ErrDurationOverflow
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// MustParseDuration parses a time duration from a string. Panic on error.
+func MustParseDuration(s string) time.Duration {
+	d, err := ParseDuration(s)
+	if err != nil {
+		panic(err.Error())
+	}
+	return d
 }
 
 // FormatDuration formats a duration to a string.
+// Weeks take priority over months so that a duration such as 30w is not
+// rewritten as 7mo.
 func FormatDuration(d time.Duration) string {
 	if d == 0 {
 		return "0s"
+	} else if d%(365*24*time.Hour) == 0 {
+		return fmt.Sprintf("%dy", d/(365*24*time.Hour))
 	} else if d%(7*24*time.Hour) == 0 {
 		return fmt.Sprintf("%dw", d/(7*24*time.Hour))
+	} else if d%(30*24*time.Hour) == 0 {
+		return fmt.Sprintf("%dmo", d/(30*24*time.Hour))
 	} else if d%(24*time.Hour) == 0 {
 		return fmt.Sprintf("%dd", d/(24*time.Hour))
 	} else if d%time.Hour == 0 {
@@ -1743,6 +2644,61 @@ func FormatDuration(d time.Duration) string {
 	return fmt.Sprintf("%d", d/time.Microsecond)
 }
 
+// FormatDurationLong formats a duration as a list of spelled out units,
+// such as "1 hour 30 minutes", using the units of FormatDuration.
+// Precision is truncated to microseconds and negative durations are
+// prefixed with a minus sign.
+func FormatDurationLong(d time.Duration) string {
+	// Convert through uint64 so the minimum duration doesn't overflow.
+	var sign string
+	u := uint64(d)
+	if d < 0 {
+		sign, u = "-", uint64(-d)
+	}
+	u -= u % uint64(time.Microsecond)
+
+	if u == 0 {
+		return "0 seconds"
+	}
+
+	// Split into units from largest to smallest. Weeks take priority over
+	// months as they do in FormatDuration.
+	var a []string
+	for _, unit := range longDurationUnits {
+		if unit.name == "month" && u%uint64(7*24*time.Hour) == 0 {
+			continue
+		} else if n := u / uint64(unit.d); n > 0 {
+			a = append(a, formatDurationUnit(n, unit.name))
+			u -= n * uint64(unit.d)
+		}
+	}
+	return sign + strings.Join(a, " ")
+}
+
+// longDurationUnits are the units used by FormatDurationLong, largest first.
+var longDurationUnits = []struct {
+	d    time.Duration
+	name string
+}{
+	{365 * 24 * time.Hour, "year"},
+	{30 * 24 * time.Hour, "month"},
+	{7 * 24 * time.Hour, "week"},
+	{24 * time.Hour, "day"},
+	{time.Hour, "hour"},
+	{time.Minute, "minute"},
+	{time.Second, "second"},
+	{time.Millisecond, "millisecond"},
+	{time.Microsecond, "microsecond"},
+}
+
+// formatDurationUnit returns n followed by the unit name, pluralized if needed.
+func formatDurationUnit(n uint64, name string) string {
+	if n == 1 {
+		return "1 " + name
+	}
+	return strconv.FormatUint(n, 10) + " " + name + "s"
+}
+
 // parseTokens consumes an expected sequence of tokens.
 func (p *Parser) parseTokens(toks []Token) error {
 	for _, expected := range toks {
@@ -1755,18 +2711,16 @@ func (p *Parser) parseTokens(toks []Token) error {
 
 // QuoteString returns a quoted string.
 func QuoteString(s string) string {
-	return `'` + strings.NewReplacer("\n", `\n`, `\`, `\\`, `'`, `\'`).Replace(s) + `'`
+	var buf bytes.Buffer
+	writeQuoted(&buf, s, '\'')
+	return buf.String()
 }
 
 // QuoteIdent returns a quoted identifier from multiple bare identifiers.
 func QuoteIdent(segments []string) string {
-	r := strings.NewReplacer("\n", `\n`, `\`, `\\`, `"`, `\"`)
-
 	var buf bytes.Buffer
 	for i, segment := range segments {
-		_ = buf.WriteByte('"')
-		_, _ = buf.WriteString(r.Replace(segment))
-		_ = buf.WriteByte('"')
+		writeQuoted(&buf, segment, '"')
 		if i < len(segments)-1 {
 			_ = buf.WriteByte('.')
 		}
@@ -1774,12 +2728,29 @@ func QuoteIdent(segments []string) string {
 	return buf.String()
 }
 
-// split splits a string into a slice of runes.
-func split(s string) (a []rune) {
+// writeQuoted writes s to buf surrounded by quote. The quote character,
+// backslashes, and control characters are escaped so that ScanString
+// returns the original string.
+func writeQuoted(buf *bytes.Buffer, s string, quote rune) {
+	_, _ = buf.WriteRune(quote)
 	for _, ch := range s {
-		a = append(a, ch)
+		switch {
+		case ch == quote || ch == '\\':
+			_ = buf.WriteByte('\\')
+			_, _ = buf.WriteRune(ch)
+		case ch == '\n':
+			_, _ = buf.WriteString(`\n`)
+		case ch == '\t':
+			_, _ = buf.WriteString(`\t`)
+		case ch == '\r':
+			_, _ = buf.WriteString(`\r`)
+		case ch < 0x20 || ch == 0x7f:
+			_, _ = fmt.Fprintf(buf, `\u%04x`, ch)
+		default:
+			_, _ = buf.WriteRune(ch)
+		}
 	}
-	return
+	_, _ = buf.WriteRune(quote)
 }
 
 // isDateString returns true if the string looks like a date-only time literal.
@@ -1788,18 +2759,44 @@ func isDateString(s string) bool { return dateStringRegexp.MatchString(s) }
 // isDateTimeString returns true if the string looks like a date+time time literal.
 func isDateTimeString(s string) bool { return dateTimeStringRegexp.MatchString(s) }
 
+// trimUTCSuffix removes a trailing "Z" or "UTC", optionally preceded by a
+// space, from a date time string. Returns false if there is no suffix.
+func trimUTCSuffix(s string) (string, bool) {
+	for _, suffix := range []string{" UTC", "UTC", "Z"} {
+		if strings.HasSuffix(s, suffix) {
+			return strings.TrimSuffix(s, suffix), true
+		}
+	}
+	return s, false
+}
+
 var dateStringRegexp = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
 var dateTimeStringRegexp = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}.+`)
 
 // ErrInvalidDuration is returned when parsing a malformatted duration.
 var ErrInvalidDuration = errors.New("invalid duration")
 
+// ErrDurationOverflow is returned when a duration is too large to be
+// represented as a time.Duration.
+var ErrDurationOverflow = errors.New("duration overflow")
+
 // ParseError represents an error that occurred during parsing.
+// Use errors.As to retrieve it from an error that has been wrapped.
+//
+// Each entry in Expected is a single token. Keywords, operators, and
+// punctuation are written as they appear in a query (e.g. "FROM", "(", ";")
+// and "EOF" is the end of the input. Lowercase entries name a class of
+// token: "identifier", "string", "number", "bool", "duration", or a data
+// type accepted after "::".
 type ParseError struct {
 	Message  string
 	Found    string
 	Expected []string
 	Pos      Pos
+
+	// Err is the underlying error that caused the parse error, if any.
+	// For example, ErrInvalidDuration when a duration literal is malformed.
+	Err error
 }
 
 // newParseError returns a new instance of ParseError.
@@ -1814,3 +2811,21 @@ func (e *ParseError) Error() string {
 	}
 	return fmt.Sprintf("found %s, expected %s at line %d, char %d", e.Found, strings.Join(e.Expected, ", "), e.Pos.Line+1, e.Pos.Char+1)
 }
+
+// ExpectedTokens returns the distinct tokens the parser expected in the
+// order they were reported. Returns nil if the error was not caused by an
+// unexpected token.
+func (e *ParseError) ExpectedTokens() []string {
+	var a []string
+	m := make(map[string]struct{}, len(e.Expected))
+	for _, tok := range e.Expected {
+		if _, ok := m[tok]; !ok {
+			m[tok] = struct{}{}
+			a = append(a, tok)
+		}
+	}
+	return a
+}
+
+// Unwrap returns the underlying error so it can be matched with errors.Is.
+func (e *ParseError) Unwrap() error { return e.Err }
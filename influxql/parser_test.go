@@ -1,9 +1,16 @@
 package influxql_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
 	"reflect"
 	"regexp"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -34,11 +41,452 @@ func TestParser_ParseQuery_Empty(t *testing.T) {
 // Ensure the parser can return an error from an malformed statement.
 func TestParser_ParseQuery_ParseError(t *testing.T) {
 	_, err := influxql.NewParser(strings.NewReader(`SELECT`)).ParseQuery()
-	if err == nil || err.Error() != `found EOF, expected identifier, string, number, bool at line 1, char 8` {
+	if err == nil || err.Error() != `found EOF, expected identifier, string, number, bool at line 1, char 7` {
 		t.Fatalf("unexpected error: %s", err)
 	}
 }
 
+// Ensure the parser handles statement separators and trailing whitespace.
+func TestParser_ParseQuery_Separators(t *testing.T) {
+	var tests = []struct {
+		s          string
+		n          int
+		terminated bool
+		err        string
+	}{
+		{s: ``, n: 0},
+		{s: `SELECT 1`, n: 1},
+		{s: `SELECT 1;`, n: 1, terminated: true},
+		{s: `SELECT 1 ; `, n: 1, terminated: true},
+		{s: "SELECT 1\n", n: 1},
+		{s: "SELECT 1;\n\t\n", n: 1, terminated: true},
+		{s: "SELECT 1; SELECT 2\n", n: 2},
+		{s: "SELECT 1; SELECT 2;", n: 2, terminated: true},
+		{s: `SELECT 1 ;; SELECT 2`, err: `empty statement at line 1, char 11`},
+		{s: `SELECT 1; ;`, err: `empty statement at line 1, char 11`},
+	}
+
+	for i, tt := range tests {
+		q, err := influxql.NewParser(strings.NewReader(tt.s)).ParseQuery()
+		if !reflect.DeepEqual(tt.err, errstring(err)) {
+			t.Errorf("%d. %q: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.s, tt.err, err)
+		} else if tt.err == "" && len(q.Statements) != tt.n {
+			t.Errorf("%d. %q: unexpected statement count: %d", i, tt.s, len(q.Statements))
+		} else if tt.err == "" && q.Terminated != tt.terminated {
+			t.Errorf("%d. %q: unexpected terminated: %v", i, tt.s, q.Terminated)
+		}
+	}
+}
+
+// Ensure statements can be streamed from a multi-statement query.
+func TestParser_NextStatement(t *testing.T) {
+	p := influxql.NewParser(strings.NewReader("SELECT a FROM b;\nSHOW DATABASES ;\n\nSELECT c FROM d\n"))
+	for i, exp := range []string{`SELECT a FROM b`, `SHOW DATABASES`, `SELECT c FROM d`} {
+		if stmt, err := p.NextStatement(); err != nil {
+			t.Fatalf("%d. unexpected error: %s", i, err)
+		} else if s := stmt.String(); s != exp {
+			t.Fatalf("%d. unexpected statement: %s", i, s)
+		} else if terminated := i < 2; p.Terminated() != terminated {
+			t.Fatalf("%d. unexpected terminated: %v", i, p.Terminated())
+		}
+	}
+
+	// Every call after the last statement returns io.EOF.
+	for i := 0; i < 2; i++ {
+		if stmt, err := p.NextStatement(); err != io.EOF {
+			t.Fatalf("expected io.EOF, got: %v (%v)", err, stmt)
+		}
+	}
+}
+
+// Ensure streaming stops at the first invalid statement.
+func TestParser_NextStatement_ParseError(t *testing.T) {
+	p := influxql.NewParser(strings.NewReader(`SELECT a FROM b; SELECT; SELECT c FROM d`))
+	if _, err := p.NextStatement(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := p.NextStatement(); errstring(err) != `found ;, expected identifier, string, number, bool at line 1, char 24` {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+// Ensure a reset parser parses a new query without state from the previous one.
+func TestParser_Reset(t *testing.T) {
+	p := influxql.NewParser(strings.NewReader(`SELECT value FROM cpu WHERE`))
+	if _, err := p.ParseStatement(); err == nil {
+		t.Fatal("expected error")
+	}
+
+	p.Reset(strings.NewReader(`SELECT max(value) FROM mem`))
+	if stmt, err := p.ParseStatement(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if s := stmt.String(); s != `SELECT max(value) FROM mem` {
+		t.Fatalf("unexpected statement: %s", s)
+	}
+
+	// Positions in errors must start over after a reset.
+	p.Reset(strings.NewReader(`SELECT`))
+	if _, err := p.ParseStatement(); errstring(err) != `found EOF, expected identifier, string, number, bool at line 1, char 7` {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+// Ensure a reset parser starts with an empty unscan buffer.
+func TestParser_Reset_Unscan(t *testing.T) {
+	// The failed LIMIT leaves tokens behind in the unscan buffer.
+	p := influxql.NewParser(strings.NewReader(`SELECT a FROM b LIMIT x y z`))
+	if _, err := p.ParseQuery(); err == nil {
+		t.Fatal("expected error")
+	}
+
+	p.Reset(strings.NewReader(`SHOW DATABASES`))
+	if q, err := p.ParseQuery(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if len(q.Statements) != 1 || q.Statements[0].String() != `SHOW DATABASES` {
+		t.Fatalf("unexpected query: %s", q)
+	}
+}
+
+// Ensure pooled parsers can be used concurrently and keep the pool's options.
+func TestParserPool(t *testing.T) {
+	pp := influxql.NewParserPool(influxql.ParserOptions{OperatorAliases: true})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 50)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			exp := fmt.Sprintf(`SELECT value FROM cpu%d WHERE host = 'a' AND value > %d.000`, i, i)
+			p := pp.Get(strings.NewReader(fmt.Sprintf(`SELECT value FROM cpu%d WHERE host = 'a' && value > %d`, i, i)))
+			defer pp.Put(p)
+			if stmt, err := p.ParseStatement(); err != nil {
+				errs <- err
+			} else if s := stmt.String(); s != exp {
+				errs <- fmt.Errorf("unexpected statement: %s", s)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+// Ensure indentation and line breaks do not change the parsed statement.
+func TestParser_ParseStatement_Indented(t *testing.T) {
+	var tests = []struct {
+		s   string
+		exp string
+	}{
+		{
+			s:   "SELECT\n\tmean(value),\n\tmax(value)\nFROM\n\tcpu\nWHERE\n\thost = 'a'\n\tAND region = 'b'\nGROUP BY\n\ttime(10m)\n",
+			exp: `SELECT mean(value), max(value) FROM cpu WHERE host = 'a' AND region = 'b' GROUP BY time(10m)`,
+		},
+		{
+			s:   "  \r\n  SELECT  value  FROM  cpu  \r\n  ORDER  BY  time  DESC  \r\n  LIMIT  10  \r\n",
+			exp: `SELECT value FROM cpu ORDER BY time DESC LIMIT 10`,
+		},
+		{
+			s:   "SHOW\tTAG\tVALUES\n\t\tFROM cpu\n\t\tWITH KEY = host",
+			exp: `SHOW TAG VALUES FROM cpu WITH KEY = host`,
+		},
+	}
+
+	for i, tt := range tests {
+		stmt, err := influxql.NewParser(strings.NewReader(tt.s)).ParseStatement()
+		if err != nil {
+			t.Errorf("%d. %q: unexpected error: %s", i, tt.s, err)
+		} else if s := stmt.String(); s != tt.exp {
+			t.Errorf("%d. %q: unexpected statement:\n  exp=%s\n  got=%s", i, tt.s, tt.exp, s)
+		} else if exp := influxql.MustParseStatement(tt.exp); !reflect.DeepEqual(exp, stmt) {
+			t.Errorf("%d. %q: statement mismatch:\n  exp=%#v\n  got=%#v", i, tt.s, exp, stmt)
+		}
+	}
+}
+
+// Ensure a query can be parsed with a context.
+func TestParseQueryContext(t *testing.T) {
+	q, err := influxql.ParseQueryContext(context.Background(), `SELECT a FROM b; SELECT c FROM d`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if len(q.Statements) != 2 {
+		t.Fatalf("unexpected statement count: %d", len(q.Statements))
+	}
+}
+
+// Ensure parsing stops once the context is cancelled or its deadline passes.
+func TestParseQueryContext_Done(t *testing.T) {
+	s := strings.Repeat(`SELECT mean(value) FROM cpu WHERE host = 'a' GROUP BY time(1m);`, 100000)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := influxql.ParseQueryContext(ctx, s); err != context.Canceled {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	if _, err := influxql.ParseQueryContext(ctx, s); err != context.DeadlineExceeded {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// Ensure a query parsed from a byte slice matches one parsed from a string.
+func TestParseQueryBytes(t *testing.T) {
+	for i, s := range []string{
+		``,
+		`SELECT mean(value) FROM cpu WHERE host = 'serverA' GROUP BY time(10m)`,
+		"SELECT \"field\" FROM \"series\"\n\tWHERE value > 10 AND time > now() - 1h;\nSHOW DATABASES;",
+		`SELECT value FROM cpu WHERE name = 'héllo wörld' ORDER BY time DESC LIMIT 10`,
+		`CREATE CONTINUOUS QUERY cq ON db BEGIN SELECT count(value) INTO c FROM cpu GROUP BY time(1h) END`,
+	} {
+		exp, err := influxql.ParseQuery(s)
+		if err != nil {
+			t.Fatalf("%d. %q: unexpected error: %s", i, s, err)
+		}
+		q, err := influxql.ParseQueryBytes([]byte(s))
+		if err != nil {
+			t.Errorf("%d. %q: unexpected error: %s", i, s, err)
+		} else if !reflect.DeepEqual(exp, q) {
+			t.Errorf("%d. %q: query mismatch:\n  exp=%s\n  got=%s", i, s, exp, q)
+		}
+	}
+}
+
+// Ensure errors parsed from a byte slice report the same position as a string.
+func TestParseQueryBytes_ParseError(t *testing.T) {
+	for i, s := range []string{
+		"SELECT value FROM cpu;\nSELECT FROM",
+		"SELECT 'héllo', \n  value FROM cpu WHERE value >",
+		`SELECT value FROM cpu WHERE name = 'unterminated`,
+	} {
+		_, exp := influxql.ParseQuery(s)
+		_, err := influxql.ParseQueryBytes([]byte(s))
+		if exp == nil {
+			t.Fatalf("%d. %q: expected error", i, s)
+		} else if !reflect.DeepEqual(exp, err) {
+			t.Errorf("%d. %q: error mismatch:\n  exp=%#v\n  got=%#v", i, s, exp, err)
+		}
+	}
+}
+
+// Ensure error positions count characters rather than bytes.
+func TestParseError_Pos_Unicode(t *testing.T) {
+	for i, tt := range []struct {
+		s   string
+		err string
+	}{
+		{s: `SELECT "vålüe" FROM "mé" WHERE`, err: `found EOF, expected identifier, string, number, bool at line 1, char 31`},
+		{s: `SELECT "vålüe" FROM "mé" LIMIT 'ü'`, err: `found ü, expected number at line 1, char 32`},
+		{s: "SELECT 'é'\nFROM cpu WHERE 'ü' =", err: `found EOF, expected identifier, string, number, bool at line 2, char 21`},
+	} {
+		if _, err := influxql.ParseQuery(tt.s); errstring(err) != tt.err {
+			t.Errorf("%d. %q: error mismatch:\n  exp=%s\n  got=%s", i, tt.s, tt.err, err)
+		}
+	}
+}
+
+// Ensure the expected tokens can be retrieved from truncated queries.
+func TestParseError_ExpectedTokens(t *testing.T) {
+	var tests = []struct {
+		s   string
+		exp []string
+	}{
+		{s: ``, exp: nil},
+		{s: `SELECT`, exp: []string{"identifier", "string", "number", "bool"}},
+		{s: `SELECT value`, exp: []string{"FROM"}},
+		{s: `SELECT value FROM`, exp: []string{"identifier"}},
+		{s: `SELECT value FROM cpu WHERE`, exp: []string{"identifier", "string", "number", "bool"}},
+		{s: `SELECT value FROM cpu GROUP`, exp: []string{"BY"}},
+		{s: `SELECT value FROM cpu ORDER BY`, exp: []string{"identifier", "ASC", "DESC"}},
+		{s: `SELECT value FROM cpu LIMIT`, exp: []string{"number"}},
+		{s: `SELECT value FROM cpu garbage`, exp: []string{";", "EOF"}},
+		{s: `SHOW`, exp: []string{"CONTINUOUS", "DATABASES", "FIELD", "MEASUREMENTS", "RETENTION", "SERIES", "TAG", "USERS"}},
+		{s: `SHOW TAG`, exp: []string{"KEYS", "KEY", "VALUES"}},
+		{s: `GRANT`, exp: []string{"READ", "WRITE", "ALL"}},
+		{s: `SELECT value::`, exp: []string{"float", "integer", "string", "boolean", "tag", "field"}},
+		{s: `SELECT value FROM cpu LIMIT 0`, exp: nil},
+	}
+
+	for i, tt := range tests {
+		_, err := influxql.ParseQuery(tt.s)
+		if tt.s == `` {
+			if err != nil {
+				t.Errorf("%d. %q: unexpected error: %s", i, tt.s, err)
+			}
+			continue
+		}
+
+		var perr *influxql.ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("%d. %q: expected parse error, got: %v", i, tt.s, err)
+		} else if got := perr.ExpectedTokens(); !reflect.DeepEqual(tt.exp, got) {
+			t.Errorf("%d. %q: expected tokens mismatch:\n  exp=%q\n  got=%q", i, tt.s, tt.exp, got)
+		}
+	}
+}
+
+// Ensure a partial query returns the tokens that are valid at the cursor.
+func TestParsePartial(t *testing.T) {
+	var tests = []struct {
+		s      string
+		cursor int
+		n      int
+		err    string
+		exp    []string
+	}{
+		{s: ``, exp: []string{"ALTER", "CREATE", "DELETE", "DROP", "GRANT", "SHOW", "REVOKE", "SELECT"}},
+		{s: `SELECT value FROM cpu WHERE `, err: `found EOF, expected identifier, string, number, bool at line 1, char 29`,
+			exp: []string{"identifier", "string", "number", "duration", "bool", "*", "("}},
+		{s: `SELECT value FROM cpu `, n: 1, exp: []string{";", "GROUP", "LIMIT", "OFFSET", "ORDER", "WHERE"}},
+		{s: `SELECT mean(value) `, err: `found EOF, expected FROM at line 1, char 20`,
+			exp: []string{"+", "-", "*", "/", "AND", "OR", "=", "!=", "=~", "!~", "LIKE", "<", "<=", ">", ">=", ",", "AS", "FROM", "INTO", "NOT"}},
+		{s: `SELECT value FROM cpu WHERE host =~ `, err: `found EOF, expected identifier, string, number, bool at line 1, char 37`,
+			exp: []string{"identifier", "string", "number", "duration", "bool", "regex", "*", "("}},
+		{s: `SHOW `, err: `found EOF, expected CONTINUOUS, DATABASES, FIELD, MEASUREMENTS, RETENTION, SERIES, TAG, USERS at line 1, char 6`,
+			exp: []string{"CONTINUOUS", "DATABASES", "FIELD", "MEASUREMENTS", "RETENTION", "SERIES", "TAG", "USERS"}},
+		{s: `SHOW DATABASES `, n: 1, exp: []string{";"}},
+		{s: `SELECT value FROM `, err: `found EOF, expected identifier at line 1, char 19`, exp: []string{"identifier", "regex", "("}},
+		{s: `SELECT now() `, n: 1, exp: []string{"+", "-", "*", "/", "AND", "OR", "=", "!=", "=~", "!~", "LIKE", "<", "<=", ">", ">=", ",", ";", "AS", "FROM", "INTO", "NOT"}},
+		{s: `SELECT value FROM cpu GROUP BY host `, n: 1, exp: []string{"+", "-", "*", "/", "AND", "OR", "=", "!=", "=~", "!~", "LIKE", "<", "<=", ">", ">=", ",", ";", "AS", "LIMIT", "NOT", "OFFSET", "ORDER"}},
+		{s: "SELECT value\nFROM cpu\nWHERE ", err: `found EOF, expected identifier, string, number, bool at line 3, char 7`,
+			exp: []string{"identifier", "string", "number", "duration", "bool", "*", "("}},
+		{s: `SELECT ) FROM cpu WHERE `, err: `found ), expected identifier, string, number, bool at line 1, char 8`},
+
+		// The cursor is within or at the end of a word.
+		{s: `SH`, err: `found SH, expected SELECT at line 1, char 1`, exp: []string{"SHOW"}},
+		{s: `SELECT value FR`, err: `found FR, expected FROM at line 1, char 14`, exp: []string{"FROM"}},
+		{s: `SELECT val`, err: `found EOF, expected FROM at line 1, char 11`, exp: []string{"identifier"}},
+		{s: `SELECT value FROM cpu WHERE x = t`, n: 1, exp: []string{"identifier", "bool"}},
+		{s: `SELECT a FROM b; SEL`, n: 1, err: `found SEL, expected SELECT at line 1, char 18`, exp: []string{"SELECT"}},
+
+		// Text after the cursor is ignored.
+		{s: `SELECT value FROM cpu WHERE host = 'a'`, cursor: 9, err: `found EOF, expected FROM at line 1, char 10`, exp: []string{"identifier"}},
+		{s: `SELECT value FROM cpu WHERE host = 'a'`, cursor: 22, n: 1, exp: []string{";", "GROUP", "LIMIT", "OFFSET", "ORDER", "WHERE"}},
+	}
+
+	for i, tt := range tests {
+		cursor := tt.cursor
+		if cursor == 0 {
+			cursor = len(tt.s)
+		}
+
+		q, perr, a := influxql.ParsePartial(tt.s, cursor)
+		if len(q.Statements) != tt.n {
+			t.Errorf("%d. %q: unexpected statement count: %d", i, tt.s, len(q.Statements))
+		}
+		var err string
+		if perr != nil {
+			err = perr.Error()
+		}
+		if err != tt.err {
+			t.Errorf("%d. %q: error mismatch:\n  exp=%s\n  got=%s", i, tt.s, tt.err, err)
+		}
+		if !reflect.DeepEqual(tt.exp, a) {
+			t.Errorf("%d. %q: suggestions mismatch:\n  exp=%q\n  got=%q", i, tt.s, tt.exp, a)
+		}
+	}
+}
+
+// Ensure a single statement can be parsed from a string.
+func TestParseStatement(t *testing.T) {
+	var tests = []struct {
+		s   string
+		err string
+	}{
+		{s: `SELECT a FROM b`},
+		{s: `SELECT a FROM b;`},
+		{s: `SELECT a FROM b ; `},
+		{s: `SELECT a FROM b garbage`, err: `found garbage, expected EOF at line 1, char 17`},
+		{s: `SELECT a FROM b; SELECT c FROM d`, err: `found SELECT, expected EOF at line 1, char 18`},
+		{s: `SELECT a FROM b;;`, err: `found ;, expected EOF at line 1, char 17`},
+		{s: `SELECT`, err: `found EOF, expected identifier, string, number, bool at line 1, char 7`},
+
+		// SHOW DATABASES takes no arguments.
+		{s: `SHOW DATABASES`},
+		{s: `show databases;`},
+		{s: `SHOW DATABASES foo`, err: `found foo, expected EOF at line 1, char 16`},
+		{s: `SHOW DATABASES ON db`, err: `found ON, expected EOF at line 1, char 16`},
+	}
+
+	for i, tt := range tests {
+		stmt, err := influxql.ParseStatement(tt.s)
+		if !reflect.DeepEqual(tt.err, errstring(err)) {
+			t.Errorf("%d. %q: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.s, tt.err, err)
+		} else if tt.err == "" && stmt == nil {
+			t.Errorf("%d. %q: expected statement", i, tt.s)
+		}
+	}
+}
+
+// Ensure MustParseQuery returns a query for valid input.
+func TestMustParseQuery(t *testing.T) {
+	if q := influxql.MustParseQuery(`SELECT a FROM b; SELECT c FROM d`); len(q.Statements) != 2 {
+		t.Fatalf("unexpected statement count: %d", len(q.Statements))
+	}
+}
+
+// Ensure MustParseQuery panics with the parse error message on invalid input.
+func TestMustParseQuery_Panic(t *testing.T) {
+	defer func() {
+		if r := recover(); r != `found EOF, expected identifier, string, number, bool at line 1, char 7` {
+			t.Fatalf("unexpected panic: %v", r)
+		}
+	}()
+	influxql.MustParseQuery(`SELECT`)
+}
+
+// Ensure MustParseStatement panics on trailing tokens.
+func TestMustParseStatement_Panic(t *testing.T) {
+	defer func() {
+		if r := recover(); r != `found SELECT, expected EOF at line 1, char 18` {
+			t.Fatalf("unexpected panic: %v", r)
+		}
+	}()
+	influxql.MustParseStatement(`SELECT a FROM b; SELECT c FROM d`)
+}
+
+// Ensure MustParseExpr panics with the parse error message on invalid input.
+func TestMustParseExpr_Panic(t *testing.T) {
+	defer func() {
+		if r := recover(); r != `found EOF, expected identifier, string, number, bool at line 1, char 4` {
+			t.Fatalf("unexpected panic: %v", r)
+		}
+	}()
+	influxql.MustParseExpr(`1 +`)
+}
+
+// Ensure a parse error can be retrieved with errors.As after being wrapped.
+func TestParseError_As(t *testing.T) {
+	_, err := influxql.ParseQuery(`SELECT a FROM b; CREATE CONTINUOUS QUERY cq ON db BEGIN SELECT count(value) INTO cnt FROM cpu GROUP BY`)
+	err = fmt.Errorf("error parsing query: %w", err)
+
+	var perr *influxql.ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("expected parse error: %s", err)
+	} else if perr.Pos != (influxql.Pos{Line: 0, Char: 102}) {
+		t.Fatalf("unexpected pos: %+v", perr.Pos)
+	}
+}
+
+// Ensure a parse error wraps the underlying error that caused it.
+func TestParseError_Unwrap(t *testing.T) {
+	_, err := influxql.ParseStatement(`CREATE RETENTION POLICY p ON db DURATION 99999999999y REPLICATION 1`)
+	if !errors.Is(err, influxql.ErrDurationOverflow) {
+		t.Fatalf("expected duration overflow: %s", err)
+	}
+
+	var perr *influxql.ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("expected parse error: %s", err)
+	} else if perr.Pos != (influxql.Pos{Line: 0, Char: 41}) {
+		t.Fatalf("unexpected pos: %+v", perr.Pos)
+	}
+}
+
 // Ensure the parser can parse strings into Statement ASTs.
 func TestParser_ParseStatement(t *testing.T) {
 	var tests = []struct {
@@ -78,8 +526,10 @@ func TestParser_ParseStatement(t *testing.T) {
 				SortFields: []*influxql.SortField{
 					{Ascending: true},
 				},
-				Limit:  20,
-				Offset: 10,
+				Limit:     20,
+				Offset:    10,
+				HasLimit:  true,
+				HasOffset: true,
 			},
 		},
 
@@ -112,6 +562,21 @@ func TestParser_ParseStatement(t *testing.T) {
 			},
 		},
 
+		// SELECT statement with a parenthesized merge
+		{
+			s: `SELECT field1 FROM (aa, "bb") GROUP BY time(1m)`,
+			stmt: &influxql.SelectStatement{
+				Fields: []*influxql.Field{{Expr: &influxql.VarRef{Val: "field1"}}},
+				Source: &influxql.Merge{
+					Measurements: []*influxql.Measurement{
+						{Name: "aa"},
+						{Name: `"bb"`},
+					},
+				},
+				Dimensions: []*influxql.Dimension{{Expr: &influxql.Call{Name: "time", Args: []influxql.Expr{&influxql.DurationLiteral{Val: time.Minute}}}}},
+			},
+		},
+
 		// SELECT statement (lowercase)
 		{
 			s: `select my_field from myseries`,
@@ -132,7 +597,69 @@ func TestParser_ParseStatement(t *testing.T) {
 					{Name: "field1"},
 					{Name: "field2"},
 				},
-				Limit: 10,
+				Limit:    10,
+				HasLimit: true,
+			},
+		},
+
+		// SELECT statement with aliased time field and time dimension
+		{
+			s: `SELECT time AS ts, mean(value) FROM cpu GROUP BY time(1m) AS t, host`,
+			stmt: &influxql.SelectStatement{
+				Fields: []*influxql.Field{
+					{Expr: &influxql.VarRef{Val: "time"}, Alias: "ts"},
+					{Expr: &influxql.Call{Name: "mean", Args: []influxql.Expr{&influxql.VarRef{Val: "value"}}}},
+				},
+				Source: &influxql.Measurement{Name: "cpu"},
+				Dimensions: []*influxql.Dimension{
+					{Expr: &influxql.Call{Name: "time", Args: []influxql.Expr{&influxql.DurationLiteral{Val: time.Minute}}}, Alias: "t"},
+					{Expr: &influxql.VarRef{Val: "host"}},
+				},
+			},
+		},
+
+		// SELECT statements without a source
+		{
+			s: `SELECT now()`,
+			stmt: &influxql.SelectStatement{
+				Fields: []*influxql.Field{{Expr: &influxql.Call{Name: "now"}}},
+			},
+		},
+		{
+			s: `SELECT 2*3`,
+			stmt: &influxql.SelectStatement{
+				Fields: []*influxql.Field{{Expr: &influxql.BinaryExpr{
+					Op:  influxql.MUL,
+					LHS: &influxql.NumberLiteral{Val: 2},
+					RHS: &influxql.NumberLiteral{Val: 3},
+				}}},
+			},
+		},
+
+		// SELECT statement with tag and field qualifiers
+		{
+			s: `SELECT "host"::tag, value::field FROM cpu WHERE "region"::tag = 'us'`,
+			stmt: &influxql.SelectStatement{
+				Fields: []*influxql.Field{
+					{Expr: &influxql.VarRef{Val: `"host"`, Type: influxql.Tag}},
+					{Expr: &influxql.VarRef{Val: "value", Type: influxql.AnyField}},
+				},
+				Source: &influxql.Measurement{Name: "cpu"},
+				Condition: &influxql.BinaryExpr{
+					Op:  influxql.EQ,
+					LHS: &influxql.VarRef{Val: `"region"`, Type: influxql.Tag},
+					RHS: &influxql.StringLiteral{Val: "us"},
+				},
+			},
+		},
+
+		// SELECT statement with an explicit zero offset
+		{
+			s: `SELECT field1 FROM myseries OFFSET 0`,
+			stmt: &influxql.SelectStatement{
+				Fields:    []*influxql.Field{{Expr: &influxql.VarRef{Val: "field1"}}},
+				Source:    &influxql.Measurement{Name: "myseries"},
+				HasOffset: true,
 			},
 		},
 
@@ -179,6 +706,27 @@ func TestParser_ParseStatement(t *testing.T) {
 			},
 		},
 
+		// SHOW SERIES FROM regex WHERE regex and exact tag matches
+		{
+			s: `SHOW SERIES FROM /cpu.*/ WHERE host =~ /web/ AND region = 'uswest'`,
+			stmt: &influxql.ShowSeriesStatement{
+				Source: &influxql.Measurement{Regex: &influxql.RegexLiteral{Val: regexp.MustCompile(`cpu.*`)}},
+				Condition: &influxql.BinaryExpr{
+					Op: influxql.AND,
+					LHS: &influxql.BinaryExpr{
+						Op:  influxql.EQREGEX,
+						LHS: &influxql.VarRef{Val: "host"},
+						RHS: &influxql.RegexLiteral{Val: regexp.MustCompile(`web`)},
+					},
+					RHS: &influxql.BinaryExpr{
+						Op:  influxql.EQ,
+						LHS: &influxql.VarRef{Val: "region"},
+						RHS: &influxql.StringLiteral{Val: "uswest"},
+					},
+				},
+			},
+		},
+
 		// SHOW MEASUREMENTS WHERE with ORDER BY and LIMIT
 		{
 			s: `SHOW MEASUREMENTS WHERE region = 'uswest' ORDER BY ASC, field1, field2 DESC LIMIT 10`,
@@ -198,6 +746,16 @@ func TestParser_ParseStatement(t *testing.T) {
 		},
 
 		// SHOW RETENTION POLICIES
+		{
+			s:    `SHOW RETENTION POLICIES`,
+			stmt: &influxql.ShowRetentionPoliciesStatement{},
+		},
+		{
+			s: `SHOW RETENTION POLICIES ON mydb`,
+			stmt: &influxql.ShowRetentionPoliciesStatement{
+				Database: "mydb",
+			},
+		},
 		{
 			s: `SHOW RETENTION POLICIES mydb`,
 			stmt: &influxql.ShowRetentionPoliciesStatement{
@@ -312,6 +870,63 @@ func TestParser_ParseStatement(t *testing.T) {
 			stmt: &influxql.ShowUsersStatement{},
 		},
 
+		// SHOW TAG KEY CARDINALITY
+		{
+			s:    `SHOW TAG KEY CARDINALITY`,
+			stmt: &influxql.ShowTagKeyCardinalityStatement{},
+		},
+
+		// SHOW TAG KEY CARDINALITY ON ... FROM ... WHERE ...
+		{
+			s: `SHOW TAG KEY CARDINALITY ON db0 FROM cpu WHERE region = 'uswest'`,
+			stmt: &influxql.ShowTagKeyCardinalityStatement{
+				Database: "db0",
+				Source:   &influxql.Measurement{Name: "cpu"},
+				Condition: &influxql.BinaryExpr{
+					Op:  influxql.EQ,
+					LHS: &influxql.VarRef{Val: "region"},
+					RHS: &influxql.StringLiteral{Val: "uswest"},
+				},
+			},
+		},
+
+		// SHOW TAG VALUES CARDINALITY WITH KEY = ...
+		{
+			s: `SHOW TAG VALUES CARDINALITY WITH KEY = host`,
+			stmt: &influxql.ShowTagValuesCardinalityStatement{
+				TagKeys: []string{"host"},
+			},
+		},
+
+		// SHOW TAG VALUES CARDINALITY ON ... FROM ... WITH KEY IN (...) WHERE ...
+		{
+			s: `SHOW TAG VALUES CARDINALITY ON db0 FROM cpu WITH KEY IN (host, region) WHERE region = 'uswest'`,
+			stmt: &influxql.ShowTagValuesCardinalityStatement{
+				Database: "db0",
+				Source:   &influxql.Measurement{Name: "cpu"},
+				TagKeys:  []string{"host", "region"},
+				Condition: &influxql.BinaryExpr{
+					Op:  influxql.EQ,
+					LHS: &influxql.VarRef{Val: "region"},
+					RHS: &influxql.StringLiteral{Val: "uswest"},
+				},
+			},
+		},
+
+		// SHOW FIELD KEY CARDINALITY
+		{
+			s:    `SHOW FIELD KEY CARDINALITY`,
+			stmt: &influxql.ShowFieldKeyCardinalityStatement{},
+		},
+
+		// SHOW FIELD KEY CARDINALITY FROM ...
+		{
+			s: `SHOW FIELD KEY CARDINALITY FROM cpu`,
+			stmt: &influxql.ShowFieldKeyCardinalityStatement{
+				Source: &influxql.Measurement{Name: "cpu"},
+			},
+		},
+
 		// SHOW FIELD KEYS
 		{
 			s: `SHOW FIELD KEYS FROM src ORDER BY ASC, field1, field2 DESC LIMIT 10`,
@@ -463,6 +1078,33 @@ func TestParser_ParseStatement(t *testing.T) {
 			},
 		},
 
+		// CREATE USER ... WITH PASSWORD HASH
+		{
+			s: `CREATE USER testuser WITH PASSWORD HASH '$2a$10$abc'`,
+			stmt: &influxql.CreateUserStatement{
+				Name:     "testuser",
+				Password: "$2a$10$abc",
+				Hashed:   true,
+			},
+		},
+
+		// HASH is not reserved so it may be used as an identifier.
+		{
+			s: `CREATE USER hash WITH PASSWORD hash 'x'`,
+			stmt: &influxql.CreateUserStatement{
+				Name:     "hash",
+				Password: "x",
+				Hashed:   true,
+			},
+		},
+		{
+			s: `SELECT hash FROM cpu`,
+			stmt: &influxql.SelectStatement{
+				Fields: []*influxql.Field{{Expr: &influxql.VarRef{Val: "hash"}}},
+				Source: &influxql.Measurement{Name: "cpu"},
+			},
+		},
+
 		// CREATE USER ... WITH ALL PRIVILEGES
 		{
 			s: `CREATE USER testuser WITH PASSWORD 'pwd1337' WITH ALL PRIVILEGES`,
@@ -510,9 +1152,9 @@ func TestParser_ParseStatement(t *testing.T) {
 		{
 			s: `GRANT READ ON testdb TO jdoe`,
 			stmt: &influxql.GrantStatement{
-				Privilege: influxql.ReadPrivilege,
-				On:        "testdb",
-				User:      "jdoe",
+				Privileges: influxql.Privileges{influxql.ReadPrivilege},
+				On:         "testdb",
+				User:       "jdoe",
 			},
 		},
 
@@ -520,9 +1162,9 @@ func TestParser_ParseStatement(t *testing.T) {
 		{
 			s: `GRANT WRITE ON testdb TO jdoe`,
 			stmt: &influxql.GrantStatement{
-				Privilege: influxql.WritePrivilege,
-				On:        "testdb",
-				User:      "jdoe",
+				Privileges: influxql.Privileges{influxql.WritePrivilege},
+				On:         "testdb",
+				User:       "jdoe",
 			},
 		},
 
@@ -530,9 +1172,9 @@ func TestParser_ParseStatement(t *testing.T) {
 		{
 			s: `GRANT ALL ON testdb TO jdoe`,
 			stmt: &influxql.GrantStatement{
-				Privilege: influxql.AllPrivileges,
-				On:        "testdb",
-				User:      "jdoe",
+				Privileges: influxql.Privileges{influxql.AllPrivileges},
+				On:         "testdb",
+				User:       "jdoe",
 			},
 		},
 
@@ -540,9 +1182,19 @@ func TestParser_ParseStatement(t *testing.T) {
 		{
 			s: `GRANT ALL PRIVILEGES ON testdb TO jdoe`,
 			stmt: &influxql.GrantStatement{
-				Privilege: influxql.AllPrivileges,
-				On:        "testdb",
-				User:      "jdoe",
+				Privileges: influxql.Privileges{influxql.AllPrivileges},
+				On:         "testdb",
+				User:       "jdoe",
+			},
+		},
+
+		// GRANT READ, WRITE
+		{
+			s: `GRANT READ, WRITE ON testdb TO jdoe`,
+			stmt: &influxql.GrantStatement{
+				Privileges: influxql.Privileges{influxql.ReadPrivilege, influxql.WritePrivilege},
+				On:         "testdb",
+				User:       "jdoe",
 			},
 		},
 
@@ -550,8 +1202,8 @@ func TestParser_ParseStatement(t *testing.T) {
 		{
 			s: `GRANT ALL PRIVILEGES TO jdoe`,
 			stmt: &influxql.GrantStatement{
-				Privilege: influxql.AllPrivileges,
-				User:      "jdoe",
+				Privileges: influxql.Privileges{influxql.AllPrivileges},
+				User:       "jdoe",
 			},
 		},
 
@@ -559,9 +1211,9 @@ func TestParser_ParseStatement(t *testing.T) {
 		{
 			s: `REVOKE READ on testdb FROM jdoe`,
 			stmt: &influxql.RevokeStatement{
-				Privilege: influxql.ReadPrivilege,
-				On:        "testdb",
-				User:      "jdoe",
+				Privileges: influxql.Privileges{influxql.ReadPrivilege},
+				On:         "testdb",
+				User:       "jdoe",
 			},
 		},
 
@@ -569,9 +1221,9 @@ func TestParser_ParseStatement(t *testing.T) {
 		{
 			s: `REVOKE WRITE ON testdb FROM jdoe`,
 			stmt: &influxql.RevokeStatement{
-				Privilege: influxql.WritePrivilege,
-				On:        "testdb",
-				User:      "jdoe",
+				Privileges: influxql.Privileges{influxql.WritePrivilege},
+				On:         "testdb",
+				User:       "jdoe",
 			},
 		},
 
@@ -579,9 +1231,9 @@ func TestParser_ParseStatement(t *testing.T) {
 		{
 			s: `REVOKE ALL ON testdb FROM jdoe`,
 			stmt: &influxql.RevokeStatement{
-				Privilege: influxql.AllPrivileges,
-				On:        "testdb",
-				User:      "jdoe",
+				Privileges: influxql.Privileges{influxql.AllPrivileges},
+				On:         "testdb",
+				User:       "jdoe",
 			},
 		},
 
@@ -589,9 +1241,19 @@ func TestParser_ParseStatement(t *testing.T) {
 		{
 			s: `REVOKE ALL PRIVILEGES ON testdb FROM jdoe`,
 			stmt: &influxql.RevokeStatement{
-				Privilege: influxql.AllPrivileges,
-				On:        "testdb",
-				User:      "jdoe",
+				Privileges: influxql.Privileges{influxql.AllPrivileges},
+				On:         "testdb",
+				User:       "jdoe",
+			},
+		},
+
+		// REVOKE READ, WRITE
+		{
+			s: `REVOKE WRITE, READ ON testdb FROM jdoe`,
+			stmt: &influxql.RevokeStatement{
+				Privileges: influxql.Privileges{influxql.WritePrivilege, influxql.ReadPrivilege},
+				On:         "testdb",
+				User:       "jdoe",
 			},
 		},
 
@@ -599,8 +1261,8 @@ func TestParser_ParseStatement(t *testing.T) {
 		{
 			s: `REVOKE ALL FROM jdoe`,
 			stmt: &influxql.RevokeStatement{
-				Privilege: influxql.AllPrivileges,
-				User:      "jdoe",
+				Privileges: influxql.Privileges{influxql.AllPrivileges},
+				User:       "jdoe",
 			},
 		},
 
@@ -657,98 +1319,640 @@ func TestParser_ParseStatement(t *testing.T) {
 			stmt: newAlterRetentionPolicyStatement("policy1", "testdb", -1, 4, false),
 		},
 
+		// ALTER RETENTION POLICY with RENAME
+		{
+			s: `ALTER RETENTION POLICY policy1 ON testdb RENAME TO policy2`,
+			stmt: &influxql.AlterRetentionPolicyStatement{
+				Name:     "policy1",
+				Database: "testdb",
+				NewName:  "policy2",
+			},
+		},
+
+		// ALTER RETENTION POLICY with RENAME and other options
+		{
+			s: `ALTER RETENTION POLICY policy1 ON testdb RENAME TO policy2 DURATION 1m REPLICATION 4 DEFAULT`,
+			stmt: func() *influxql.AlterRetentionPolicyStatement {
+				stmt := newAlterRetentionPolicyStatement("policy1", "testdb", time.Minute, 4, true)
+				stmt.NewName = "policy2"
+				return stmt
+			}(),
+		},
+
 		// Errors
 		{s: ``, err: `found EOF, expected SELECT at line 1, char 1`},
-		{s: `SELECT`, err: `found EOF, expected identifier, string, number, bool at line 1, char 8`},
+		{s: `SELECT`, err: `found EOF, expected identifier, string, number, bool at line 1, char 7`},
 		{s: `blah blah`, err: `found blah, expected SELECT at line 1, char 1`},
+		{s: `INSERT cpu,host=a value=1`, err: `found INSERT, expected SELECT at line 1, char 1`},
 		{s: `SELECT field1 X`, err: `found X, expected FROM at line 1, char 15`},
+		{s: `SELECT value`, err: `found EOF, expected FROM at line 1, char 13`},
+		{s: `SELECT *`, err: `found EOF, expected FROM at line 1, char 9`},
+		{s: `SELECT 1 WHERE value > 1`, err: `found WHERE, expected FROM at line 1, char 10`},
 		{s: `SELECT field1 FROM "series" WHERE X +;`, err: `found ;, expected identifier, string, number, bool at line 1, char 38`},
-		{s: `SELECT field1 FROM myseries GROUP`, err: `found EOF, expected BY at line 1, char 35`},
-		{s: `SELECT field1 FROM myseries LIMIT`, err: `found EOF, expected number at line 1, char 35`},
+		{s: `SELECT field1 FROM (SELECT value FROM cpu)`, err: `subqueries are not supported at line 1, char 21`},
+		{s: `SELECT field1 FROM ()`, err: `found ), expected identifier at line 1, char 21`},
+		{s: `SELECT field1 FROM (aa, bb`, err: `found EOF, expected ) at line 1, char 27`},
+		{s: `SELECT field1 FROM (aa, 1)`, err: `found 1, expected identifier at line 1, char 25`},
+		{s: `SELECT field1 FROM myseries GROUP`, err: `found EOF, expected BY at line 1, char 34`},
+		{s: `SELECT field1 FROM myseries LIMIT`, err: `found EOF, expected number at line 1, char 34`},
 		{s: `SELECT field1 FROM myseries LIMIT 10.5`, err: `fractional parts not allowed in LIMIT at line 1, char 35`},
+		{s: `SELECT field1 FROM myseries LIMIT 1e2`, err: `exponents not allowed in LIMIT at line 1, char 35`},
 		{s: `SELECT field1 FROM myseries LIMIT 0`, err: `LIMIT must be > 0 at line 1, char 35`},
-		{s: `SELECT field1 FROM myseries OFFSET`, err: `found EOF, expected number at line 1, char 36`},
+		{s: `SELECT field1 FROM myseries OFFSET`, err: `found EOF, expected number at line 1, char 35`},
 		{s: `SELECT field1 FROM myseries OFFSET 10.5`, err: `fractional parts not allowed in OFFSET at line 1, char 36`},
-		{s: `SELECT field1 FROM myseries OFFSET 0`, err: `OFFSET must be > 0 at line 1, char 36`},
-		{s: `SELECT field1 FROM myseries ORDER`, err: `found EOF, expected BY at line 1, char 35`},
-		{s: `SELECT field1 FROM myseries ORDER BY /`, err: `found /, expected identifier, ASC, or DESC at line 1, char 38`},
-		{s: `SELECT field1 FROM myseries ORDER BY 1`, err: `found 1, expected identifier, ASC, or DESC at line 1, char 38`},
-		{s: `SELECT field1 AS`, err: `found EOF, expected identifier at line 1, char 18`},
+		{s: `SELECT field1 FROM myseries OFFSET -1`, err: `OFFSET must be >= 0 at line 1, char 36`},
+		{s: `SELECT field1 FROM myseries ORDER`, err: `found EOF, expected BY at line 1, char 34`},
+		{s: `SELECT field1 FROM myseries ORDER BY /`, err: `found /, expected identifier, ASC, DESC at line 1, char 38`},
+		{s: `SELECT field1 FROM myseries ORDER BY 1`, err: `found 1, expected identifier, ASC, DESC at line 1, char 38`},
+		{s: `SELECT field1 AS`, err: `found EOF, expected identifier at line 1, char 17`},
 		{s: `SELECT field1 FROM 12`, err: `found 12, expected identifier at line 1, char 20`},
 		{s: `SELECT 1000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000 FROM myseries`, err: `unable to parse number at line 1, char 8`},
+		{s: `SELECT value FROM myseries WHERE time > now() - 1000000000000w`, err: `duration overflow at line 1, char 49`},
 		{s: `SELECT 10.5h FROM myseries`, err: `found h, expected FROM at line 1, char 12`},
-		{s: `DELETE`, err: `found EOF, expected FROM at line 1, char 8`},
-		{s: `DELETE FROM`, err: `found EOF, expected identifier at line 1, char 13`},
-		{s: `DELETE FROM myseries WHERE`, err: `found EOF, expected identifier, string, number, bool at line 1, char 28`},
-		{s: `DROP MEASUREMENT`, err: `found EOF, expected identifier at line 1, char 18`},
-		{s: `DROP SERIES`, err: `found EOF, expected number at line 1, char 13`},
-		{s: `DROP SERIES FROM`, err: `found EOF, expected identifier at line 1, char 18`},
-		{s: `DROP SERIES FROM src WHERE`, err: `found EOF, expected identifier, string, number, bool at line 1, char 28`},
-		{s: `SHOW CONTINUOUS`, err: `found EOF, expected QUERIES at line 1, char 17`},
-		{s: `SHOW RETENTION`, err: `found EOF, expected POLICIES at line 1, char 16`},
-		{s: `SHOW RETENTION POLICIES`, err: `found EOF, expected identifier at line 1, char 25`},
+		{s: `DELETE`, err: `found EOF, expected FROM at line 1, char 7`},
+		{s: `DELETE FROM`, err: `found EOF, expected identifier at line 1, char 12`},
+		{s: `DELETE FROM myseries WHERE`, err: `found EOF, expected identifier, string, number, bool at line 1, char 27`},
+		{s: `DROP MEASUREMENT`, err: `found EOF, expected identifier at line 1, char 17`},
+		{s: `DROP SERIES`, err: `found EOF, expected FROM, WHERE, or series id at line 1, char 12`},
+		{s: `DROP SERIES cpu`, err: `found cpu, expected FROM, WHERE, or series id at line 1, char 13`},
+		{s: `DROP SERIES 1.5`, err: `strconv.ParseUint: parsing "1.5": invalid syntax at line 1, char 13`},
+		{s: `DROP SERIES FROM`, err: `found EOF, expected identifier at line 1, char 17`},
+		{s: `DROP SERIES FROM src WHERE`, err: `found EOF, expected identifier, string, number, bool at line 1, char 27`},
+		{s: `SHOW CONTINUOUS`, err: `found EOF, expected QUERIES at line 1, char 16`},
+		{s: `SHOW RETENTION`, err: `found EOF, expected POLICIES at line 1, char 15`},
+		{s: `SHOW RETENTION POLICIES ON`, err: `found EOF, expected identifier at line 1, char 27`},
 		{s: `SHOW FOO`, err: `found FOO, expected CONTINUOUS, DATABASES, FIELD, MEASUREMENTS, RETENTION, SERIES, TAG, USERS at line 1, char 6`},
-		{s: `DROP CONTINUOUS`, err: `found EOF, expected QUERY at line 1, char 17`},
-		{s: `DROP CONTINUOUS QUERY`, err: `found EOF, expected identifier at line 1, char 23`},
-		{s: `CREATE CONTINUOUS`, err: `found EOF, expected QUERY at line 1, char 19`},
-		{s: `CREATE CONTINUOUS QUERY`, err: `found EOF, expected identifier at line 1, char 25`},
+		{s: `DROP CONTINUOUS`, err: `found EOF, expected QUERY at line 1, char 16`},
+		{s: `DROP CONTINUOUS QUERY`, err: `found EOF, expected identifier at line 1, char 22`},
+		{s: `CREATE CONTINUOUS`, err: `found EOF, expected QUERY at line 1, char 18`},
+		{s: `CREATE CONTINUOUS QUERY`, err: `found EOF, expected identifier at line 1, char 24`},
+		{s: `CREATE CONTINUOUS QUERY cq ON db BEGIN SELECT count(value) INTO c FROM cpu END`, err: `found END, expected GROUP BY time(...) at line 1, char 76`},
+		{s: `CREATE CONTINUOUS QUERY cq ON db BEGIN SELECT count(value) INTO c FROM cpu`, err: `found EOF, expected GROUP BY time(...) at line 1, char 75`},
+		{s: `CREATE CONTINUOUS QUERY cq ON db BEGIN SELECT count(value) INTO c FROM cpu OFFSET 1 END`, err: `found END, expected GROUP BY time(...) at line 1, char 85`},
+		{s: `CREATE CONTINUOUS QUERY cq ON db BEGIN SELECT count(value) INTO c FROM cpu LIMIT 1, 2 END`, err: `found END, expected GROUP BY time(...) at line 1, char 87`},
+		{s: `CREATE CONTINUOUS QUERY cq ON db BEGIN SELECT count(value) INTO c FROM cpu GROUP BY time(1m, 1s) END`, err: `found END, expected GROUP BY time(...), time dimension expected one argument at line 1, char 98`},
 		{s: `DROP FOO`, err: `found FOO, expected SERIES, CONTINUOUS, MEASUREMENT at line 1, char 6`},
-		{s: `DROP DATABASE`, err: `found EOF, expected identifier at line 1, char 15`},
-		{s: `DROP RETENTION`, err: `found EOF, expected POLICY at line 1, char 16`},
-		{s: `DROP RETENTION POLICY`, err: `found EOF, expected identifier at line 1, char 23`},
-		{s: `DROP RETENTION POLICY "1h.cpu"`, err: `found EOF, expected ON at line 1, char 32`},
-		{s: `DROP RETENTION POLICY "1h.cpu" ON`, err: `found EOF, expected identifier at line 1, char 35`},
-		{s: `DROP USER`, err: `found EOF, expected identifier at line 1, char 11`},
-		{s: `CREATE USER testuser`, err: `found EOF, expected WITH at line 1, char 22`},
-		{s: `CREATE USER testuser WITH`, err: `found EOF, expected PASSWORD at line 1, char 27`},
-		{s: `CREATE USER testuser WITH PASSWORD`, err: `found EOF, expected string at line 1, char 36`},
-		{s: `CREATE USER testuser WITH PASSWORD 'pwd' WITH`, err: `found EOF, expected ALL at line 1, char 47`},
-		{s: `CREATE USER testuser WITH PASSWORD 'pwd' WITH ALL`, err: `found EOF, expected PRIVILEGES at line 1, char 51`},
-		{s: `GRANT`, err: `found EOF, expected READ, WRITE, ALL [PRIVILEGES] at line 1, char 7`},
-		{s: `GRANT BOGUS`, err: `found BOGUS, expected READ, WRITE, ALL [PRIVILEGES] at line 1, char 7`},
-		{s: `GRANT READ`, err: `found EOF, expected ON at line 1, char 12`},
+		{s: `DROP DATABASE`, err: `found EOF, expected identifier at line 1, char 14`},
+		{s: `DROP RETENTION`, err: `found EOF, expected POLICY at line 1, char 15`},
+		{s: `DROP RETENTION POLICY`, err: `found EOF, expected identifier at line 1, char 22`},
+		{s: `DROP RETENTION POLICY "1h.cpu"`, err: `found EOF, expected ON at line 1, char 31`},
+		{s: `DROP RETENTION POLICY "1h.cpu" ON`, err: `found EOF, expected identifier at line 1, char 34`},
+		{s: `DROP USER`, err: `found EOF, expected identifier at line 1, char 10`},
+		{s: `CREATE USER testuser`, err: `found EOF, expected WITH at line 1, char 21`},
+		{s: `CREATE USER testuser WITH`, err: `found EOF, expected PASSWORD at line 1, char 26`},
+		{s: `CREATE USER testuser WITH PASSWORD`, err: `found EOF, expected string at line 1, char 35`},
+		{s: `CREATE USER testuser WITH PASSWORD HASH`, err: `found EOF, expected string at line 1, char 40`},
+		{s: `CREATE USER testuser WITH PASSWORD 'pwd' WITH`, err: `found EOF, expected ALL at line 1, char 46`},
+		{s: `CREATE USER testuser WITH PASSWORD 'pwd' WITH ALL`, err: `found EOF, expected PRIVILEGES at line 1, char 50`},
+		{s: `GRANT`, err: `found EOF, expected READ, WRITE, ALL at line 1, char 6`},
+		{s: `GRANT BOGUS`, err: `found BOGUS, expected READ, WRITE, ALL at line 1, char 7`},
+		{s: `GRANT READ`, err: `found EOF, expected ON at line 1, char 11`},
 		{s: `GRANT READ TO jdoe`, err: `found TO, expected ON at line 1, char 12`},
-		{s: `GRANT READ ON`, err: `found EOF, expected identifier at line 1, char 15`},
-		{s: `GRANT READ ON testdb`, err: `found EOF, expected TO at line 1, char 22`},
-		{s: `GRANT READ ON testdb TO`, err: `found EOF, expected identifier at line 1, char 25`}, {s: `GRANT`, err: `found EOF, expected READ, WRITE, ALL [PRIVILEGES] at line 1, char 7`},
-		{s: `REVOKE BOGUS`, err: `found BOGUS, expected READ, WRITE, ALL [PRIVILEGES] at line 1, char 8`},
-		{s: `REVOKE READ`, err: `found EOF, expected ON at line 1, char 13`},
+		{s: `GRANT READ ON`, err: `found EOF, expected identifier at line 1, char 14`},
+		{s: `GRANT READ ON testdb`, err: `found EOF, expected TO at line 1, char 21`},
+		{s: `GRANT READ ON testdb TO`, err: `found EOF, expected identifier at line 1, char 24`}, {s: `GRANT`, err: `found EOF, expected READ, WRITE, ALL at line 1, char 6`},
+		{s: `GRANT READ,`, err: `found EOF, expected READ, WRITE, ALL at line 1, char 12`},
+		{s: `GRANT READ, ALL ON testdb TO jdoe`, err: `ALL PRIVILEGES cannot be combined with other privileges at line 1, char 13`},
+		{s: `GRANT ALL PRIVILEGES, READ ON testdb TO jdoe`, err: `ALL PRIVILEGES cannot be combined with other privileges at line 1, char 21`},
+		{s: `REVOKE ALL, WRITE FROM jdoe`, err: `ALL PRIVILEGES cannot be combined with other privileges at line 1, char 11`},
+		{s: `REVOKE BOGUS`, err: `found BOGUS, expected READ, WRITE, ALL at line 1, char 8`},
+		{s: `REVOKE READ`, err: `found EOF, expected ON at line 1, char 12`},
 		{s: `REVOKE READ TO jdoe`, err: `found TO, expected ON at line 1, char 13`},
-		{s: `REVOKE READ ON`, err: `found EOF, expected identifier at line 1, char 16`},
-		{s: `REVOKE READ ON testdb`, err: `found EOF, expected FROM at line 1, char 23`},
-		{s: `REVOKE READ ON testdb FROM`, err: `found EOF, expected identifier at line 1, char 28`},
-		{s: `CREATE RETENTION`, err: `found EOF, expected POLICY at line 1, char 18`},
-		{s: `CREATE RETENTION POLICY`, err: `found EOF, expected identifier at line 1, char 25`},
-		{s: `CREATE RETENTION POLICY policy1`, err: `found EOF, expected ON at line 1, char 33`},
-		{s: `CREATE RETENTION POLICY policy1 ON`, err: `found EOF, expected identifier at line 1, char 36`},
-		{s: `CREATE RETENTION POLICY policy1 ON testdb`, err: `found EOF, expected DURATION at line 1, char 43`},
-		{s: `CREATE RETENTION POLICY policy1 ON testdb DURATION`, err: `found EOF, expected duration at line 1, char 52`},
+		{s: `REVOKE READ ON`, err: `found EOF, expected identifier at line 1, char 15`},
+		{s: `REVOKE READ ON testdb`, err: `found EOF, expected FROM at line 1, char 22`},
+		{s: `REVOKE READ ON testdb FROM`, err: `found EOF, expected identifier at line 1, char 27`},
+		{s: `CREATE RETENTION`, err: `found EOF, expected POLICY at line 1, char 17`},
+		{s: `CREATE RETENTION POLICY`, err: `found EOF, expected identifier at line 1, char 24`},
+		{s: `CREATE RETENTION POLICY policy1`, err: `found EOF, expected ON at line 1, char 32`},
+		{s: `CREATE RETENTION POLICY policy1 ON`, err: `found EOF, expected identifier at line 1, char 35`},
+		{s: `CREATE RETENTION POLICY policy1 ON testdb`, err: `found EOF, expected DURATION at line 1, char 42`},
+		{s: `CREATE RETENTION POLICY policy1 ON testdb DURATION`, err: `found EOF, expected duration at line 1, char 51`},
 		{s: `CREATE RETENTION POLICY policy1 ON testdb DURATION bad`, err: `found bad, expected duration at line 1, char 52`},
 		{s: `CREATE RETENTION POLICY policy1 ON testdb DURATION 1h`, err: `found EOF, expected REPLICATION at line 1, char 54`},
-		{s: `CREATE RETENTION POLICY policy1 ON testdb DURATION 1h REPLICATION`, err: `found EOF, expected number at line 1, char 67`},
+		{s: `CREATE RETENTION POLICY policy1 ON testdb DURATION 1h REPLICATION`, err: `found EOF, expected number at line 1, char 66`},
 		{s: `CREATE RETENTION POLICY policy1 ON testdb DURATION 1h REPLICATION 3.14`, err: `number must be an integer at line 1, char 67`},
 		{s: `CREATE RETENTION POLICY policy1 ON testdb DURATION 1h REPLICATION 0`, err: `invalid value 0: must be 1 <= n <= 2147483647 at line 1, char 67`},
 		{s: `CREATE RETENTION POLICY policy1 ON testdb DURATION 1h REPLICATION bad`, err: `found bad, expected number at line 1, char 67`},
-		{s: `ALTER`, err: `found EOF, expected RETENTION at line 1, char 7`},
-		{s: `ALTER RETENTION`, err: `found EOF, expected POLICY at line 1, char 17`},
-		{s: `ALTER RETENTION POLICY`, err: `found EOF, expected identifier at line 1, char 24`},
-		{s: `ALTER RETENTION POLICY policy1`, err: `found EOF, expected ON at line 1, char 32`}, {s: `ALTER RETENTION POLICY policy1 ON`, err: `found EOF, expected identifier at line 1, char 35`},
-		{s: `ALTER RETENTION POLICY policy1 ON testdb`, err: `found EOF, expected DURATION, RETENTION, DEFAULT at line 1, char 42`},
+		{s: `ALTER`, err: `found EOF, expected RETENTION at line 1, char 6`},
+		{s: `ALTER RETENTION`, err: `found EOF, expected POLICY at line 1, char 16`},
+		{s: `ALTER RETENTION POLICY`, err: `found EOF, expected identifier at line 1, char 23`},
+		{s: `ALTER RETENTION POLICY policy1`, err: `found EOF, expected ON at line 1, char 31`}, {s: `ALTER RETENTION POLICY policy1 ON`, err: `found EOF, expected identifier at line 1, char 34`},
+		{s: `ALTER RETENTION POLICY policy1 ON testdb`, err: `found EOF, expected DURATION, REPLICATION, DEFAULT, RENAME at line 1, char 41`},
+		{s: `ALTER RETENTION POLICY policy1 ON testdb RENAME`, err: `found EOF, expected TO at line 1, char 48`},
+		{s: `ALTER RETENTION POLICY policy1 ON testdb RENAME TO`, err: `found EOF, expected identifier at line 1, char 51`},
+	}
+
+	for i, tt := range tests {
+		stmt, err := influxql.NewParser(strings.NewReader(tt.s)).ParseStatement()
+
+		if !reflect.DeepEqual(tt.err, errstring(err)) {
+			t.Errorf("%d. %q: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.s, tt.err, err)
+		} else if st, ok := stmt.(*influxql.CreateContinuousQueryStatement); ok { // if it's a CQ, there is a non-exported field that gets memoized during parsing that needs to be set
+			if st != nil && st.Source != nil {
+				tt.stmt.(*influxql.CreateContinuousQueryStatement).Source.GroupByInterval()
+			}
+		} else if tt.err == "" && !reflect.DeepEqual(tt.stmt, stmt) {
+			t.Errorf("%d. %q\n\nstmt mismatch:\n\nexp=%#v\n\ngot=%#v\n\n", i, tt.s, tt.stmt, stmt)
+		}
+	}
+}
+
+// Ensure SHOW statements accept their clauses in order and reject others.
+func TestParser_ParseStatement_ShowClauses(t *testing.T) {
+	var tests = []struct {
+		s   string
+		err string
+	}{
+		{s: `SHOW SERIES FROM cpu WHERE host = 'a' ORDER BY host ASC LIMIT 10 OFFSET 20`},
+		{s: `SHOW MEASUREMENTS WHERE host = 'a' ORDER BY host ASC LIMIT 10 OFFSET 20`},
+		{s: `SHOW TAG KEYS FROM cpu WHERE host = 'a' ORDER BY host ASC LIMIT 10 OFFSET 20`},
+		{s: `SHOW TAG VALUES FROM cpu WITH KEY = host WHERE host = 'a' ORDER BY host ASC LIMIT 10 OFFSET 20`},
+		{s: `SHOW FIELD KEYS FROM cpu ORDER BY host ASC LIMIT 10 OFFSET 20`},
+
+		{s: `SHOW SERIES FROM /cpu.*/ WHERE host = 'a' LIMIT 10`},
+		{s: `SHOW SERIES FROM /cpu WHERE host = 'a'`, err: `unterminated regex at line 1, char 18`},
+		{s: `SHOW SERIES FROM // WHERE`, err: `found EOF, expected identifier, string, number, bool at line 1, char 26`},
+		{s: `SELECT value FROM cpu WHERE host =~ /unterminated`, err: `unterminated regex at line 1, char 37`},
+		{s: `SHOW SERIES FROM /cpu(/`, err: `error parsing regexp: missing closing ): ` + "`cpu(`" + ` at line 1, char 18`},
+
+		{s: `SHOW MEASUREMENTS FROM cpu`, err: `found FROM, expected EOF at line 1, char 19`},
+		{s: `SHOW FIELD KEYS FROM cpu WHERE host = 'a'`, err: `found WHERE, expected EOF at line 1, char 26`},
+		{s: `SHOW SERIES WHERE host = 'a' FROM cpu`, err: `found FROM, expected EOF at line 1, char 30`},
+		{s: `SHOW SERIES ORDER BY host WHERE host = 'a'`, err: `found WHERE, expected EOF at line 1, char 27`},
+		{s: `SHOW TAG KEYS LIMIT 1 ORDER BY host`, err: `found ORDER, expected EOF at line 1, char 23`},
+		{s: `SHOW TAG KEYS OFFSET 1 LIMIT 1`, err: `found LIMIT, expected EOF at line 1, char 24`},
+		{s: `SHOW TAG VALUES WITH KEY = host FROM cpu`, err: `found FROM, expected EOF at line 1, char 33`},
+		{s: `SHOW TAG KEY CARDINALITY FROM cpu ON db0`, err: `found ON, expected EOF at line 1, char 35`},
+		{s: `SHOW FIELD KEY CARDINALITY LIMIT 1`, err: `found LIMIT, expected EOF at line 1, char 28`},
+		{s: `SHOW FIELD KEY`, err: `found EOF, expected CARDINALITY at line 1, char 15`},
+		{s: `SHOW FIELD VALUES`, err: `found VALUES, expected KEYS, KEY at line 1, char 12`},
+		{s: `SHOW TAG KEY FROM cpu`, err: `found FROM, expected CARDINALITY at line 1, char 14`},
+		{s: `SHOW TAG KEY CARDINALITY ON`, err: `found EOF, expected identifier at line 1, char 28`},
+		{s: `SHOW TAG VALUES CARDINALITY`, err: `found EOF, expected WITH at line 1, char 28`},
+		{s: `SHOW TAG VALUES CARDINALITY WITH KEY = host FROM cpu`, err: `found FROM, expected EOF at line 1, char 45`},
+		{s: `SHOW TAG VALUES CARDINALITY WITH KEY = host LIMIT 1`, err: `found LIMIT, expected EOF at line 1, char 45`},
+	}
+
+	for i, tt := range tests {
+		stmt, err := influxql.ParseStatement(tt.s)
+		if errstring(err) != tt.err {
+			t.Errorf("%d. %q: error mismatch:\n  exp=%s\n  got=%s", i, tt.s, tt.err, err)
+		} else if err == nil && stmt.String() != tt.s {
+			t.Errorf("%d. %q: unexpected string: %s", i, tt.s, stmt)
+		}
+	}
+}
+
+// Ensure the parser can parse the RESAMPLE clause of a continuous query.
+func TestParser_ParseStatement_Resample(t *testing.T) {
+	const src = `BEGIN SELECT count(value) INTO cpu_count FROM cpu GROUP BY time(1m) END`
+	var tests = []struct {
+		s     string
+		every time.Duration
+		for_  time.Duration
+		exp   string
+		err   string
+	}{
+		{s: `CREATE CONTINUOUS QUERY cq0 ON db0 ` + src, exp: `CREATE CONTINUOUS QUERY cq0 ON db0 BEGIN SELECT count(value) INTO cpu_count FROM cpu GROUP BY time(1m) END`},
+		{s: `CREATE CONTINUOUS QUERY cq0 ON db0 RESAMPLE EVERY 5m ` + src, every: 5 * time.Minute, exp: `CREATE CONTINUOUS QUERY cq0 ON db0 RESAMPLE EVERY 5m BEGIN SELECT count(value) INTO cpu_count FROM cpu GROUP BY time(1m) END`},
+		{s: `CREATE CONTINUOUS QUERY cq0 ON db0 RESAMPLE FOR 1h ` + src, for_: time.Hour, exp: `CREATE CONTINUOUS QUERY cq0 ON db0 RESAMPLE FOR 1h BEGIN SELECT count(value) INTO cpu_count FROM cpu GROUP BY time(1m) END`},
+		{s: `CREATE CONTINUOUS QUERY cq0 ON db0 RESAMPLE EVERY 1m FOR 30m ` + src, every: time.Minute, for_: 30 * time.Minute, exp: `CREATE CONTINUOUS QUERY cq0 ON db0 RESAMPLE EVERY 1m FOR 30m BEGIN SELECT count(value) INTO cpu_count FROM cpu GROUP BY time(1m) END`},
+		{s: `CREATE CONTINUOUS QUERY cq0 ON db0 RESAMPLE ` + src, err: `found BEGIN, expected EVERY, FOR at line 1, char 45`},
+		{s: `CREATE CONTINUOUS QUERY cq0 ON db0 RESAMPLE FOR 30m EVERY 1m ` + src, err: `found EVERY, expected BEGIN at line 1, char 53`},
+		{s: `CREATE CONTINUOUS QUERY cq0 ON db0 RESAMPLE EVERY ` + src, err: `found BEGIN, expected duration at line 1, char 51`},
+		{s: `CREATE CONTINUOUS QUERY cq0 ON db0 RESAMPLE EVERY 0s ` + src, err: `RESAMPLE EVERY duration must be positive at line 1, char 51`},
+		{s: `CREATE CONTINUOUS QUERY cq0 ON db0 RESAMPLE EVERY 1m FOR 0s ` + src, err: `RESAMPLE FOR duration must be positive at line 1, char 58`},
+	}
+
+	for i, tt := range tests {
+		stmt, err := influxql.ParseStatement(tt.s)
+		if errstring(err) != tt.err {
+			t.Errorf("%d. %q: error mismatch:\n  exp=%s\n  got=%s", i, tt.s, tt.err, err)
+			continue
+		} else if err != nil {
+			continue
+		}
+
+		cq := stmt.(*influxql.CreateContinuousQueryStatement)
+		if cq.ResampleEvery != tt.every || cq.ResampleFor != tt.for_ {
+			t.Errorf("%d. %q: unexpected durations: every=%s for=%s", i, tt.s, cq.ResampleEvery, cq.ResampleFor)
+		} else if s := cq.String(); s != tt.exp {
+			t.Errorf("%d. %q: unexpected string: %s", i, tt.s, s)
+		} else if other, err := influxql.ParseStatement(s); err != nil {
+			t.Errorf("%d. %q: cannot reparse: %s", i, tt.s, err)
+		} else if other.String() != s {
+			t.Errorf("%d. %q: round trip mismatch: %s", i, tt.s, other)
+		}
+	}
+}
+
+// Ensure aliased fields and dimensions round-trip.
+func TestSelectStatement_String_Alias(t *testing.T) {
+	for i, s := range []string{
+		`SELECT time AS ts, value FROM cpu`,
+		`SELECT mean(value) FROM cpu GROUP BY time(1m) AS t`,
+		`SELECT mean(value) AS m FROM cpu GROUP BY time(1m) AS t, host`,
+	} {
+		stmt := MustParseSelectStatement(s)
+		if other := stmt.String(); other != s {
+			t.Errorf("%d. %q: unexpected string: %s", i, s, other)
+		} else if other := stmt.Clone().String(); other != s {
+			t.Errorf("%d. %q: unexpected clone string: %s", i, s, other)
+		}
+	}
+}
+
+// Ensure LIMIT and OFFSET clauses round-trip and distinguish zero from absent.
+func TestSelectStatement_LimitOffset(t *testing.T) {
+	for i, tt := range []struct {
+		s         string
+		hasLimit  bool
+		hasOffset bool
+	}{
+		{s: `SELECT field1 FROM myseries`},
+		{s: `SELECT field1 FROM myseries LIMIT 10`, hasLimit: true},
+		{s: `SELECT field1 FROM myseries OFFSET 0`, hasOffset: true},
+		{s: `SELECT field1 FROM myseries LIMIT 10 OFFSET 0`, hasLimit: true, hasOffset: true},
+	} {
+		stmt := MustParseSelectStatement(tt.s)
+		if stmt.HasLimit != tt.hasLimit {
+			t.Errorf("%d. %q: unexpected HasLimit: %v", i, tt.s, stmt.HasLimit)
+		} else if stmt.HasOffset != tt.hasOffset {
+			t.Errorf("%d. %q: unexpected HasOffset: %v", i, tt.s, stmt.HasOffset)
+		} else if s := stmt.String(); s != tt.s {
+			t.Errorf("%d. %q: unexpected string: %s", i, tt.s, s)
+		} else if s := stmt.Clone().String(); s != tt.s {
+			t.Errorf("%d. %q: unexpected clone string: %s", i, tt.s, s)
+		}
+	}
+}
+
+// Ensure the "LIMIT <offset>, <count>" form sets both clauses.
+func TestSelectStatement_LimitComma(t *testing.T) {
+	for i, tt := range []struct {
+		s   string
+		exp string
+		err string
+	}{
+		{s: `SELECT field1 FROM myseries LIMIT 10, 20`, exp: `SELECT field1 FROM myseries LIMIT 20 OFFSET 10`},
+		{s: `SELECT field1 FROM myseries LIMIT 0,20`, exp: `SELECT field1 FROM myseries LIMIT 20 OFFSET 0`},
+		{s: `SELECT field1 FROM myseries LIMIT 10,`, err: `found EOF, expected number at line 1, char 38`},
+		{s: `SELECT field1 FROM myseries LIMIT 10, 0`, err: `LIMIT must be > 0 at line 1, char 39`},
+		{s: `SELECT field1 FROM myseries LIMIT 1.5, 20`, err: `fractional parts not allowed in OFFSET at line 1, char 35`},
+		{s: `SELECT field1 FROM myseries LIMIT 10, 20 OFFSET 5`, err: `OFFSET cannot be combined with LIMIT <offset>, <count> at line 1, char 42`},
+	} {
+		stmt, err := influxql.ParseStatement(tt.s)
+		if errstring(err) != tt.err {
+			t.Errorf("%d. %q: error mismatch:\n  exp=%s\n  got=%s", i, tt.s, tt.err, err)
+		} else if err == nil && stmt.String() != tt.exp {
+			t.Errorf("%d. %q: unexpected string: %s", i, tt.s, stmt)
+		}
+	}
+}
+
+// Ensure a continuous query without an interval reports where it was expected.
+func TestParser_ParseStatement_ContinuousQueryInterval(t *testing.T) {
+	_, err := influxql.ParseStatement(`CREATE CONTINUOUS QUERY cq ON db BEGIN SELECT count(value) INTO c FROM cpu END`)
+
+	var perr *influxql.ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("expected parse error, got: %v", err)
+	} else if perr.Found != "END" || !reflect.DeepEqual(perr.Expected, []string{"GROUP BY time(...)"}) {
+		t.Fatalf("unexpected error: found=%q expected=%q", perr.Found, perr.Expected)
+	} else if perr.Pos != (influxql.Pos{Line: 0, Char: 75}) {
+		t.Fatalf("unexpected position: %#v", perr.Pos)
+	}
+}
+
+// Ensure "&&" and "||" are only accepted when operator aliases are enabled.
+func TestParser_ParseExpr_OperatorAliases(t *testing.T) {
+	for i, tt := range []struct {
+		s       string
+		aliases bool
+		expr    string
+		err     string
+	}{
+		{s: `a = 1 && b = 2 || c = 3`, aliases: true, expr: `a = 1.000 AND b = 2.000 OR c = 3.000`},
+		{s: `a || b && c`, aliases: true, expr: `a OR b AND c`},
+		{s: `a AND b`, aliases: true, expr: `a AND b`},
+		{s: `a & b`, aliases: true, expr: `a`},
+		{s: `a && b`, expr: `a`},
+		{s: `a || b`, expr: `a`},
+	} {
+		p := influxql.NewParserWithOptions(strings.NewReader(tt.s), influxql.ParserOptions{OperatorAliases: tt.aliases})
+		expr, err := p.ParseExpr()
+		if errstring(err) != tt.err {
+			t.Errorf("%d. %q: error mismatch:\n  exp=%s\n  got=%s", i, tt.s, tt.err, err)
+		} else if err == nil && expr.String() != tt.expr {
+			t.Errorf("%d. %q: unexpected expr: %s", i, tt.s, expr)
+		}
+	}
+
+	// Aliases bind with the same precedence as the keywords they replace.
+	p := influxql.NewParserWithOptions(strings.NewReader(`a || b && c`), influxql.ParserOptions{OperatorAliases: true})
+	if expr, err := p.ParseExpr(); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(expr, influxql.MustParseExpr(`a OR b AND c`)) {
+		t.Fatalf("unexpected expr: %#v", expr)
+	}
+
+	// Without aliases the remaining tokens are rejected by the statement.
+	_, err := influxql.ParseStatement(`SELECT a FROM b WHERE a = 1 && b = 2`)
+	if errstring(err) != `found &, expected EOF at line 1, char 29` {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+// Ensure time literals without an offset use the configured location.
+func TestParser_ParseExpr_Location(t *testing.T) {
+	loc := time.FixedZone("EST", -5*60*60)
+	for i, tt := range []struct {
+		s    string
+		loc  *time.Location
+		expr string
+	}{
+		{s: `'2000-01-01 00:00:00'`, expr: `'2000-01-01T00:00:00Z'`},
+		{s: `'2000-01-01 00:00:00'`, loc: loc, expr: `'2000-01-01T05:00:00Z'`},
+		{s: `'2000-01-01'`, loc: loc, expr: `'2000-01-01T05:00:00Z'`},
+		{s: `'2000-01-01T00:00:00Z'`, loc: loc, expr: `'2000-01-01T00:00:00Z'`},
+		{s: `'2000-01-01T00:00:00+01:00'`, loc: loc, expr: `'1999-12-31T23:00:00Z'`},
+		{s: `'2000-01-01 00:00:00Z'`, loc: loc, expr: `'2000-01-01T00:00:00Z'`},
+		{s: `'2000-01-01 00:00:00 UTC'`, loc: loc, expr: `'2000-01-01T00:00:00Z'`},
+		{s: `'2000-01-01 00:00:00.5UTC'`, loc: loc, expr: `'2000-01-01T00:00:00.5Z'`},
+	} {
+		p := influxql.NewParserWithOptions(strings.NewReader(tt.s), influxql.ParserOptions{Location: tt.loc})
+		if expr, err := p.ParseExpr(); err != nil {
+			t.Errorf("%d. %q: unexpected error: %s", i, tt.s, err)
+		} else if expr.String() != tt.expr {
+			t.Errorf("%d. %q: unexpected expr: %s", i, tt.s, expr)
+		}
+	}
+}
+
+// Ensure time literals keep nanosecond precision and print it back.
+func TestParser_ParseExpr_TimeLiteralPrecision(t *testing.T) {
+	for i, tt := range []struct {
+		s    string
+		nsec int
+		expr string
+	}{
+		{s: `'2015-01-01 12:00:00.123456789'`, nsec: 123456789, expr: `'2015-01-01T12:00:00.123456789Z'`},
+		{s: `'2015-01-01 12:00:00.000000001Z'`, nsec: 1, expr: `'2015-01-01T12:00:00.000000001Z'`},
+		{s: `'2015-01-01 12:00:00.123456789 UTC'`, nsec: 123456789, expr: `'2015-01-01T12:00:00.123456789Z'`},
+		{s: `'2015-01-01 12:00:00.1'`, nsec: 100000000, expr: `'2015-01-01T12:00:00.1Z'`},
+		{s: `'2015-01-01T12:00:00.123456789Z'`, nsec: 123456789, expr: `'2015-01-01T12:00:00.123456789Z'`},
+	} {
+		expr, err := influxql.ParseExpr(tt.s)
+		if err != nil {
+			t.Errorf("%d. %q: unexpected error: %s", i, tt.s, err)
+			continue
+		}
+		lit := expr.(*influxql.TimeLiteral)
+		if lit.Val.Nanosecond() != tt.nsec {
+			t.Errorf("%d. %q: unexpected nanoseconds: %d", i, tt.s, lit.Val.Nanosecond())
+		} else if lit.String() != tt.expr {
+			t.Errorf("%d. %q: unexpected expr: %s", i, tt.s, lit)
+		} else if other := influxql.MustParseExpr(lit.String()); !reflect.DeepEqual(other, expr) {
+			t.Errorf("%d. %q: round trip mismatch: %s", i, tt.s, other)
+		}
+	}
+}
+
+// Ensure deeply nested expressions return an error instead of exhausting the stack.
+func TestParser_ParseExpr_MaxDepth(t *testing.T) {
+	n := 100000
+	s := strings.Repeat("(", n) + "1" + strings.Repeat(")", n)
+	if _, err := influxql.ParseExpr(s); errstring(err) != `expression nested more than 1000 levels deep at line 1, char 1001` {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	s = strings.Repeat("f(", n) + "1" + strings.Repeat(")", n)
+	if _, err := influxql.ParseExpr(s); errstring(err) != `expression nested more than 1000 levels deep at line 1, char 2002` {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Expressions within the limit are parsed.
+	s = strings.Repeat("(", 10) + "1" + strings.Repeat(")", 10)
+	p := influxql.NewParserWithOptions(strings.NewReader(s), influxql.ParserOptions{MaxExprDepth: 10})
+	if _, err := p.ParseExpr(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	s = strings.Repeat("(", 11) + "1" + strings.Repeat(")", 11)
+	p = influxql.NewParserWithOptions(strings.NewReader(s), influxql.ParserOptions{MaxExprDepth: 10})
+	if _, err := p.ParseExpr(); errstring(err) != `expression nested more than 10 levels deep at line 1, char 11` {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+// Ensure oversized regex patterns are rejected before they are compiled.
+func TestParser_ParseExpr_MaxRegexLength(t *testing.T) {
+	for i, tt := range []struct {
+		s   string
+		err string
+	}{
+		{s: `host =~ /` + strings.Repeat("a", 10) + `/`},
+		{s: `host =~ /` + strings.Repeat("a", 11) + `/`, err: `regex pattern exceeds 10 bytes at line 1, char 9`},
+		{s: `host =~ '` + strings.Repeat("a", 11) + `'`, err: `regex pattern exceeds 10 bytes at line 1, char 6`},
+	} {
+		p := influxql.NewParserWithOptions(strings.NewReader(tt.s), influxql.ParserOptions{MaxRegexLength: 10})
+		if _, err := p.ParseExpr(); errstring(err) != tt.err {
+			t.Errorf("%d. %q: error mismatch:\n  exp=%s\n  got=%s", i, tt.s, tt.err, err)
+		}
+	}
+
+	// The default limit applies when no option is set.
+	s := `host =~ /` + strings.Repeat("a", influxql.DefaultMaxRegexLength+1) + `/`
+	if _, err := influxql.ParseExpr(s); errstring(err) != `regex pattern exceeds 4096 bytes at line 1, char 9` {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+// Ensure bracketed lists are only accepted when list literals are enabled.
+func TestParser_ParseExpr_ListLiterals(t *testing.T) {
+	for i, tt := range []struct {
+		s     string
+		lists bool
+		expr  string
+		err   string
+	}{
+		{s: `f(value, [1, 2, 3])`, lists: true, expr: `f(value, [1.000, 2.000, 3.000])`},
+		{s: `f(['a','b'])`, lists: true, expr: `f(['a', 'b'])`},
+		{s: `f([])`, lists: true, expr: `f([])`},
+		{s: `f([-1, 2h])`, lists: true, expr: `f([-1.000, 2h])`},
+		{s: `[1, 2`, lists: true, err: `found EOF, expected ,, ] at line 1, char 6`},
+		{s: `f([1 2])`, lists: true, err: `found 2, expected ,, ] at line 1, char 6`},
+		{s: `f([1,])`, lists: true, err: `found ], expected identifier, string, number, bool at line 1, char 6`},
+		{s: `f([1, 2])`, err: `found [, expected identifier, string, number, bool at line 1, char 3`},
+	} {
+		p := influxql.NewParserWithOptions(strings.NewReader(tt.s), influxql.ParserOptions{ListLiterals: tt.lists})
+		expr, err := p.ParseExpr()
+		if errstring(err) != tt.err {
+			t.Errorf("%d. %q: error mismatch:\n  exp=%s\n  got=%s", i, tt.s, tt.err, err)
+		} else if err == nil && expr.String() != tt.expr {
+			t.Errorf("%d. %q: unexpected expr: %s", i, tt.s, expr)
+		}
+	}
+
+	// The string representation parses back to the same list.
+	opts := influxql.ParserOptions{ListLiterals: true}
+	expr, err := influxql.NewParserWithOptions(strings.NewReader(`f(value, [1, 2])`), opts).ParseExpr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := influxql.NewParserWithOptions(strings.NewReader(expr.String()), opts).ParseExpr()
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(expr, other) {
+		t.Fatalf("unexpected round trip: %s", other)
+	}
+}
+
+// Ensure OFFSET without LIMIT is only rejected when the option is enabled.
+func TestParser_ParseStatement_RequireLimitWithOffset(t *testing.T) {
+	for i, tt := range []struct {
+		s       string
+		require bool
+		err     string
+	}{
+		{s: `SELECT value FROM cpu LIMIT 10 OFFSET 20`, require: true},
+		{s: `SELECT value FROM cpu OFFSET 20`, require: true, err: `OFFSET requires LIMIT at line 1, char 23`},
+		{s: `SELECT value FROM cpu OFFSET 0`, require: true, err: `OFFSET requires LIMIT at line 1, char 23`},
+		{s: `SELECT value FROM cpu`, require: true},
+		{s: `SELECT value FROM cpu LIMIT 10`, require: true},
+		{s: `SELECT value FROM cpu OFFSET 20`},
+	} {
+		p := influxql.NewParserWithOptions(strings.NewReader(tt.s), influxql.ParserOptions{RequireLimitWithOffset: tt.require})
+		if _, err := p.ParseStatement(); errstring(err) != tt.err {
+			t.Errorf("%d. %q: error mismatch:\n  exp=%s\n  got=%s", i, tt.s, tt.err, err)
+		}
+	}
+}
+
+// Ensure single-quoted fields are only rejected when the option is enabled.
+func TestParser_ParseStatement_RejectStringFields(t *testing.T) {
+	for i, tt := range []struct {
+		s      string
+		reject bool
+		err    string
+	}{
+		{s: `SELECT 'value' FROM cpu`},
+		{s: `SELECT 'value' FROM cpu`, reject: true, err: `found string 'value', expected field (identifiers are double quoted) at line 1, char 8`},
+		{s: `SELECT value, ('host') FROM cpu`, reject: true, err: `found string ('host'), expected field (identifiers are double quoted) at line 1, char 15`},
+		{s: `SELECT "value" FROM cpu WHERE host = 'a'`, reject: true},
+		{s: `SELECT value + 'a' FROM cpu`, reject: true},
+	} {
+		p := influxql.NewParserWithOptions(strings.NewReader(tt.s), influxql.ParserOptions{RejectStringFields: tt.reject})
+		if _, err := p.ParseStatement(); errstring(err) != tt.err {
+			t.Errorf("%d. %q: error mismatch:\n  exp=%s\n  got=%s", i, tt.s, tt.err, err)
+		}
+	}
+}
+
+// Ensure variable references can be qualified by their measurement.
+func TestParser_ParseExpr_QualifiedVarRef(t *testing.T) {
+	for i, tt := range []struct {
+		s        string
+		segments []string
+		err      string
+	}{
+		{s: `value`, segments: []string{"value"}},
+		{s: `cpu.value`, segments: []string{"cpu", "value"}},
+		{s: `"cpu"."value"`, segments: []string{"cpu", "value"}},
+		{s: `"a.b".c`, segments: []string{"a.b", "c"}},
+		{s: `rp.cpu.value::float`, segments: []string{"rp", "cpu", "value"}},
+		{s: `db.rp.cpu.value`, segments: []string{"db", "rp", "cpu", "value"}},
+		{s: `db.rp.cpu.value.x`, err: `identifier db.rp.cpu.value.x has 5 segments, expected at most 4 at line 1, char 1`},
+		{s: `1 + a.b.c.d.e.f`, err: `identifier a.b.c.d.e.f has 6 segments, expected at most 4 at line 1, char 5`},
+	} {
+		expr, err := influxql.ParseExpr(tt.s)
+		if errstring(err) != tt.err {
+			t.Errorf("%d. %q: error mismatch:\n  exp=%s\n  got=%s", i, tt.s, tt.err, err)
+		} else if err == nil && !reflect.DeepEqual(expr.(*influxql.VarRef).Segments(), tt.segments) {
+			t.Errorf("%d. %q: unexpected segments: %q", i, tt.s, expr.(*influxql.VarRef).Segments())
+		}
+	}
+
+	// A qualified name followed by a paren is a call.
+	if expr := influxql.MustParseExpr(`a.b(1)`); !reflect.DeepEqual(expr, &influxql.Call{Name: "a.b", Args: []influxql.Expr{&influxql.NumberLiteral{Val: 1}}}) {
+		t.Fatalf("unexpected expr: %#v", expr)
+	}
+
+	// Quoted segments round trip through QuoteIdent.
+	segments := []string{"my.db", "cpu", `va"lue`}
+	ref := &influxql.VarRef{Val: influxql.QuoteIdent(segments)}
+	if other, err := influxql.ParseExpr(`x > 0 AND ` + ref.String()); err != nil {
+		t.Fatal(err)
+	} else if a := other.(*influxql.BinaryExpr).RHS.(*influxql.VarRef).Segments(); !reflect.DeepEqual(a, segments) {
+		t.Fatalf("unexpected segments: %q", a)
+	}
+
+	// Qualified references are walked and named like any other reference.
+	stmt := MustParseSelectStatement(`SELECT cpu.value, mem.value FROM join(cpu, mem) WHERE cpu.value > 0.5`)
+	if names := stmt.NamesInSelect(); !reflect.DeepEqual(names, []string{"cpu.value", "mem.value"}) {
+		t.Fatalf("unexpected names: %q", names)
+	}
+	var refs []string
+	influxql.WalkFunc(stmt.Condition, func(n influxql.Node) {
+		if ref, ok := n.(*influxql.VarRef); ok {
+			refs = append(refs, ref.Val)
+		}
+	})
+	if !reflect.DeepEqual(refs, []string{"cpu.value"}) {
+		t.Fatalf("unexpected refs: %q", refs)
+	}
+}
+
+// Ensure variable references can carry a type hint.
+func TestParser_ParseExpr_VarRefType(t *testing.T) {
+	for i, tt := range []struct {
+		s    string
+		expr influxql.Expr
+		err  string
+	}{
+		{s: `value::float`, expr: &influxql.VarRef{Val: "value", Type: influxql.Float}},
+		{s: `value::integer`, expr: &influxql.VarRef{Val: "value", Type: influxql.Integer}},
+		{s: `value::string`, expr: &influxql.VarRef{Val: "value", Type: influxql.String}},
+		{s: `value::boolean`, expr: &influxql.VarRef{Val: "value", Type: influxql.Boolean}},
+		{s: `host::tag`, expr: &influxql.VarRef{Val: "host", Type: influxql.Tag}},
+		{s: `value::field`, expr: &influxql.VarRef{Val: "value", Type: influxql.AnyField}},
+		{s: `value::FLOAT`, expr: &influxql.VarRef{Val: "value", Type: influxql.Float}},
+		{s: `value::unsigned`, err: `invalid type unsigned at line 1, char 8`},
+		{s: `value::`, err: `found EOF, expected float, integer, string, boolean, tag, field at line 1, char 8`},
+		{s: `value:float`, expr: &influxql.VarRef{Val: "value"}},
+
+		// CAST is another spelling of a type hint.
+		{s: `CAST(a AS integer)`, expr: &influxql.VarRef{Val: "a", Type: influxql.Integer}},
+		{s: `cast( cpu.value as FLOAT )`, expr: &influxql.VarRef{Val: "cpu.value", Type: influxql.Float}},
+		{s: `CAST(host AS tag)`, expr: &influxql.VarRef{Val: "host", Type: influxql.Tag}},
+		{s: `CAST(a AS unsigned)`, err: `invalid type unsigned at line 1, char 11`},
+		{s: `CAST(a integer)`, err: `found integer, expected AS at line 1, char 8`},
+		{s: `CAST(a AS integer`, err: `found EOF, expected ) at line 1, char 18`},
+		{s: `CAST(1 AS integer)`, err: `found 1, expected identifier at line 1, char 6`},
+		{s: `CAST(a::float AS integer)`, err: `cannot cast a::float which already has a type at line 1, char 6`},
+	} {
+		expr, err := influxql.ParseExpr(tt.s)
+		if errstring(err) != tt.err {
+			t.Errorf("%d. %q: error mismatch:\n  exp=%s\n  got=%s", i, tt.s, tt.err, err)
+		} else if tt.err == "" && !reflect.DeepEqual(tt.expr, expr) {
+			t.Errorf("%d. %q: expr mismatch:\n  exp=%#v\n  got=%#v", i, tt.s, tt.expr, expr)
+		}
 	}
 
-	for i, tt := range tests {
-		stmt, err := influxql.NewParser(strings.NewReader(tt.s)).ParseStatement()
-
-		if !reflect.DeepEqual(tt.err, errstring(err)) {
-			t.Errorf("%d. %q: error mismatch:\n  exp=%s\n  got=%s\n\n", i, tt.s, tt.err, err)
-		} else if st, ok := stmt.(*influxql.CreateContinuousQueryStatement); ok { // if it's a CQ, there is a non-exported field that gets memoized during parsing that needs to be set
-			if st != nil && st.Source != nil {
-				tt.stmt.(*influxql.CreateContinuousQueryStatement).Source.GroupByInterval()
-			}
-		} else if tt.err == "" && !reflect.DeepEqual(tt.stmt, stmt) {
-			t.Errorf("%d. %q\n\nstmt mismatch:\n\nexp=%#v\n\ngot=%#v\n\n", i, tt.s, tt.stmt, stmt)
+	// Type hints round-trip.
+	for i, s := range []string{
+		`SELECT value::float, host::tag FROM cpu`,
+		`SELECT mean(value::integer) FROM cpu`,
+	} {
+		if other := MustParseSelectStatement(s).String(); other != s {
+			t.Errorf("%d. %q: unexpected string: %s", i, s, other)
 		}
 	}
+
+	// CAST is written with the "::" suffix.
+	if s := MustParseSelectStatement(`SELECT mean(CAST(value AS integer)) FROM cpu`).String(); s != `SELECT mean(value::integer) FROM cpu` {
+		t.Errorf("unexpected string: %s", s)
+	}
 }
 
 // Ensure the parser can parse expressions into an AST.
@@ -760,6 +1964,9 @@ func TestParser_ParseExpr(t *testing.T) {
 	}{
 		// Primitives
 		{s: `100`, expr: &influxql.NumberLiteral{Val: 100}},
+		{s: `1.5e3`, expr: &influxql.NumberLiteral{Val: 1500}},
+		{s: `NaN`, expr: &influxql.VarRef{Val: "NaN"}},
+		{s: `Inf`, expr: &influxql.VarRef{Val: "Inf"}},
 		{s: `'foo bar'`, expr: &influxql.StringLiteral{Val: "foo bar"}},
 		{s: `true`, expr: &influxql.BooleanLiteral{Val: true}},
 		{s: `false`, expr: &influxql.BooleanLiteral{Val: false}},
@@ -858,6 +2065,26 @@ func TestParser_ParseExpr(t *testing.T) {
 			},
 		},
 
+		// Binary expression with regex literal.
+		{
+			s: `host =~ /a\/b/`,
+			expr: &influxql.BinaryExpr{
+				Op:  influxql.EQREGEX,
+				LHS: &influxql.VarRef{Val: "host"},
+				RHS: &influxql.RegexLiteral{Val: regexp.MustCompile(`a/b`)},
+			},
+		},
+
+		// Binary expression with regex literal using inline flags.
+		{
+			s: `host !~/(?i)x/`,
+			expr: &influxql.BinaryExpr{
+				Op:  influxql.NEQREGEX,
+				LHS: &influxql.VarRef{Val: "host"},
+				RHS: &influxql.RegexLiteral{Val: regexp.MustCompile(`(?i)x`)},
+			},
+		},
+
 		// Complex binary expression.
 		{
 			s: `value + 3 < 30 AND 1 + 2 OR true`,
@@ -884,6 +2111,100 @@ func TestParser_ParseExpr(t *testing.T) {
 			},
 		},
 
+		// AND binds more tightly than OR.
+		{
+			s: `a OR b AND c`,
+			expr: &influxql.BinaryExpr{
+				Op:  influxql.OR,
+				LHS: &influxql.VarRef{Val: "a"},
+				RHS: &influxql.BinaryExpr{
+					Op:  influxql.AND,
+					LHS: &influxql.VarRef{Val: "b"},
+					RHS: &influxql.VarRef{Val: "c"},
+				},
+			},
+		},
+
+		// Multiplication binds more tightly than addition.
+		{
+			s: `a + b * c`,
+			expr: &influxql.BinaryExpr{
+				Op:  influxql.ADD,
+				LHS: &influxql.VarRef{Val: "a"},
+				RHS: &influxql.BinaryExpr{
+					Op:  influxql.MUL,
+					LHS: &influxql.VarRef{Val: "b"},
+					RHS: &influxql.VarRef{Val: "c"},
+				},
+			},
+		},
+
+		// Arithmetic binds more tightly than comparison.
+		{
+			s: `a < b - c`,
+			expr: &influxql.BinaryExpr{
+				Op:  influxql.LT,
+				LHS: &influxql.VarRef{Val: "a"},
+				RHS: &influxql.BinaryExpr{
+					Op:  influxql.SUB,
+					LHS: &influxql.VarRef{Val: "b"},
+					RHS: &influxql.VarRef{Val: "c"},
+				},
+			},
+		},
+
+		// Precedence is resolved across more than two levels.
+		{
+			s: `a OR b AND c = d + e * f`,
+			expr: &influxql.BinaryExpr{
+				Op:  influxql.OR,
+				LHS: &influxql.VarRef{Val: "a"},
+				RHS: &influxql.BinaryExpr{
+					Op:  influxql.AND,
+					LHS: &influxql.VarRef{Val: "b"},
+					RHS: &influxql.BinaryExpr{
+						Op:  influxql.EQ,
+						LHS: &influxql.VarRef{Val: "c"},
+						RHS: &influxql.BinaryExpr{
+							Op:  influxql.ADD,
+							LHS: &influxql.VarRef{Val: "d"},
+							RHS: &influxql.BinaryExpr{
+								Op:  influxql.MUL,
+								LHS: &influxql.VarRef{Val: "e"},
+								RHS: &influxql.VarRef{Val: "f"},
+							},
+						},
+					},
+				},
+			},
+		},
+
+		// Regex operators have comparison precedence.
+		{
+			s: `a = 1 AND b =~ 'x.*' OR c !~ 'y'`,
+			expr: &influxql.BinaryExpr{
+				Op: influxql.OR,
+				LHS: &influxql.BinaryExpr{
+					Op: influxql.AND,
+					LHS: &influxql.BinaryExpr{
+						Op:  influxql.EQ,
+						LHS: &influxql.VarRef{Val: "a"},
+						RHS: &influxql.NumberLiteral{Val: 1},
+					},
+					RHS: &influxql.BinaryExpr{
+						Op:  influxql.EQREGEX,
+						LHS: &influxql.VarRef{Val: "b"},
+						RHS: &influxql.RegexLiteral{Val: regexp.MustCompile(`x.*`)},
+					},
+				},
+				RHS: &influxql.BinaryExpr{
+					Op:  influxql.NEQREGEX,
+					LHS: &influxql.VarRef{Val: "c"},
+					RHS: &influxql.RegexLiteral{Val: regexp.MustCompile(`y`)},
+				},
+			},
+		},
+
 		// Function call (empty)
 		{
 			s: `my_func()`,
@@ -907,6 +2228,42 @@ func TestParser_ParseExpr(t *testing.T) {
 				},
 			},
 		},
+
+		// LIKE operators have comparison precedence.
+		{
+			s: `host LIKE 'web%' AND region not like 'us\\_%'`,
+			expr: &influxql.BinaryExpr{
+				Op: influxql.AND,
+				LHS: &influxql.BinaryExpr{
+					Op:  influxql.LIKE,
+					LHS: &influxql.VarRef{Val: "host"},
+					RHS: &influxql.StringLiteral{Val: "web%"},
+				},
+				RHS: &influxql.BinaryExpr{
+					Op:  influxql.NLIKE,
+					LHS: &influxql.VarRef{Val: "region"},
+					RHS: &influxql.StringLiteral{Val: `us\_%`},
+				},
+			},
+		},
+
+		// LIKE errors
+		{s: `host NOT 'web%'`, err: `found web%, expected LIKE at line 1, char 10`},
+		{s: `host LIKE 'web\\'`, err: `invalid LIKE pattern: trailing backslash at line 1, char 6`},
+
+		// Number errors
+		{s: `1e400`, err: `unable to parse number at line 1, char 1`},
+		{s: `value > -1e400`, err: `unable to parse number at line 1, char 9`},
+
+		// Regex errors
+		{s: `host =~ /(/`, err: "error parsing regexp: missing closing ): `(` at line 1, char 9"},
+		{s: `host =~ /abc`, err: `unterminated regex at line 1, char 9`},
+		{s: `host =~ /abc\`, err: `unterminated regex at line 1, char 9`},
+		{s: "host =~ /abc\n/", err: `unterminated regex at line 1, char 9`},
+		{s: `host =~ /abc AND region = 'a'`, err: `unterminated regex at line 1, char 9`},
+		{s: `host =~ // AND`, err: `found EOF, expected identifier, string, number, bool at line 1, char 15`},
+		{s: `host =~ '(' `, err: "error parsing regexp: missing closing ): `(` at line 1, char 6"},
+		{s: `1 =~ /x/`, err: `left operand of operator =~ must be an identifier at line 1, char 3`},
 	}
 
 	for i, tt := range tests {
@@ -919,6 +2276,50 @@ func TestParser_ParseExpr(t *testing.T) {
 	}
 }
 
+// Ensure RFC3339 time literals keep their offset and round-trip to the same instant.
+func TestParser_ParseExpr_TimeLiteral(t *testing.T) {
+	for i, tt := range []struct {
+		s      string
+		t      time.Time
+		offset int
+	}{
+		{s: `'2015-01-01T00:00:00Z'`, t: mustParseTime("2015-01-01T00:00:00Z"), offset: 0},
+		{s: `'2015-01-01T00:00:00+02:00'`, t: mustParseTime("2014-12-31T22:00:00Z"), offset: 2 * 60 * 60},
+		{s: `'2015-01-01T00:00:00.123456789-07:30'`, t: mustParseTime("2015-01-01T07:30:00.123456789Z"), offset: -(7*60 + 30) * 60},
+
+		// US daylight saving time starts: 02:00 EST does not exist locally but the offset is explicit.
+		{s: `'2015-03-08T02:30:00-05:00'`, t: mustParseTime("2015-03-08T07:30:00Z"), offset: -5 * 60 * 60},
+		{s: `'2015-03-08T03:30:00-04:00'`, t: mustParseTime("2015-03-08T07:30:00Z"), offset: -4 * 60 * 60},
+
+		// US daylight saving time ends: 01:30 occurs twice locally and the offset disambiguates.
+		{s: `'2015-11-01T01:30:00-04:00'`, t: mustParseTime("2015-11-01T05:30:00Z"), offset: -4 * 60 * 60},
+		{s: `'2015-11-01T01:30:00-05:00'`, t: mustParseTime("2015-11-01T06:30:00Z"), offset: -5 * 60 * 60},
+	} {
+		expr, err := influxql.ParseExpr(tt.s)
+		if err != nil {
+			t.Errorf("%d. %s: unexpected error: %s", i, tt.s, err)
+			continue
+		}
+
+		lit, ok := expr.(*influxql.TimeLiteral)
+		if !ok {
+			t.Errorf("%d. %s: unexpected expr: %#v", i, tt.s, expr)
+			continue
+		} else if !lit.Val.Equal(tt.t) {
+			t.Errorf("%d. %s: time mismatch: exp=%s, got=%s", i, tt.s, tt.t, lit.Val)
+		} else if _, offset := lit.Val.Zone(); offset != tt.offset {
+			t.Errorf("%d. %s: offset mismatch: exp=%d, got=%d", i, tt.s, tt.offset, offset)
+		}
+
+		// Verify the string form parses back to the same instant.
+		if other, err := influxql.ParseExpr(lit.String()); err != nil {
+			t.Errorf("%d. %s: round trip error: %s", i, tt.s, err)
+		} else if other, ok := other.(*influxql.TimeLiteral); !ok || !other.Val.Equal(lit.Val) {
+			t.Errorf("%d. %s: round trip mismatch: %s", i, tt.s, lit.String())
+		}
+	}
+}
+
 // Ensure a time duration can be parsed.
 func TestParseDuration(t *testing.T) {
 	var tests = []struct {
@@ -936,11 +2337,75 @@ func TestParseDuration(t *testing.T) {
 		{s: `2h`, d: 2 * time.Hour},
 		{s: `2d`, d: 2 * 24 * time.Hour},
 		{s: `2w`, d: 2 * 7 * 24 * time.Hour},
+		{s: `6mo`, d: 6 * 30 * 24 * time.Hour},
+		{s: `1y`, d: 365 * 24 * time.Hour},
+		{s: `1m`, d: time.Minute},
+		{s: `1mo`, d: 30 * 24 * time.Hour},
+
+		// Whitespace and mixed-case units.
+		{s: `1 h`, d: time.Hour},
+		{s: `30 M`, d: 30 * time.Minute},
+		{s: `5  m`, d: 5 * time.Minute},
+		{s: "5\tMS", d: 5 * time.Millisecond},
+		{s: ` 2Mo `, d: 2 * 30 * 24 * time.Hour},
+		{s: `2MO`, d: 2 * 30 * 24 * time.Hour},
+		{s: `3 Y`, d: 3 * 365 * 24 * time.Hour},
+		{s: `10 U`, d: 10 * time.Microsecond},
+		{s: ` 7 `, d: 7 * time.Microsecond},
+		{s: `-5 s`, d: -5 * time.Second},
+
+		// Largest valid duration for each unit.
+		{s: `9223372036854775`, d: 9223372036854775 * time.Microsecond},
+		{s: `9223372036854775u`, d: 9223372036854775 * time.Microsecond},
+		{s: `9223372036854ms`, d: 9223372036854 * time.Millisecond},
+		{s: `9223372036s`, d: 9223372036 * time.Second},
+		{s: `153722867m`, d: 153722867 * time.Minute},
+		{s: `2562047h`, d: 2562047 * time.Hour},
+		{s: `106751d`, d: 106751 * 24 * time.Hour},
+		{s: `15250w`, d: 15250 * 7 * 24 * time.Hour},
+		{s: `3558mo`, d: 3558 * 30 * 24 * time.Hour},
+		{s: `292y`, d: 292 * 365 * 24 * time.Hour},
+
+		// First overflowing duration for each unit.
+		{s: `9223372036854776`, err: "duration overflow"},
+		{s: `9223372036854776u`, err: "duration overflow"},
+		{s: `9223372036855ms`, err: "duration overflow"},
+		{s: `9223372037s`, err: "duration overflow"},
+		{s: `153722868m`, err: "duration overflow"},
+		{s: `2562048h`, err: "duration overflow"},
+		{s: `106752d`, err: "duration overflow"},
+		{s: `15251w`, err: "duration overflow"},
+		{s: `3559mo`, err: "duration overflow"},
+		{s: `293y`, err: "duration overflow"},
+		{s: `1000000000000w`, err: "duration overflow"},
 
 		{s: ``, err: "invalid duration"},
 		{s: `w`, err: "invalid duration"},
 		{s: `1.2w`, err: "invalid duration"},
 		{s: `10x`, err: "invalid duration"},
+		{s: `   `, err: "invalid duration"},
+		{s: `1 0m`, err: "invalid duration"},
+		{s: `10 m s`, err: "invalid duration"},
+		{s: `h 10`, err: "invalid duration"},
+		{s: `- 5s`, err: "invalid duration"},
+		{s: `10 min`, err: "invalid duration"},
+
+		// Go durations are accepted when the InfluxQL form is invalid.
+		{s: `1.5s`, d: 1500 * time.Millisecond},
+		{s: `2h45m`, d: 2*time.Hour + 45*time.Minute},
+		{s: `1s500ms`, d: 1500 * time.Millisecond},
+		{s: ` 10us `, d: 10 * time.Microsecond},
+		{s: `100ns`, d: 100 * time.Nanosecond},
+		{s: `-1.5h`, d: -90 * time.Minute},
+		{s: `.5m`, d: 30 * time.Second},
+		{s: `1.5mo`, err: "invalid duration"},
+		{s: `1h 30m`, err: "invalid duration"},
+		{s: `3000000h30m`, err: "invalid duration"},
+
+		// The InfluxQL form takes precedence over the Go form.
+		{s: `1M`, d: time.Minute},
+		{s: `10`, d: 10 * time.Microsecond},
+		{s: `0`, d: 0},
 	}
 
 	for i, tt := range tests {
@@ -953,6 +2418,46 @@ func TestParseDuration(t *testing.T) {
 	}
 }
 
+// Ensure MustParseDuration returns a duration for valid input.
+func TestMustParseDuration(t *testing.T) {
+	if d := influxql.MustParseDuration(`90m`); d != 90*time.Minute {
+		t.Fatalf("unexpected duration: %s", d)
+	}
+}
+
+// Ensure MustParseDuration panics with the error message on invalid input.
+func TestMustParseDuration_Panic(t *testing.T) {
+	defer func() {
+		if r := recover(); r != `invalid duration` {
+			t.Fatalf("unexpected panic: %v", r)
+		}
+	}()
+	influxql.MustParseDuration(`10x`)
+}
+
+// Ensure a duration literal can be converted to a string and parsed back.
+func TestDurationLiteral_RoundTrip(t *testing.T) {
+	for i, d := range []time.Duration{
+		0,
+		time.Microsecond,
+		1500 * time.Microsecond,
+		15 * time.Millisecond,
+		90 * time.Second,
+		90 * time.Minute,
+		36 * time.Hour,
+		14 * 24 * time.Hour,
+		60 * 24 * time.Hour,
+		2 * 365 * 24 * time.Hour,
+		-5 * time.Minute,
+		-3 * time.Microsecond,
+	} {
+		lit := influxql.NewDurationLiteral(d)
+		if expr := influxql.MustParseExpr(lit.String()); !reflect.DeepEqual(lit, expr) {
+			t.Errorf("%d. %s: mismatch: %#v", i, lit, expr)
+		}
+	}
+}
+
 // Ensure a time duration can be formatted.
 func TestFormatDuration(t *testing.T) {
 	var tests = []struct {
@@ -967,6 +2472,10 @@ func TestFormatDuration(t *testing.T) {
 		{d: 2 * time.Hour, s: `2h`},
 		{d: 2 * 24 * time.Hour, s: `2d`},
 		{d: 2 * 7 * 24 * time.Hour, s: `2w`},
+		{d: 6 * 30 * 24 * time.Hour, s: `6mo`},
+		{d: 365 * 24 * time.Hour, s: `1y`},
+		{d: 30 * 7 * 24 * time.Hour, s: `30w`},
+		{d: 31 * 24 * time.Hour, s: `31d`},
 	}
 
 	for i, tt := range tests {
@@ -977,6 +2486,39 @@ func TestFormatDuration(t *testing.T) {
 	}
 }
 
+// Ensure a duration can be formatted in long form.
+func TestFormatDurationLong(t *testing.T) {
+	var tests = []struct {
+		d time.Duration
+		s string
+	}{
+		{0, `0 seconds`},
+		{999 * time.Nanosecond, `0 seconds`},
+		{time.Microsecond, `1 microsecond`},
+		{1500 * time.Microsecond, `1 millisecond 500 microseconds`},
+		{time.Second, `1 second`},
+		{2 * time.Second, `2 seconds`},
+		{90 * time.Minute, `1 hour 30 minutes`},
+		{2*time.Hour + time.Minute + time.Second, `2 hours 1 minute 1 second`},
+		{-90 * time.Minute, `-1 hour 30 minutes`},
+		{24 * time.Hour, `1 day`},
+		{14 * 24 * time.Hour, `2 weeks`},
+		{35 * 24 * time.Hour, `5 weeks`},
+		{30 * 24 * time.Hour, `1 month`},
+		{210 * 24 * time.Hour, `30 weeks`},
+		{39 * 24 * time.Hour, `1 month 1 week 2 days`},
+		{365 * 24 * time.Hour, `1 year`},
+		{366*24*time.Hour + time.Millisecond, `1 year 1 day 1 millisecond`},
+		{math.MinInt64, `-292 years 5 months 3 weeks 23 hours 47 minutes 16 seconds 854 milliseconds 775 microseconds`},
+	}
+
+	for i, tt := range tests {
+		if s := influxql.FormatDurationLong(tt.d); tt.s != s {
+			t.Errorf("%d. %v: mismatch: %s != %s", i, tt.d, tt.s, s)
+		}
+	}
+}
+
 // Ensure a string can be quoted.
 func TestQuote(t *testing.T) {
 	for i, tt := range []struct {
@@ -988,6 +2530,9 @@ func TestQuote(t *testing.T) {
 		{"foo\nbar", `'foo\nbar'`},
 		{`foo bar\\`, `'foo bar\\\\'`},
 		{`'foo'`, `'\'foo\''`},
+		{"a\tb\rc", `'a\tb\rc'`},
+		{"\x00\x1b\x7f", `'\u0000\u001b\u007f'`},
+		{`"é"`, `'"é"'`},
 	} {
 		if out := influxql.QuoteString(tt.in); tt.out != out {
 			t.Errorf("%d. %s: mismatch: %s != %s", i, tt.in, tt.out, out)
@@ -995,6 +2540,71 @@ func TestQuote(t *testing.T) {
 	}
 }
 
+// Ensure a quoted string is scanned back into the original string.
+func TestQuoteString_RoundTrip(t *testing.T) {
+	strs := []string{``, `foo`, `'`, `\`, `\'`, `"`, "\n", "\r\n", "\t", "\x00", "a\x01b\x1fc\x7f", `héllo wörld`, `\q`, `\u0041`}
+
+	// Add random combinations of control and escape characters.
+	rand := rand.New(rand.NewSource(0))
+	alphabet := []rune("a'\"\\\n\r\t\x00\x07\x7fü")
+	for i := 0; i < 200; i++ {
+		r := make([]rune, rand.Intn(8))
+		for j := range r {
+			r[j] = alphabet[rand.Intn(len(alphabet))]
+		}
+		strs = append(strs, string(r))
+	}
+
+	for _, s := range strs {
+		q := influxql.QuoteString(s)
+		if expr, err := influxql.ParseExpr(q); err != nil {
+			t.Errorf("%q: unexpected error: %s", q, err)
+		} else if lit, ok := expr.(*influxql.StringLiteral); !ok {
+			t.Errorf("%q: unexpected expr: %#v", q, expr)
+		} else if lit.Val != s {
+			t.Errorf("%q: mismatch: exp=%q got=%q", q, s, lit.Val)
+		}
+	}
+}
+
+// Ensure LIKE patterns are converted to regular expressions.
+func TestLikeRegexp(t *testing.T) {
+	var tests = []struct {
+		pattern string
+		s       string
+		match   bool
+	}{
+		{pattern: `web%`, s: `web01`, match: true},
+		{pattern: `web%`, s: `web`, match: true},
+		{pattern: `web%`, s: `db01`, match: false},
+		{pattern: `%01`, s: `web01`, match: true},
+		{pattern: `w_b`, s: `web`, match: true},
+		{pattern: `w_b`, s: `wb`, match: false},
+		{pattern: `w_b`, s: `wéb`, match: true},
+		{pattern: `%`, s: "a\nb", match: true},
+		{pattern: `a.b`, s: `axb`, match: false},
+		{pattern: `a(b)*`, s: `a(b)*`, match: true},
+		{pattern: `100\%`, s: `100%`, match: true},
+		{pattern: `100\%`, s: `1000`, match: false},
+		{pattern: `a\_b`, s: `a_b`, match: true},
+		{pattern: `a\_b`, s: `axb`, match: false},
+		{pattern: `a\\b`, s: `a\b`, match: true},
+	}
+
+	for i, tt := range tests {
+		re, err := influxql.LikeRegexp(tt.pattern)
+		if err != nil {
+			t.Errorf("%d. %q: unexpected error: %s", i, tt.pattern, err)
+		} else if match := re.MatchString(tt.s); match != tt.match {
+			t.Errorf("%d. %q: unexpected match for %q: %v", i, tt.pattern, tt.s, match)
+		}
+	}
+
+	if _, err := influxql.LikeRegexp(`abc\`); err != influxql.ErrInvalidLikePattern {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
 // Ensure an identifier's segments can be quoted.
 func TestQuoteIdent(t *testing.T) {
 	for i, tt := range []struct {
@@ -1012,6 +2622,72 @@ func TestQuoteIdent(t *testing.T) {
 	}
 }
 
+// Ensure a quoted identifier is scanned and split back into its original segments.
+func TestQuoteIdent_RoundTrip(t *testing.T) {
+	idents := []string{``, `foo`, `foo bar`, `foo.bar`, `a"b`, `"`, `\`, `a\"b`, `\\"`, "a\nb", "\n", `select`, `héllo wörld`, `'`}
+
+	// Add random combinations of characters that require escaping.
+	rand := rand.New(rand.NewSource(0))
+	alphabet := []rune("a.\"\\\n '\tü")
+	for i := 0; i < 200; i++ {
+		r := make([]rune, rand.Intn(8))
+		for j := range r {
+			r[j] = alphabet[rand.Intn(len(alphabet))]
+		}
+		idents = append(idents, string(r))
+	}
+
+	for _, ident := range idents {
+		q := influxql.QuoteIdent([]string{ident, ident})
+		stmt, err := influxql.ParseStatement(`SELECT value FROM ` + q)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", q, err)
+			continue
+		}
+
+		name := stmt.(*influxql.SelectStatement).Source.(*influxql.Measurement).Name
+		if segments, err := influxql.SplitIdent(name); err != nil {
+			t.Errorf("%q: split error: %s", q, err)
+		} else if !reflect.DeepEqual(segments, []string{ident, ident}) {
+			t.Errorf("%q: mismatch: %q", q, segments)
+		}
+	}
+}
+
+// Ensure SHOW statements can be converted back to the string they were parsed from.
+func TestShowStatement_String(t *testing.T) {
+	for i, s := range []string{
+		`SHOW SERIES`,
+		`SHOW SERIES FROM cpu WHERE host = 'a' LIMIT 10`,
+		`SHOW SERIES FROM cpu WHERE host = 'a' ORDER BY ASC, host DESC LIMIT 10 OFFSET 20`,
+		`SHOW SERIES FROM /cpu.*/ WHERE host =~ /web/ AND region = 'uswest'`,
+		`SHOW SERIES FROM /a\/b/ WHERE host !~ /^db\d+$/`,
+		`SHOW MEASUREMENTS`,
+		`SHOW MEASUREMENTS WHERE region = 'uswest' ORDER BY DESC LIMIT 10 OFFSET 5`,
+		`SHOW TAG KEYS`,
+		`SHOW TAG KEYS FROM cpu WHERE region = 'uswest' ORDER BY ASC LIMIT 10 OFFSET 5`,
+		`SHOW TAG VALUES FROM cpu WITH KEY = host`,
+		`SHOW TAG VALUES WITH KEY IN (host, region) WHERE region = 'uswest' ORDER BY ASC LIMIT 10 OFFSET 5`,
+		`SHOW FIELD KEYS`,
+		`SHOW FIELD KEYS FROM cpu ORDER BY ASC LIMIT 10 OFFSET 5`,
+		`SHOW TAG KEY CARDINALITY`,
+		`SHOW TAG KEY CARDINALITY ON db0 FROM cpu WHERE region = 'uswest'`,
+		`SHOW TAG VALUES CARDINALITY WITH KEY = host`,
+		`SHOW TAG VALUES CARDINALITY ON db0 FROM cpu WITH KEY IN (host, region) WHERE region = 'uswest'`,
+		`SHOW FIELD KEY CARDINALITY`,
+		`SHOW FIELD KEY CARDINALITY ON db0 WHERE region = 'uswest'`,
+		`SHOW RETENTION POLICIES`,
+		`SHOW RETENTION POLICIES ON mydb`,
+	} {
+		stmt, err := influxql.ParseStatement(s)
+		if err != nil {
+			t.Errorf("%d. %q: unexpected error: %s", i, s, err)
+		} else if other := stmt.String(); other != s {
+			t.Errorf("%d. %q: string mismatch:\n  exp=%s\n  got=%s", i, s, s, other)
+		}
+	}
+}
+
 // Ensure DropSeriesStatement can convert to a string
 func TestDropSeriesStatement_String(t *testing.T) {
 	var tests = []struct {
@@ -1068,9 +2744,113 @@ func TestDropSeriesStatement_String(t *testing.T) {
 	}
 }
 
+// Ensure CREATE USER never renders the password.
+func TestCreateUserStatement_String(t *testing.T) {
+	var tests = []struct {
+		s    string
+		stmt influxql.Statement
+	}{
+		{
+			s:    `CREATE USER jdoe WITH PASSWORD '[REDACTED]'`,
+			stmt: &influxql.CreateUserStatement{Name: "jdoe", Password: "secret"},
+		},
+		{
+			s:    `CREATE USER jdoe WITH PASSWORD HASH '[REDACTED]' WITH ALL PRIVILEGES`,
+			stmt: &influxql.CreateUserStatement{Name: "jdoe", Password: "$2a$10$abc", Hashed: true, Privilege: influxql.NewPrivilege(influxql.AllPrivileges)},
+		},
+	}
+
+	for _, test := range tests {
+		s := test.stmt.String()
+		if s != test.s {
+			t.Errorf("error rendering string. expected %s, actual: %s", test.s, s)
+		}
+	}
+}
+
 func BenchmarkParserParseStatement(b *testing.B) {
 	b.ReportAllocs()
-	s := `SELECT field FROM "series" WHERE value > 10`
+	s := `SELECT value FROM "series" WHERE value > 10`
+	for i := 0; i < b.N; i++ {
+		if stmt, err := influxql.NewParser(strings.NewReader(s)).ParseStatement(); err != nil {
+			b.Fatalf("unexpected error: %s", err)
+		} else if stmt == nil {
+			b.Fatalf("expected statement: %s", stmt)
+		}
+	}
+	b.SetBytes(int64(len(s)))
+}
+
+func BenchmarkParserParseStatement_QuotedIdent(b *testing.B) {
+	b.ReportAllocs()
+	s := `SELECT "field" FROM "series" WHERE "value" > 10`
+	for i := 0; i < b.N; i++ {
+		if stmt, err := influxql.NewParser(strings.NewReader(s)).ParseStatement(); err != nil {
+			b.Fatalf("unexpected error: %s", err)
+		} else if stmt == nil {
+			b.Fatalf("expected statement: %s", stmt)
+		}
+	}
+	b.SetBytes(int64(len(s)))
+}
+
+func BenchmarkParserParseStatement_Reset(b *testing.B) {
+	b.ReportAllocs()
+	s := `SELECT value FROM "series" WHERE value > 10`
+	p := influxql.NewParser(strings.NewReader(s))
+	r := strings.NewReader(s)
+	for i := 0; i < b.N; i++ {
+		r.Reset(s)
+		p.Reset(r)
+		if stmt, err := p.ParseStatement(); err != nil {
+			b.Fatalf("unexpected error: %s", err)
+		} else if stmt == nil {
+			b.Fatalf("expected statement: %s", stmt)
+		}
+	}
+	b.SetBytes(int64(len(s)))
+}
+
+func BenchmarkParserParseStatement_Pool(b *testing.B) {
+	b.ReportAllocs()
+	s := `SELECT value FROM "series" WHERE value > 10`
+	pp := influxql.NewParserPool(influxql.ParserOptions{})
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			p := pp.Get(strings.NewReader(s))
+			if stmt, err := p.ParseStatement(); err != nil {
+				b.Errorf("unexpected error: %s", err)
+				return
+			} else if stmt == nil {
+				b.Errorf("expected statement: %s", stmt)
+				return
+			}
+			pp.Put(p)
+		}
+	})
+	b.SetBytes(int64(len(s)))
+}
+
+func BenchmarkParserParseStatement_Parallel(b *testing.B) {
+	b.ReportAllocs()
+	s := `SELECT value FROM "series" WHERE value > 10`
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if stmt, err := influxql.NewParser(strings.NewReader(s)).ParseStatement(); err != nil {
+				b.Errorf("unexpected error: %s", err)
+				return
+			} else if stmt == nil {
+				b.Errorf("expected statement: %s", stmt)
+				return
+			}
+		}
+	})
+	b.SetBytes(int64(len(s)))
+}
+
+func BenchmarkParserParseStatement_Indented(b *testing.B) {
+	b.ReportAllocs()
+	s := "SELECT\n\t\"field\"\nFROM\n\t\"series\"\nWHERE\n\tvalue    >    10\n\tAND host = 'a'\n"
 	for i := 0; i < b.N; i++ {
 		if stmt, err := influxql.NewParser(strings.NewReader(s)).ParseStatement(); err != nil {
 			b.Fatalf("unexpected error: %s", err)
@@ -1081,6 +2861,76 @@ func BenchmarkParserParseStatement(b *testing.B) {
 	b.SetBytes(int64(len(s)))
 }
 
+func BenchmarkParseQuery(b *testing.B) {
+	b.ReportAllocs()
+	buf := []byte(`SELECT "field" FROM "series" WHERE value > 10 AND host = 'a' GROUP BY time(1m)`)
+	for i := 0; i < b.N; i++ {
+		if _, err := influxql.ParseQuery(string(buf)); err != nil {
+			b.Fatalf("unexpected error: %s", err)
+		}
+	}
+	b.SetBytes(int64(len(buf)))
+}
+
+func BenchmarkParseQueryBytes(b *testing.B) {
+	b.ReportAllocs()
+	buf := []byte(`SELECT "field" FROM "series" WHERE value > 10 AND host = 'a' GROUP BY time(1m)`)
+	for i := 0; i < b.N; i++ {
+		if _, err := influxql.ParseQueryBytes(buf); err != nil {
+			b.Fatalf("unexpected error: %s", err)
+		}
+	}
+	b.SetBytes(int64(len(buf)))
+}
+
+// Ensure malformed input returns an error instead of panicking.
+func FuzzParseQuery(f *testing.F) {
+	for _, s := range []string{
+		``,
+		`;`,
+		`SELECT mean(value) FROM cpu WHERE host = 'serverA' AND time > now() - 1h GROUP BY time(10m), region ORDER BY time DESC LIMIT 100 OFFSET 10`,
+		`SELECT value::float, "q""uote" FROM "db"."rp"./cpu.*/ WHERE region =~ /us-.*/ AND a NOT LIKE 'x%'`,
+		`SELECT count_if(value, host = 'a'), derivative(max(value), 1s) INTO db.rp.out FROM join(cpu, mem) GROUP BY *, time(1mo)`,
+		`SELECT * FROM cpu LIMIT 10, 20`,
+		`CREATE CONTINUOUS QUERY cq ON db RESAMPLE EVERY 1m FOR 1h BEGIN SELECT count(value) INTO c FROM cpu GROUP BY time(1h) END`,
+		`CREATE CONTINUOUS QUERY cq ON db BEGIN`,
+		`CREATE USER u WITH PASSWORD HASH '$2a$10$abc' WITH ALL PRIVILEGES`,
+		`ALTER RETENTION POLICY rp ON db DURATION 1h REPLICATION 2 DEFAULT RENAME TO rp2`,
+		`SHOW TAG VALUES CARDINALITY ON db FROM cpu WITH KEY IN (host, region) WHERE region = 'uswest'`,
+		`SHOW RETENTION POLICIES ON db; DROP SERIES 1; DELETE FROM cpu WHERE time < '2000-01-01 UTC'`,
+		`SELECT 'unterminated`,
+		`SELECT /unterminated`,
+		`SELECT "\q" FROM cpu`,
+		`SELECT ((((value FROM cpu`,
+		`SELECT value FROM cpu WHERE time > 1000000000000000000000h`,
+		`SELECT value FROM a.b.c.d.e`,
+	} {
+		f.Add(s)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		q, err := influxql.ParseQuery(s)
+		if err != nil {
+			return
+		}
+		_ = q.String()
+		_ = q.Hash()
+	})
+}
+
+// Ensure malformed durations return an error instead of panicking.
+func FuzzParseDuration(f *testing.F) {
+	for _, s := range []string{``, `1`, `10s`, `1mo`, `2 w`, `1.5h`, `1h30m`, `-1d`, `1µs`, `9999999999999999999y`} {
+		f.Add(s)
+	}
+
+	f.Fuzz(func(t *testing.T, s string) {
+		if d, err := influxql.ParseDuration(s); err == nil {
+			_ = influxql.FormatDuration(d)
+		}
+	})
+}
+
 // MustParseSelectStatement parses a select statement. Panic on error.
 func MustParseSelectStatement(s string) *influxql.SelectStatement {
 	stmt, err := influxql.NewParser(strings.NewReader(s)).ParseStatement()
@@ -1090,15 +2940,6 @@ func MustParseSelectStatement(s string) *influxql.SelectStatement {
 	return stmt.(*influxql.SelectStatement)
 }
 
-// MustParseExpr parses an expression. Panic on error.
-func MustParseExpr(s string) influxql.Expr {
-	expr, err := influxql.NewParser(strings.NewReader(s)).ParseExpr()
-	if err != nil {
-		panic(err.Error())
-	}
-	return expr
-}
-
 // errstring converts an error to its string representation.
 func errstring(err error) string {
 	if err != nil {
@@ -7,12 +7,17 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 // Scanner represents a lexical scanner for InfluxQL.
 type Scanner struct {
 	r *reader
+
+	// If true, "&&" and "||" are scanned as AND and OR.
+	operatorAliases bool
 }
 
 // NewScanner returns a new instance of Scanner.
@@ -91,10 +96,29 @@ func (s *Scanner) Scan() (tok Token, pos Pos, lit string) {
 		return LPAREN, pos, ""
 	case ')':
 		return RPAREN, pos, ""
+	case '[':
+		return LBRACKET, pos, ""
+	case ']':
+		return RBRACKET, pos, ""
 	case ',':
 		return COMMA, pos, ""
 	case ';':
 		return SEMICOLON, pos, ""
+	case ':':
+		if ch1, _ := s.r.read(); ch1 == ':' {
+			return DOUBLECOLON, pos, ""
+		}
+		s.r.unread()
+	case '&':
+		if ch1, _ := s.r.read(); ch1 == '&' && s.operatorAliases {
+			return AND, pos, ""
+		}
+		s.r.unread()
+	case '|':
+		if ch1, _ := s.r.read(); ch1 == '|' && s.operatorAliases {
+			return OR, pos, ""
+		}
+		s.r.unread()
 	}
 
 	return ILLEGAL, pos, string(ch0)
@@ -102,13 +126,23 @@ func (s *Scanner) Scan() (tok Token, pos Pos, lit string) {
 
 // scanWhitespace consumes the current rune and all contiguous whitespace.
 func (s *Scanner) scanWhitespace() (tok Token, pos Pos, lit string) {
-	// Create a buffer and read the current character into it.
-	var buf bytes.Buffer
 	ch, pos := s.r.curr()
-	_, _ = buf.WriteRune(ch)
+
+	// Most whitespace runs are a single character so return those
+	// without building a buffer.
+	next, _ := s.r.read()
+	if next == eof {
+		return WS, pos, string(ch)
+	} else if !isWhitespace(next) {
+		s.r.unread()
+		return WS, pos, string(ch)
+	}
 
 	// Read every subsequent whitespace character into the buffer.
 	// Non-whitespace characters and EOF will cause the loop to exit.
+	var buf strings.Builder
+	_, _ = buf.WriteRune(ch)
+	_, _ = buf.WriteRune(next)
 	for {
 		ch, _ = s.r.read()
 		if ch == eof {
@@ -140,9 +174,8 @@ func (s *Scanner) scanIdent() (tok Token, pos Pos, lit string) {
 			if tok0, pos0, lit0 := s.scanString(); tok0 == BADSTRING || tok0 == BADESCAPE {
 				return tok0, pos0, lit0
 			} else {
-				_ = buf.WriteByte('"')
-				_, _ = buf.WriteString(lit0)
-				_ = buf.WriteByte('"')
+				// Re-escape the segment so the literal can be split again.
+				_, _ = buf.WriteString(QuoteIdent([]string{lit0}))
 			}
 		} else if isIdentChar(ch) {
 			s.r.unread()
@@ -165,8 +198,8 @@ func (s *Scanner) scanIdent() (tok Token, pos Pos, lit string) {
 // scanString consumes a contiguous string of non-quote characters.
 // Quote characters can be consumed if they're first escaped with a backslash.
 func (s *Scanner) scanString() (tok Token, pos Pos, lit string) {
-	s.r.unread()
 	_, pos = s.r.curr()
+	s.r.unread()
 
 	var err error
 	lit, err = ScanString(s.r)
@@ -234,15 +267,38 @@ func (s *Scanner) scanNumber() (tok Token, pos Pos, lit string) {
 		s.r.unread()
 	}
 
-	// Attempt to read as a duration if it doesn't have a fractional part.
-	if !strings.Contains(buf.String(), ".") {
-		// If the next rune is a duration unit (u,µ,ms,s) then return a duration token
-		if ch0, _ := s.r.read(); ch0 == 'u' || ch0 == 'µ' || ch0 == 's' || ch0 == 'h' || ch0 == 'd' || ch0 == 'w' {
+	// If next code points are an exponent then consume them.
+	if ch0, _ := s.r.read(); ch0 == 'e' || ch0 == 'E' {
+		// Peek at the next two runes for a digit or a sign and a digit.
+		ch1, _ := s.r.read()
+		ch2, _ := s.r.read()
+		s.r.unread()
+		s.r.unread()
+
+		if isDigit(ch1) || ((ch1 == '+' || ch1 == '-') && isDigit(ch2)) {
+			_, _ = buf.WriteRune(ch0)
+			if !isDigit(ch1) {
+				_, _ = s.r.read()
+				_, _ = buf.WriteRune(ch1)
+			}
+			_, _ = buf.WriteString(s.scanDigits())
+		} else {
+			s.r.unread()
+		}
+	} else {
+		s.r.unread()
+	}
+
+	// Attempt to read as a duration if it doesn't have a fractional part
+	// or an exponent.
+	if !strings.ContainsAny(buf.String(), ".eE") {
+		// If the next rune is a duration unit (u,µ,ms,s,m,mo,...) then return a duration token
+		if ch0, _ := s.r.read(); ch0 == 'u' || ch0 == 'µ' || ch0 == 's' || ch0 == 'h' || ch0 == 'd' || ch0 == 'w' || ch0 == 'y' {
 			_, _ = buf.WriteRune(ch0)
 			return DURATION_VAL, pos, buf.String()
 		} else if ch0 == 'm' {
 			_, _ = buf.WriteRune(ch0)
-			if ch1, _ := s.r.read(); ch1 == 's' {
+			if ch1, _ := s.r.read(); ch1 == 's' || ch1 == 'o' {
 				_, _ = buf.WriteRune(ch1)
 			} else {
 				s.r.unread()
@@ -254,6 +310,51 @@ func (s *Scanner) scanNumber() (tok Token, pos Pos, lit string) {
 	return NUMBER, pos, buf.String()
 }
 
+// ScanRegex consumes a regular expression delimited by forward slashes.
+// A forward slash can be included in the expression by escaping it with a
+// backslash. All other escapes are passed through to the regex unchanged.
+func (s *Scanner) ScanRegex() (tok Token, pos Pos, lit string) {
+	// Read the opening slash.
+	ch, pos := s.r.read()
+	if ch != '/' {
+		return ILLEGAL, pos, string(ch)
+	}
+
+	var buf bytes.Buffer
+	for {
+		ch0, _ := s.r.read()
+		if ch0 == '/' {
+			return REGEX, pos, buf.String()
+		} else if ch0 == eof || ch0 == '\n' {
+			return BADREGEX, pos, buf.String()
+		} else if ch0 == '\\' {
+			// Unescape slashes but leave all other escapes for the regex.
+			if ch1, _ := s.r.read(); ch1 == '/' {
+				_, _ = buf.WriteRune('/')
+			} else if ch1 == eof {
+				return BADREGEX, pos, buf.String()
+			} else {
+				_, _ = buf.WriteRune(ch0)
+				_, _ = buf.WriteRune(ch1)
+			}
+		} else {
+			_, _ = buf.WriteRune(ch0)
+		}
+	}
+}
+
+// peekRuneIgnoreWhitespace consumes any whitespace and returns the next rune
+// without consuming it.
+func (s *Scanner) peekRuneIgnoreWhitespace() rune {
+	for {
+		ch, _ := s.r.read()
+		if !isWhitespace(ch) {
+			s.r.unread()
+			return ch
+		}
+	}
+}
+
 // scanDigits consume a contiguous series of digits.
 func (s *Scanner) scanDigits() string {
 	var buf bytes.Buffer
@@ -277,6 +378,11 @@ func isLetter(ch rune) bool { return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && c
 // isDigit returns true if the rune is a digit.
 func isDigit(ch rune) bool { return (ch >= '0' && ch <= '9') }
 
+// isHexDigit returns true if the rune is a hexadecimal digit.
+func isHexDigit(ch rune) bool {
+	return isDigit(ch) || (ch >= 'a' && ch <= 'f') || (ch >= 'A' && ch <= 'F')
+}
+
 // isIdentChar returns true if the rune that be used in a bare identifier.
 func isIdentChar(ch rune) bool { return isLetter(ch) || isDigit(ch) || ch == '_' }
 
@@ -298,8 +404,31 @@ func newBufScanner(r io.Reader) *bufScanner {
 	return &bufScanner{s: NewScanner(r)}
 }
 
+// reset discards any buffered tokens and begins scanning from r.
+func (s *bufScanner) reset(r io.Reader) {
+	s.s.r.reset(r)
+	s.i, s.n = 0, 0
+	s.buf = [3]struct {
+		tok Token
+		pos Pos
+		lit string
+	}{}
+}
+
 // Scan reads the next token from the scanner.
 func (s *bufScanner) Scan() (tok Token, pos Pos, lit string) {
+	return s.scanFunc(s.s.Scan)
+}
+
+// ScanRegex reads a regex literal from the scanner.
+// This must only be called when there are no unread tokens in the buffer.
+func (s *bufScanner) ScanRegex() (tok Token, pos Pos, lit string) {
+	assert(s.n == 0, "cannot scan regex with %d unread tokens", s.n)
+	return s.scanFunc(s.s.ScanRegex)
+}
+
+// scanFunc reads the next token using the given scan function.
+func (s *bufScanner) scanFunc(scan func() (Token, Pos, string)) (tok Token, pos Pos, lit string) {
 	// If we have unread tokens then read them off the buffer first.
 	if s.n > 0 {
 		s.n--
@@ -309,12 +438,22 @@ func (s *bufScanner) Scan() (tok Token, pos Pos, lit string) {
 	// Move buffer position forward and save the token.
 	s.i = (s.i + 1) % len(s.buf)
 	buf := &s.buf[s.i]
-	buf.tok, buf.pos, buf.lit = s.s.Scan()
+	buf.tok, buf.pos, buf.lit = scan()
 
 	return s.curr()
 }
 
+// peekRuneIgnoreWhitespace consumes any whitespace and returns the next rune.
+// Returns zero if there are unread tokens in the buffer.
+func (s *bufScanner) peekRuneIgnoreWhitespace() rune {
+	if s.n > 0 {
+		return 0
+	}
+	return s.s.peekRuneIgnoreWhitespace()
+}
+
 // Unscan pushes the previously token back onto the buffer.
+// At most len(s.buf) tokens can be pushed back, including whitespace.
 func (s *bufScanner) Unscan() { s.n++ }
 
 // curr returns the last read token.
@@ -329,12 +468,22 @@ type reader struct {
 	r   io.RuneScanner
 	i   int // buffer index
 	n   int // buffer char count
-	pos Pos // last read rune position
+	pos Pos // next rune position
 	buf [3]struct {
 		ch  rune
 		pos Pos
 	}
-	eof bool // true if reader has ever seen eof.
+}
+
+// reset discards any buffered runes and begins reading from rd.
+// The underlying bufio.Reader is reused when possible.
+func (r *reader) reset(rd io.Reader) {
+	if br, ok := r.r.(*bufio.Reader); ok {
+		br.Reset(rd)
+	} else {
+		r.r = bufio.NewReader(rd)
+	}
+	r.i, r.n, r.pos = 0, 0, Pos{}
 }
 
 // ReadRune reads the next rune from the reader.
@@ -382,21 +531,16 @@ func (r *reader) read() (ch rune, pos Pos) {
 	buf := &r.buf[r.i]
 	buf.ch, buf.pos = ch, r.pos
 
-	// Update position.
-	// Only count EOF once.
+	// Update position. Characters are counted by rune, not byte.
+	// EOF does not advance the position so reading past the end of the
+	// input repeatedly returns the same position.
 	if ch == '\n' {
 		r.pos.Line++
 		r.pos.Char = 0
-	} else if !r.eof {
+	} else if ch != eof {
 		r.pos.Char++
 	}
 
-	// Mark the reader as EOF.
-	// This is used so we don't double count EOF characters.
-	if ch == eof {
-		r.eof = true
-	}
-
 	return r.curr()
 }
 
@@ -433,13 +577,22 @@ func ScanString(r io.RuneScanner) (string, error) {
 			// If the next character is an escape then write the escaped char.
 			// If it's not a valid escape then return an error.
 			ch1, _, _ := r.ReadRune()
-			if ch1 == 'n' {
+			switch ch1 {
+			case 'n':
 				_, _ = buf.WriteRune('\n')
-			} else if ch1 == '\\' {
-				_, _ = buf.WriteRune('\\')
-			} else if ch1 == '"' {
-				_, _ = buf.WriteRune('"')
-			} else {
+			case 't':
+				_, _ = buf.WriteRune('\t')
+			case 'r':
+				_, _ = buf.WriteRune('\r')
+			case '\\', '"', '\'':
+				_, _ = buf.WriteRune(ch1)
+			case 'u':
+				ch, lit, ok := scanUnicodeEscape(r)
+				if !ok {
+					return `\u` + lit, errBadEscape
+				}
+				_, _ = buf.WriteRune(ch)
+			default:
 				return string(ch0) + string(ch1), errBadEscape
 			}
 		} else {
@@ -448,6 +601,29 @@ func ScanString(r io.RuneScanner) (string, error) {
 	}
 }
 
+// scanUnicodeEscape reads the four hex digits following a "\u" escape.
+// Returns the characters read if they are not a valid code point.
+func scanUnicodeEscape(r io.RuneScanner) (ch rune, lit string, ok bool) {
+	var buf [4]rune
+	for i := range buf {
+		c, _, err := r.ReadRune()
+		if err != nil {
+			return 0, string(buf[:i]), false
+		}
+		buf[i] = c
+
+		if !isHexDigit(c) {
+			return 0, string(buf[:i+1]), false
+		}
+	}
+
+	v, _ := strconv.ParseUint(string(buf[:]), 16, 32)
+	if ch = rune(v); !utf8.ValidRune(ch) {
+		return 0, string(buf[:]), false
+	}
+	return ch, "", true
+}
+
 var errBadString = errors.New("bad string")
 var errBadEscape = errors.New("bad escape")
 
@@ -533,6 +709,39 @@ func SplitIdent(s string) (segments []string, err error) {
 	}
 }
 
+// splitIdentSegments splits an identifier on every dot outside of quotes and
+// unquotes each segment. Unlike SplitIdent, bare segments are not joined so
+// "cpu.value" returns "cpu" and "value".
+func splitIdentSegments(s string) ([]string, error) {
+	var segments []string
+	r := strings.NewReader(s)
+	for {
+		// Each segment is either quoted or bare.
+		if ch, _, err := r.ReadRune(); err != nil {
+			return nil, errInvalidIdentifier
+		} else if ch == '"' {
+			_ = r.UnreadRune()
+			segment, err := ScanString(r)
+			if err != nil {
+				return nil, err
+			}
+			segments = append(segments, segment)
+		} else if isIdentChar(ch) {
+			_ = r.UnreadRune()
+			segments = append(segments, ScanBareIdent(r))
+		} else {
+			return nil, errInvalidIdentifier
+		}
+
+		// Stop at EOF and continue after a dot.
+		if ch, _, err := r.ReadRune(); err != nil {
+			return segments, nil
+		} else if ch != '.' {
+			return nil, errInvalidIdentifier
+		}
+	}
+}
+
 // lastIdent returns the last identifier.
 func lastIdent(s string) string {
 	a, _ := SplitIdent(s)
@@ -40,6 +40,8 @@ func TestScanner_Scan(t *testing.T) {
 		{s: `and`, tok: influxql.AND},
 		{s: `OR`, tok: influxql.OR},
 		{s: `or`, tok: influxql.OR},
+		{s: `&&`, tok: influxql.ILLEGAL, lit: `&`},
+		{s: `||`, tok: influxql.ILLEGAL, lit: `|`},
 
 		{s: `=`, tok: influxql.EQ},
 		{s: `<>`, tok: influxql.NEQ},
@@ -52,18 +54,22 @@ func TestScanner_Scan(t *testing.T) {
 		// Misc tokens
 		{s: `(`, tok: influxql.LPAREN},
 		{s: `)`, tok: influxql.RPAREN},
+		{s: `[`, tok: influxql.LBRACKET},
+		{s: `]`, tok: influxql.RBRACKET},
 		{s: `,`, tok: influxql.COMMA},
 		{s: `;`, tok: influxql.SEMICOLON},
 		{s: `.`, tok: influxql.DOT},
+		{s: `::`, tok: influxql.DOUBLECOLON},
+		{s: `:`, tok: influxql.ILLEGAL, lit: `:`},
 
 		// Identifiers
 		{s: `foo`, tok: influxql.IDENT, lit: `foo`},
 		{s: `Zx12_3U_-`, tok: influxql.IDENT, lit: `Zx12_3U_`},
 		{s: `"foo".bar`, tok: influxql.IDENT, lit: `"foo".bar`},
-		{s: `"foo\\bar"`, tok: influxql.IDENT, lit: `"foo\bar"`},
+		{s: `"foo\\bar"`, tok: influxql.IDENT, lit: `"foo\\bar"`},
 		{s: `"foo\bar"`, tok: influxql.BADESCAPE, lit: `\b`, pos: influxql.Pos{Line: 0, Char: 5}},
-		{s: `"foo\"bar\""`, tok: influxql.IDENT, lit: `"foo"bar""`},
-		{s: `test"`, tok: influxql.BADSTRING, lit: "", pos: influxql.Pos{Line: 0, Char: 3}},
+		{s: `"foo\"bar\""`, tok: influxql.IDENT, lit: `"foo\"bar\""`},
+		{s: `test"`, tok: influxql.BADSTRING, lit: "", pos: influxql.Pos{Line: 0, Char: 4}},
 		{s: `"test`, tok: influxql.BADSTRING, lit: `test`},
 
 		{s: `true`, tok: influxql.TRUE},
@@ -86,6 +92,14 @@ func TestScanner_Scan(t *testing.T) {
 		{s: `.23`, tok: influxql.NUMBER, lit: `.23`},
 		{s: `+.23`, tok: influxql.NUMBER, lit: `+.23`},
 		{s: `-.23`, tok: influxql.NUMBER, lit: `-.23`},
+		{s: `1e5`, tok: influxql.NUMBER, lit: `1e5`},
+		{s: `1.5E-3`, tok: influxql.NUMBER, lit: `1.5E-3`},
+		{s: `-2e+10`, tok: influxql.NUMBER, lit: `-2e+10`},
+		{s: `1e400`, tok: influxql.NUMBER, lit: `1e400`},
+		{s: `1e5s`, tok: influxql.NUMBER, lit: `1e5`},
+		{s: `1e`, tok: influxql.NUMBER, lit: `1`},
+		{s: `1e+`, tok: influxql.NUMBER, lit: `1`},
+		{s: `1ex`, tok: influxql.NUMBER, lit: `1`},
 		//{s: `.`, tok: influxql.ILLEGAL, lit: `.`},
 		{s: `-.`, tok: influxql.SUB, lit: ``},
 		{s: `+.`, tok: influxql.ADD, lit: ``},
@@ -100,6 +114,8 @@ func TestScanner_Scan(t *testing.T) {
 		{s: `10h`, tok: influxql.DURATION_VAL, lit: `10h`},
 		{s: `10d`, tok: influxql.DURATION_VAL, lit: `10d`},
 		{s: `10w`, tok: influxql.DURATION_VAL, lit: `10w`},
+		{s: `6mo`, tok: influxql.DURATION_VAL, lit: `6mo`},
+		{s: `1y`, tok: influxql.DURATION_VAL, lit: `1y`},
 		{s: `10x`, tok: influxql.NUMBER, lit: `10`}, // non-duration unit
 
 		// Keywords
@@ -195,7 +211,7 @@ func TestScanner_Scan_Multi(t *testing.T) {
 		{tok: influxql.WS, pos: influxql.Pos{Line: 0, Char: 34}, lit: " "},
 		{tok: influxql.EQ, pos: influxql.Pos{Line: 0, Char: 35}, lit: ""},
 		{tok: influxql.WS, pos: influxql.Pos{Line: 0, Char: 36}, lit: " "},
-		{tok: influxql.STRING, pos: influxql.Pos{Line: 0, Char: 36}, lit: "b"},
+		{tok: influxql.STRING, pos: influxql.Pos{Line: 0, Char: 37}, lit: "b"},
 		{tok: influxql.EOF, pos: influxql.Pos{Line: 0, Char: 40}, lit: ""},
 	}
 
@@ -226,6 +242,139 @@ func TestScanner_Scan_Multi(t *testing.T) {
 	}
 }
 
+// Ensure positions count runes rather than bytes and reset on each line.
+func TestScanner_Scan_Pos(t *testing.T) {
+	for i, tt := range []struct {
+		s   string
+		tok influxql.Token
+		lit string
+		pos influxql.Pos
+	}{
+		{s: `SELECT "vålüe" FROM cpu`, tok: influxql.FROM, pos: influxql.Pos{Line: 0, Char: 15}},
+		{s: `SELECT "日本".x FROM cpu`, tok: influxql.FROM, pos: influxql.Pos{Line: 0, Char: 14}},
+		{s: `SELECT value FROM "mé"`, tok: influxql.EOF, pos: influxql.Pos{Line: 0, Char: 22}},
+		{s: `SELECT value FROM mé`, tok: influxql.EOF, pos: influxql.Pos{Line: 0, Char: 20}},
+		{s: `WHERE a = 'ééé' AND b`, tok: influxql.STRING, lit: "ééé", pos: influxql.Pos{Line: 0, Char: 10}},
+		{s: `WHERE a = 'ééé' AND b`, tok: influxql.AND, pos: influxql.Pos{Line: 0, Char: 16}},
+		{s: "SELECT \"ü\"\n  FROM cpu", tok: influxql.FROM, pos: influxql.Pos{Line: 1, Char: 2}},
+		{s: "SELECT 'ü'\r\nFROM 'ö'", tok: influxql.STRING, lit: "ö", pos: influxql.Pos{Line: 1, Char: 5}},
+	} {
+		s := influxql.NewScanner(strings.NewReader(tt.s))
+		for {
+			tok, pos, lit := s.Scan()
+			if tok == tt.tok && lit == tt.lit {
+				if pos != tt.pos {
+					t.Errorf("%d. %q %s pos mismatch: exp=%#v got=%#v", i, tt.s, tt.tok, tt.pos, pos)
+				}
+				break
+			} else if tok == influxql.EOF {
+				t.Errorf("%d. %q: %s not found", i, tt.s, tt.tok)
+				break
+			}
+		}
+	}
+}
+
+// Ensure every keyword is recognized regardless of case and that identifiers
+// which merely contain a keyword are not.
+func TestLookup(t *testing.T) {
+	toks := []influxql.Token{influxql.AND, influxql.OR, influxql.TRUE, influxql.FALSE}
+	for tok := influxql.ALL; tok <= influxql.WRITE; tok++ {
+		toks = append(toks, tok)
+	}
+
+	for _, tok := range toks {
+		kw := tok.String()
+		for _, s := range []string{kw, strings.ToLower(kw), strings.ToUpper(kw[:1]) + strings.ToLower(kw[1:])} {
+			if got := influxql.Lookup(s); got != tok {
+				t.Errorf("%q: unexpected token: exp=%s got=%s", s, tok, got)
+			}
+		}
+		for _, s := range []string{kw + "ion", "x" + kw, kw + "_", kw[:len(kw)-1]} {
+			if got := influxql.Lookup(s); got != influxql.IDENT && got.String() != strings.ToUpper(s) {
+				t.Errorf("%q: unexpected token: %s", s, got)
+			}
+		}
+	}
+
+	for _, s := range []string{"selection", "fromage", "wherever", "selectá", "", "value"} {
+		if got := influxql.Lookup(s); got != influxql.IDENT {
+			t.Errorf("%q: unexpected token: %s", s, got)
+		}
+	}
+}
+
+// Ensure every token has at most one classification.
+func TestToken_Classification(t *testing.T) {
+	for tok := influxql.ILLEGAL; tok <= influxql.WRITE; tok++ {
+		if tok.String() == "" {
+			continue // range markers
+		}
+
+		var classes []string
+		if tok.IsLiteral() {
+			classes = append(classes, "literal")
+		}
+		if tok.IsOperator() {
+			classes = append(classes, "operator")
+		}
+		if tok.IsKeyword() {
+			classes = append(classes, "keyword")
+		}
+
+		var exp string
+		switch {
+		case tok >= influxql.IDENT && tok <= influxql.FALSE:
+			exp = "literal"
+		case tok >= influxql.ADD && tok <= influxql.GTE:
+			exp = "operator"
+		case tok >= influxql.ALL:
+			exp = "keyword"
+		}
+		if got := strings.Join(classes, ","); got != exp {
+			t.Errorf("%s: unexpected classification: exp=%q got=%q", tok, exp, got)
+		}
+	}
+
+	// Reserved words that are not keywords.
+	for _, tok := range []influxql.Token{influxql.AND, influxql.OR, influxql.LIKE} {
+		if !tok.IsOperator() || tok.IsKeyword() {
+			t.Errorf("%s: expected operator", tok)
+		}
+	}
+	for _, tok := range []influxql.Token{influxql.TRUE, influxql.FALSE} {
+		if !tok.IsLiteral() || tok.IsKeyword() {
+			t.Errorf("%s: expected literal", tok)
+		}
+	}
+}
+
+// Ensure the scanner can scan regex literals.
+func TestScanner_ScanRegex(t *testing.T) {
+	var tests = []struct {
+		in  string
+		tok influxql.Token
+		lit string
+	}{
+		{in: `/^payments\./`, tok: influxql.REGEX, lit: `^payments\.`},
+		{in: `/foo\/bar/`, tok: influxql.REGEX, lit: `foo/bar`},
+		{in: `/(?i)web/ AND`, tok: influxql.REGEX, lit: `(?i)web`},
+		{in: `//`, tok: influxql.REGEX, lit: ``},
+		{in: `/foo`, tok: influxql.BADREGEX, lit: `foo`},
+		{in: "/foo\nbar/", tok: influxql.BADREGEX, lit: `foo`},
+		{in: `foo`, tok: influxql.ILLEGAL, lit: `f`},
+	}
+
+	for i, tt := range tests {
+		tok, _, lit := influxql.NewScanner(strings.NewReader(tt.in)).ScanRegex()
+		if tt.tok != tok {
+			t.Errorf("%d. %s: token: exp=%s, got=%s", i, tt.in, tt.tok, tok)
+		} else if tt.lit != lit {
+			t.Errorf("%d. %s: literal: exp=%s, got=%s", i, tt.in, tt.lit, lit)
+		}
+	}
+}
+
 // Ensure the library can correctly scan strings.
 func TestScanString(t *testing.T) {
 	var tests = []struct {
@@ -239,10 +388,17 @@ func TestScanString(t *testing.T) {
 		{in: `"foo\nbar"`, out: "foo\nbar"},
 		{in: `"foo\\bar"`, out: `foo\bar`},
 		{in: `"foo\"bar"`, out: `foo"bar`},
+		{in: `'foo\'bar'`, out: `foo'bar`},
+		{in: `'foo\tbar\r\n'`, out: "foo\tbar\r\n"},
+		{in: `'\u00e9\u0000\u001F'`, out: "\u00e9\x00\x1f"},
 
 		{in: `"foo` + "\n", out: `foo`, err: "bad string"}, // newline in string
 		{in: `"foo`, out: `foo`, err: "bad string"},        // unclosed quotes
 		{in: `"foo\xbar"`, out: `\x`, err: "bad escape"},   // invalid escape
+		{in: `'foo\qbar'`, out: `\q`, err: "bad escape"},   // invalid escape
+		{in: `'\u12g4'`, out: `\u12g`, err: "bad escape"},  // invalid hex digit
+		{in: `'\u12'`, out: `\u12'`, err: "bad escape"},    // short unicode escape
+		{in: `'\ud800'`, out: `\ud800`, err: "bad escape"}, // surrogate half
 	}
 
 	for i, tt := range tests {
@@ -287,3 +443,31 @@ func TestSplitIdent(t *testing.T) {
 		}
 	}
 }
+
+func BenchmarkScanner_Scan(b *testing.B) {
+	queries := []string{
+		`SELECT mean(value) FROM cpu WHERE host = 'serverA' AND time > now() - 1h GROUP BY time(10m), region ORDER BY time DESC LIMIT 100`,
+		`select count(value) from "db"."rp".mem where region =~ /us-.*/ group by time(1m) fill(0)`,
+		`SHOW TAG VALUES FROM cpu WITH KEY = host WHERE region = 'uswest' LIMIT 10`,
+		`CREATE CONTINUOUS QUERY cq ON db BEGIN SELECT sum(value) INTO cpu_1h FROM cpu GROUP BY time(1h) END`,
+		`Select Value From Measurements Where Field_Key = 'x' Order By Time Asc`,
+	}
+
+	var n int
+	for _, q := range queries {
+		n += len(q)
+	}
+
+	b.ReportAllocs()
+	b.SetBytes(int64(n))
+	for i := 0; i < b.N; i++ {
+		for _, q := range queries {
+			s := influxql.NewScanner(strings.NewReader(q))
+			for {
+				if tok, _, _ := s.Scan(); tok == influxql.EOF {
+					break
+				}
+			}
+		}
+	}
+}
@@ -2,6 +2,7 @@ package influxql
 
 import (
 	"strings"
+	"unicode/utf8"
 )
 
 // Token is a lexical token of the InfluxQL language.
@@ -21,6 +22,8 @@ const (
 	STRING       // "abc"
 	BADSTRING    // "abc
 	BADESCAPE    // \q
+	REGEX        // /^a.*$/
+	BADREGEX     // /abc
 	TRUE         // true
 	FALSE        // false
 	literal_end
@@ -36,20 +39,25 @@ const (
 	OR  // OR
 
 	EQ       // =
-	NEQ      // !=
+	NEQ      // != or <>
 	EQREGEX  // =~
 	NEQREGEX // !~
+	LIKE     // LIKE
+	NLIKE    // NOT LIKE
 	LT       // <
 	LTE      // <=
 	GT       // >
 	GTE      // >=
 	operator_end
 
-	LPAREN    // (
-	RPAREN    // )
-	COMMA     // ,
-	SEMICOLON // ;
-	DOT       // .
+	LPAREN      // (
+	RPAREN      // )
+	LBRACKET    // [
+	RBRACKET    // ]
+	COMMA       // ,
+	SEMICOLON   // ;
+	DOT         // .
+	DOUBLECOLON // ::
 
 	keyword_beg
 	// Keywords
@@ -59,6 +67,7 @@ const (
 	ASC
 	BEGIN
 	BY
+	CARDINALITY
 	CREATE
 	CONTINUOUS
 	DATABASE
@@ -69,9 +78,11 @@ const (
 	DROP
 	DURATION
 	END
+	EVERY
 	EXISTS
 	EXPLAIN
 	FIELD
+	FOR
 	FROM
 	GRANT
 	GROUP
@@ -86,6 +97,7 @@ const (
 	SHOW
 	MEASUREMENT
 	MEASUREMENTS
+	NOT
 	OFFSET
 	ON
 	ORDER
@@ -96,7 +108,9 @@ const (
 	QUERIES
 	QUERY
 	READ
+	RENAME
 	REPLICATION
+	RESAMPLE
 	RETENTION
 	REVOKE
 	SELECT
@@ -123,6 +137,8 @@ var tokens = [...]string{
 	STRING:       "STRING",
 	BADSTRING:    "BADSTRING",
 	BADESCAPE:    "BADESCAPE",
+	REGEX:        "REGEX",
+	BADREGEX:     "BADREGEX",
 	TRUE:         "TRUE",
 	FALSE:        "FALSE",
 
@@ -138,16 +154,21 @@ var tokens = [...]string{
 	NEQ:      "!=",
 	EQREGEX:  "=~",
 	NEQREGEX: "!~",
+	LIKE:     "LIKE",
+	NLIKE:    "NOT LIKE",
 	LT:       "<",
 	LTE:      "<=",
 	GT:       ">",
 	GTE:      ">=",
 
-	LPAREN:    "(",
-	RPAREN:    ")",
-	COMMA:     ",",
-	SEMICOLON: ";",
-	DOT:       ".",
+	LPAREN:      "(",
+	RPAREN:      ")",
+	LBRACKET:    "[",
+	RBRACKET:    "]",
+	COMMA:       ",",
+	SEMICOLON:   ";",
+	DOT:         ".",
+	DOUBLECOLON: "::",
 
 	ALL:          "ALL",
 	ALTER:        "ALTER",
@@ -155,6 +176,7 @@ var tokens = [...]string{
 	ASC:          "ASC",
 	BEGIN:        "BEGIN",
 	BY:           "BY",
+	CARDINALITY:  "CARDINALITY",
 	CREATE:       "CREATE",
 	CONTINUOUS:   "CONTINUOUS",
 	DATABASE:     "DATABASE",
@@ -165,9 +187,11 @@ var tokens = [...]string{
 	DROP:         "DROP",
 	DURATION:     "DURATION",
 	END:          "END",
+	EVERY:        "EVERY",
 	EXISTS:       "EXISTS",
 	EXPLAIN:      "EXPLAIN",
 	FIELD:        "FIELD",
+	FOR:          "FOR",
 	FROM:         "FROM",
 	GRANT:        "GRANT",
 	GROUP:        "GROUP",
@@ -182,6 +206,7 @@ var tokens = [...]string{
 	SHOW:         "SHOW",
 	MEASUREMENT:  "MEASUREMENT",
 	MEASUREMENTS: "MEASUREMENTS",
+	NOT:          "NOT",
 	OFFSET:       "OFFSET",
 	ON:           "ON",
 	ORDER:        "ORDER",
@@ -192,7 +217,9 @@ var tokens = [...]string{
 	QUERIES:      "QUERIES",
 	QUERY:        "QUERY",
 	READ:         "READ",
+	RENAME:       "RENAME",
 	REPLICATION:  "REPLICATION",
+	RESAMPLE:     "RESAMPLE",
 	RETENTION:    "RETENTION",
 	REVOKE:       "REVOKE",
 	SELECT:       "SELECT",
@@ -207,20 +234,26 @@ var tokens = [...]string{
 	WRITE:        "WRITE",
 }
 
+// keywords maps the lowercase form of each keyword to its token.
 var keywords map[string]Token
 
+// maxKeywordLen is the size of the buffer used to case-fold identifiers
+// in Lookup. It must be at least as long as the longest keyword.
+const maxKeywordLen = 32
+
 func init() {
 	keywords = make(map[string]Token)
 	for tok := keyword_beg + 1; tok < keyword_end; tok++ {
-		keywords[strings.ToUpper(tokens[tok])] = tok
 		keywords[strings.ToLower(tokens[tok])] = tok
 	}
-	for _, tok := range []Token{AND, OR} {
-		keywords[strings.ToUpper(tokens[tok])] = tok
+	for _, tok := range []Token{AND, OR, LIKE, TRUE, FALSE} {
 		keywords[strings.ToLower(tokens[tok])] = tok
 	}
-	keywords["true"] = TRUE
-	keywords["false"] = FALSE
+	for k := range keywords {
+		if len(k) > maxKeywordLen {
+			panic("keyword exceeds maxKeywordLen: " + k)
+		}
+	}
 }
 
 // String returns the string representation of the token.
@@ -232,13 +265,21 @@ func (tok Token) String() string {
 }
 
 // Precedence returns the operator precedence of the binary operator token.
+// Higher values bind more tightly and operators of equal precedence are
+// left associative. Non-operator tokens return zero.
+//
+//	5  *  /
+//	4  +  -
+//	3  =  !=  =~  !~  <  <=  >  >=
+//	2  AND
+//	1  OR
 func (tok Token) Precedence() int {
 	switch tok {
 	case OR:
 		return 1
 	case AND:
 		return 2
-	case EQ, NEQ, LT, LTE, GT, GTE:
+	case EQ, NEQ, EQREGEX, NEQREGEX, LIKE, NLIKE, LT, LTE, GT, GTE:
 		return 3
 	case ADD, SUB:
 		return 4
@@ -248,8 +289,16 @@ func (tok Token) Precedence() int {
 	return 0
 }
 
-// isOperator returns true for operator tokens.
-func (tok Token) isOperator() bool { return tok > operator_beg && tok < operator_end }
+// IsLiteral returns true for literal tokens, including TRUE and FALSE.
+func (tok Token) IsLiteral() bool { return tok > literal_beg && tok < literal_end }
+
+// IsOperator returns true for operator tokens. This includes AND, OR and
+// LIKE which are spelled like keywords but are classified as operators.
+func (tok Token) IsOperator() bool { return tok > operator_beg && tok < operator_end }
+
+// IsKeyword returns true for keyword tokens. Reserved words that are
+// literals or operators, such as TRUE or AND, are not keywords.
+func (tok Token) IsKeyword() bool { return tok > keyword_beg && tok < keyword_end }
 
 // tokstr returns a literal if provided, otherwise returns the token string.
 func tokstr(tok Token, lit string) string {
@@ -260,16 +309,41 @@ func tokstr(tok Token, lit string) string {
 }
 
 // Lookup returns the token associated with a given string.
+// Keywords are matched case-insensitively.
 func Lookup(ident string) Token {
-	if tok, ok := keywords[ident]; ok {
+	if len(ident) > maxKeywordLen {
+		return IDENT
+	}
+
+	// Fold to lowercase on the stack. Keywords are ASCII so any other
+	// character means the identifier cannot be a keyword.
+	var buf [maxKeywordLen]byte
+	for i := 0; i < len(ident); i++ {
+		ch := ident[i]
+		if ch >= 'A' && ch <= 'Z' {
+			ch += 'a' - 'A'
+		} else if ch >= utf8.RuneSelf {
+			return IDENT
+		}
+		buf[i] = ch
+	}
+
+	if tok, ok := keywords[string(buf[:len(ident)])]; ok {
 		return tok
 	}
 	return IDENT
 }
 
 // Pos specifies the line and character position of a token.
-// The Char and Line are both zero-based indexes.
+// The Char and Line are both zero-based indexes. ParseError adds one to
+// each when formatting its message.
 type Pos struct {
+	// Line is the number of newlines before the token. A carriage return
+	// followed by a newline counts as a single line break.
 	Line int
+
+	// Char is the number of runes, not bytes, between the start of the
+	// line and the token. The end of input is positioned one past the
+	// last rune.
 	Char int
 }
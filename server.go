@@ -981,6 +981,16 @@ func (s *Server) CreateUser(username, password string, admin bool) error {
 	return err
 }
 
+// CreateUserWithHash creates a user on the server from a bcrypt password hash.
+func (s *Server) CreateUserWithHash(username, hash string, admin bool) error {
+	if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+		return err
+	}
+	c := &createUserCommand{Username: username, Password: hash, Hashed: true, Admin: admin}
+	_, err := s.broadcast(createUserMessageType, c)
+	return err
+}
+
 func (s *Server) applyCreateUser(m *messaging.Message) (err error) {
 	var c createUserCommand
 	mustUnmarshalJSON(m.Data, &c)
@@ -992,10 +1002,12 @@ func (s *Server) applyCreateUser(m *messaging.Message) (err error) {
 		return ErrUserExists
 	}
 
-	// Generate the hash of the password.
-	hash, err := HashPassword(c.Password)
-	if err != nil {
-		return err
+	// Generate the hash of the password unless it is already hashed.
+	hash := []byte(c.Password)
+	if !c.Hashed {
+		if hash, err = HashPassword(c.Password); err != nil {
+			return err
+		}
 	}
 
 	// Create the user.
@@ -1236,9 +1248,16 @@ func (s *Server) applyUpdateRetentionPolicy(m *messaging.Message) (err error) {
 		return ErrRetentionPolicyNotFound
 	}
 
-	// Update the policy name.
-	if c.Policy.Name != nil {
+	// Update the policy name. The default policy follows the rename.
+	if c.Policy.Name != nil && *c.Policy.Name != p.Name {
+		if db.policies[*c.Policy.Name] != nil {
+			return ErrRetentionPolicyExists
+		}
+
 		delete(db.policies, p.Name)
+		if db.defaultRetentionPolicy == p.Name {
+			db.defaultRetentionPolicy = *c.Policy.Name
+		}
 		p.Name = *c.Policy.Name
 		db.policies[p.Name] = p
 	}
@@ -1755,6 +1774,12 @@ func (s *Server) ExecuteQuery(q *influxql.Query, database string, user *User) Re
 			break
 		}
 
+		// Only SHOW statements can expand a regex source.
+		if err := checkRegexSource(stmt); err != nil {
+			results.Results[i] = &Result{Err: err}
+			break
+		}
+
 		var res *Result
 		switch stmt := stmt.(type) {
 		case *influxql.SelectStatement:
@@ -1785,6 +1810,8 @@ func (s *Server) ExecuteQuery(q *influxql.Query, database string, user *User) Re
 			res = s.executeShowTagValuesStatement(stmt, database, user)
 		case *influxql.ShowFieldKeysStatement:
 			res = s.executeShowFieldKeysStatement(stmt, database, user)
+		case *influxql.ShowTagKeyCardinalityStatement, *influxql.ShowFieldKeyCardinalityStatement, *influxql.ShowTagValuesCardinalityStatement:
+			res = &Result{Err: ErrNotImplemented}
 		case *influxql.GrantStatement:
 			res = s.executeGrantStatement(stmt, user)
 		case *influxql.RevokeStatement:
@@ -1796,7 +1823,7 @@ func (s *Server) ExecuteQuery(q *influxql.Query, database string, user *User) Re
 		case *influxql.DropRetentionPolicyStatement:
 			res = s.executeDropRetentionPolicyStatement(stmt, user)
 		case *influxql.ShowRetentionPoliciesStatement:
-			res = s.executeShowRetentionPoliciesStatement(stmt, user)
+			res = s.executeShowRetentionPoliciesStatement(stmt, database, user)
 		case *influxql.CreateContinuousQueryStatement:
 			res = s.executeCreateContinuousQueryStatement(stmt, user)
 		case *influxql.DropContinuousQueryStatement:
@@ -1919,6 +1946,9 @@ func (s *Server) executeCreateUserStatement(q *influxql.CreateUserStatement, use
 	if q.Privilege != nil {
 		isAdmin = *q.Privilege == influxql.AllPrivileges
 	}
+	if q.Hashed {
+		return &Result{Err: s.CreateUserWithHash(q.Name, q.Password, isAdmin)}
+	}
 	return &Result{Err: s.CreateUser(q.Name, q.Password, isAdmin)}
 }
 
@@ -2353,7 +2383,7 @@ func (s *Server) executeShowFieldKeysStatement(stmt *influxql.ShowFieldKeysState
 }
 
 func (s *Server) executeGrantStatement(stmt *influxql.GrantStatement, user *User) *Result {
-	return &Result{Err: s.SetPrivilege(stmt.Privilege, stmt.User, stmt.On)}
+	return &Result{Err: s.SetPrivilege(stmt.Privileges.Max(), stmt.User, stmt.On)}
 }
 
 func (s *Server) executeRevokeStatement(stmt *influxql.RevokeStatement, user *User) *Result {
@@ -2367,7 +2397,14 @@ func measurementsFromSourceOrDB(stmt influxql.Source, db *database) (Measurement
 	var measurements Measurements
 	if stmt != nil {
 		// TODO: handle multiple measurement sources
-		if m, ok := stmt.(*influxql.Measurement); ok {
+		if m, ok := stmt.(*influxql.Measurement); ok && m.Regex != nil {
+			// Get all measurements with series whose names match the regex.
+			for _, mm := range db.Measurements() {
+				if len(mm.seriesIDs) > 0 && m.Regex.Val.MatchString(mm.Name) {
+					measurements = append(measurements, mm)
+				}
+			}
+		} else if m, ok := stmt.(*influxql.Measurement); ok {
 			segments, err := influxql.SplitIdent(m.Name)
 			if err != nil {
 				return nil, err
@@ -2439,6 +2476,13 @@ func (s *Server) executeAlterRetentionPolicyStatement(stmt *influxql.AlterRetent
 		}(),
 	}
 
+	// Rename the policy, if requested.
+	name := stmt.Name
+	if stmt.NewName != "" {
+		rpu.Name = &stmt.NewName
+		name = stmt.NewName
+	}
+
 	// Update the retention policy.
 	err := s.UpdateRetentionPolicy(stmt.Database, stmt.Name, rpu)
 	if err != nil {
@@ -2447,7 +2491,7 @@ func (s *Server) executeAlterRetentionPolicyStatement(stmt *influxql.AlterRetent
 
 	// If requested, set as default retention policy.
 	if stmt.Default {
-		err = s.SetDefaultRetentionPolicy(stmt.Database, stmt.Name)
+		err = s.SetDefaultRetentionPolicy(stmt.Database, name)
 	}
 
 	return &Result{Err: err}
@@ -2457,8 +2501,13 @@ func (s *Server) executeDropRetentionPolicyStatement(q *influxql.DropRetentionPo
 	return &Result{Err: s.DeleteRetentionPolicy(q.Database, q.Name)}
 }
 
-func (s *Server) executeShowRetentionPoliciesStatement(q *influxql.ShowRetentionPoliciesStatement, user *User) *Result {
-	a, err := s.RetentionPolicies(q.Database)
+func (s *Server) executeShowRetentionPoliciesStatement(q *influxql.ShowRetentionPoliciesStatement, database string, user *User) *Result {
+	// Use the current database if one isn't specified.
+	if q.Database != "" {
+		database = q.Database
+	}
+
+	a, err := s.RetentionPolicies(database)
 	if err != nil {
 		return &Result{Err: err}
 	}
@@ -2552,6 +2601,12 @@ func (s *Server) normalizeStatement(stmt influxql.Statement, defaultDatabase str
 		}
 		switch n := n.(type) {
 		case *influxql.Measurement:
+			// A regex is matched against the measurement names of the
+			// default database so there is no name to qualify.
+			if n.Regex != nil {
+				return
+			}
+
 			name, e := s.normalizeMeasurement(n.Name, defaultDatabase)
 			if e != nil {
 				err = e
@@ -2580,6 +2635,30 @@ func (s *Server) normalizeStatement(stmt influxql.Statement, defaultDatabase str
 	return
 }
 
+// checkRegexSource returns an error if stmt reads from a regex source but is
+// not a SHOW statement. Other statements are planned against a single named
+// measurement.
+func checkRegexSource(stmt influxql.Statement) error {
+	var source influxql.Source
+	switch stmt := stmt.(type) {
+	case *influxql.SelectStatement:
+		source = stmt.Source
+	case *influxql.CreateContinuousQueryStatement:
+		if stmt.Source != nil {
+			source = stmt.Source.Source
+		}
+	case *influxql.DeleteStatement:
+		source = stmt.Source
+	case *influxql.DropSeriesStatement:
+		source = stmt.Source
+	}
+
+	if m, ok := source.(*influxql.Measurement); ok && m.Regex != nil {
+		return ErrRegexSourceNotSupported
+	}
+	return nil
+}
+
 // NormalizeMeasurement inserts the default database or policy into all measurement names.
 func (s *Server) NormalizeMeasurement(name string, defaultDatabase string) (string, error) {
 	s.mu.RLock()
@@ -645,6 +645,38 @@ func TestServer_AlterRetentionPolicy(t *testing.T) {
 	}
 }
 
+// Ensure renaming the default retention policy keeps it as the default.
+func TestServer_AlterRetentionPolicy_RenameDefault(t *testing.T) {
+	s := OpenServer(NewMessagingClient())
+	defer s.Close()
+	s.CreateDatabase("foo")
+	s.CreateRetentionPolicy("foo", &influxdb.RetentionPolicy{Name: "bar", Duration: time.Hour})
+	s.CreateRetentionPolicy("foo", &influxdb.RetentionPolicy{Name: "other", Duration: time.Hour})
+	s.SetDefaultRetentionPolicy("foo", "bar")
+
+	results := s.ExecuteQuery(MustParseQuery(`ALTER RETENTION POLICY bar ON foo RENAME TO baz`), "foo", nil)
+	if results.Error() != nil {
+		t.Fatalf("unexpected error: %s", results.Error())
+	}
+
+	// Restart the server to make sure the changes persist afterwards.
+	s.Restart()
+
+	if o, _ := s.RetentionPolicy("foo", "bar"); o != nil {
+		t.Fatal("old retention policy still exists")
+	} else if o, _ := s.DefaultRetentionPolicy("foo"); o == nil {
+		t.Fatal("default policy not found")
+	} else if o.Name != "baz" {
+		t.Fatalf("unexpected default policy: %s", o.Name)
+	}
+
+	// Renaming onto an existing policy is an error.
+	results = s.ExecuteQuery(MustParseQuery(`ALTER RETENTION POLICY baz ON foo RENAME TO other`), "foo", nil)
+	if results.Error() != influxdb.ErrRetentionPolicyExists {
+		t.Fatalf("unexpected error: %s", results.Error())
+	}
+}
+
 // Ensure the server can delete an existing retention policy.
 func TestServer_DeleteRetentionPolicy(t *testing.T) {
 	s := OpenServer(NewMessagingClient())
@@ -1346,6 +1378,58 @@ func TestServer_ExecuteQuery(t *testing.T) {
 	}
 }
 
+// Ensure the server returns an error for statements it cannot execute yet.
+func TestServer_ExecuteQuery_NotImplemented(t *testing.T) {
+	s := OpenServer(NewMessagingClient())
+	defer s.Close()
+	s.CreateDatabase("foo")
+	s.CreateRetentionPolicy("foo", &influxdb.RetentionPolicy{Name: "raw", Duration: 1 * time.Hour})
+	s.SetDefaultRetentionPolicy("foo", "raw")
+
+	for _, q := range []string{
+		`SHOW TAG KEY CARDINALITY FROM cpu`,
+		`SHOW FIELD KEY CARDINALITY`,
+		`SHOW TAG VALUES CARDINALITY FROM cpu WITH KEY = host`,
+	} {
+		results := s.ExecuteQuery(MustParseQuery(q), "foo", nil)
+		if res := results.Results[0]; res.Err != influxdb.ErrNotImplemented {
+			t.Fatalf("%s: unexpected error: %s", q, res.Err)
+		}
+	}
+}
+
+// Ensure the server expands regex sources in SHOW statements only.
+func TestServer_ExecuteQuery_RegexSource(t *testing.T) {
+	s := OpenServer(NewMessagingClient())
+	defer s.Close()
+	s.CreateDatabase("foo")
+	s.CreateRetentionPolicy("foo", &influxdb.RetentionPolicy{Name: "raw", Duration: 1 * time.Hour})
+	s.SetDefaultRetentionPolicy("foo", "raw")
+
+	s.MustWriteSeries("foo", "raw", []influxdb.Point{{Name: "cpu", Tags: map[string]string{"host": "serverA"}, Timestamp: mustParseTime("2000-01-01T00:00:00Z"), Fields: map[string]interface{}{"value": float64(10)}}})
+	s.MustWriteSeries("foo", "raw", []influxdb.Point{{Name: "cpu2", Tags: map[string]string{"host": "serverB"}, Timestamp: mustParseTime("2000-01-01T00:00:00Z"), Fields: map[string]interface{}{"value": float64(20)}}})
+	s.MustWriteSeries("foo", "raw", []influxdb.Point{{Name: "memory", Tags: map[string]string{"host": "serverA"}, Timestamp: mustParseTime("2000-01-01T00:00:00Z"), Fields: map[string]interface{}{"value": float64(30)}}})
+
+	results := s.ExecuteQuery(MustParseQuery(`SHOW SERIES FROM /^cpu/`), "foo", nil)
+	if res := results.Results[0]; res.Err != nil {
+		t.Fatalf("unexpected error: %s", res.Err)
+	} else if s := mustMarshalJSON(res); s != `{"series":[{"name":"cpu","columns":["id","host"],"values":[[1,"serverA"]]},{"name":"cpu2","columns":["id","host"],"values":[[2,"serverB"]]}]}` {
+		t.Fatalf("unexpected row(0): %s", s)
+	}
+
+	for _, q := range []string{
+		`SELECT value FROM /^cpu/`,
+		`DELETE FROM /^cpu/ WHERE time < '2000-01-01T00:00:00Z'`,
+		`DROP SERIES FROM /^cpu/`,
+		`CREATE CONTINUOUS QUERY cq ON foo BEGIN SELECT count(value) INTO cpu_count FROM /^cpu/ GROUP BY time(1h) END`,
+	} {
+		results := s.ExecuteQuery(MustParseQuery(q), "foo", nil)
+		if res := results.Results[0]; res.Err != influxdb.ErrRegexSourceNotSupported {
+			t.Fatalf("%s: unexpected error: %s", q, res.Err)
+		}
+	}
+}
+
 // Ensure the server respects limit and offset in show series queries
 func TestServer_ShowSeriesLimitOffset(t *testing.T) {
 	s := OpenServer(NewMessagingClient())